@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/check"
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/simulate"
+)
+
+func runCheck(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	limits := limitFlags(flags)
+	closed := flags.Bool("closed", false, "do not receive messages from the environment")
+	trace := flags.String("trace", "", "save the counterexample of an invariant to this file, so that it can be replayed and shrunk; counterexamples of properties can't be replayed")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected a single specification, got %d", flags.NArg())
+	}
+
+	m, sys, err := loadSystem(flags.Arg(0), compose.Options{Closed: *closed})
+	if err != nil {
+		return err
+	}
+
+	v, stats, err := check.Invariants(sys, m.Invariants, *limits)
+	if err != nil {
+		return err
+	}
+
+	if v != nil {
+		fmt.Print(v.Format(sys))
+		if *trace != "" {
+			if err := saveTrace(sys, simulate.Options{Invariants: m.Invariants, Spec: flags.Arg(0), Closed: *closed}, v, *trace); err != nil {
+				return err
+			}
+		}
+		return errViolation
+	}
+
+	printStats(stats)
+	if stats.Complete() && len(m.Invariants) != 0 {
+		fmt.Printf("%d invariant(s) hold\n", len(m.Invariants))
+	}
+
+	for _, prop := range m.Properties {
+		v, stats, err := check.Property(sys, prop, m.Fairness, *limits)
+		if err != nil {
+			return err
+		}
+
+		if v != nil && v.Fair() {
+			fmt.Print(v.Format(sys))
+			if *trace != "" {
+				fmt.Printf("the counterexample of property %s is not saved to %s, only invariant violations can be replayed\n", prop.Name, *trace)
+			}
+			return errViolation
+		}
+
+		if v != nil {
+			fmt.Printf("property %s only holds under the fairness assumptions, without them:\n", prop.Name)
+			fmt.Print(v.Format(sys))
+			continue
+		}
+
+		if stats.Complete() {
+			fmt.Printf("property %s holds\n", prop.Name)
+		} else {
+			fmt.Printf("property %s holds in the explored part, which reached the %s\n", prop.Name, stats.Stopped)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"dberk.nl/graphchecker/internal/ioco"
+	"dberk.nl/graphchecker/internal/telemetry"
+)
+
+func runConform(args []string) error {
+	flags := flag.NewFlagSet("conform", flag.ContinueOnError)
+	trace := flags.String("trace", "", "the recorded events, or a trace file that is mapped onto events")
+	format := flags.String("format", "events", "the format of the trace: events (JSON lines), otlp or jaeger")
+	mapping := flags.String("mapping", "", "the mapping of the spans of an otlp or jaeger trace onto messages")
+	process := flags.String("process", "", "the instance that recorded the trace, may be omitted if there is only one")
+	quiescence := flags.Duration("quiescence", 0, "observe a gap of this long between two events as quiescence, 0 never does")
+	coverage := flags.String("coverage", "", "add the coverage of the specification by the trace to this file")
+	max := flags.Int("max-states", 100000, "fail if the process or its suspension automaton has more states than this")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected a single specification, got %d", flags.NArg())
+	}
+	if *trace == "" {
+		return fmt.Errorf("expected a trace, pass it with --trace")
+	}
+
+	spec, err := loadSpec(flags.Arg(0), *process, *max)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*trace)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var events []ioco.Event
+	switch {
+	case *format == "events":
+		events, err = ioco.ReadEvents(f, *trace)
+	case *mapping == "":
+		return fmt.Errorf("expected the mapping of the spans onto messages, pass it with --mapping")
+	default:
+		events, err = importSpans(f, *format, *mapping)
+	}
+	if err != nil {
+		return err
+	}
+
+	return checkEvents(spec, events, *quiescence, *coverage)
+}
+
+// importSpans reads the spans of an OpenTelemetry or Jaeger trace and maps them onto events.
+func importSpans(r io.Reader, format, path string) ([]ioco.Event, error) {
+	m, err := telemetry.LoadMapping(path)
+	if err != nil {
+		return nil, err
+	}
+
+	spans, err := telemetry.Read(r, format)
+	if err != nil {
+		return nil, err
+	}
+	return m.Events(spans), nil
+}
+
+// checkEvents checks the events against the specification and reports the divergence, if any. The accepted part of the
+// trace is added to the coverage file, if any.
+func checkEvents(spec *ioco.Spec, events []ioco.Event, quiescence time.Duration, coverage string) error {
+	trace, d := ioco.Conform(spec, events, quiescence)
+	if err := recordCoverage(coverage, spec, trace); err != nil {
+		return err
+	}
+
+	if d != nil {
+		fmt.Print(d)
+		return errViolation
+	}
+
+	fmt.Printf("the specification accepts the trace of %d event(s)\n", len(events))
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"dberk.nl/graphchecker/internal/ioco"
+	"dberk.nl/graphchecker/internal/lts"
+)
+
+func runCoverage(args []string) error {
+	flags := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	process := flags.String("process", "", "the instance that the coverage was recorded for, may be omitted if there is only one")
+	format := flags.String("format", "text", "the format of the report: text, json or html")
+	output := flags.String("o", "", "write the report to this file instead of stdout")
+	max := flags.Int("max-states", 100000, "fail if the process or its suspension automaton has more states than this")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: graphchecker coverage [flags] spec.lisp coverage.json")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		return fmt.Errorf("expected a specification and a coverage file, got %d argument(s)", flags.NArg())
+	}
+
+	spec, err := loadSpec(flags.Arg(0), *process, *max)
+	if err != nil {
+		return err
+	}
+
+	c, err := ioco.LoadCoverage(flags.Arg(1), spec.Process)
+	if err != nil {
+		return err
+	}
+
+	r, err := c.Report(spec.Process)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "text":
+		_, err = io.WriteString(w, r.Text())
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	case "html":
+		err = ioco.WriteHTML(w, r, os.ReadFile)
+	default:
+		return fmt.Errorf("unknown format %s, expected text, json or html", *format)
+	}
+	return err
+}
+
+// recordCoverage adds the run to the coverage in the file, if the path isn't empty.
+func recordCoverage(path string, spec *ioco.Spec, trace []lts.Label) error {
+	if path == "" {
+		return nil
+	}
+
+	c, err := ioco.LoadCoverage(path, spec.Process)
+	if err != nil {
+		return err
+	}
+	if err := c.Add(spec, trace); err != nil {
+		return err
+	}
+	return c.Save(path)
+}
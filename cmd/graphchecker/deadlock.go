@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/check"
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/simulate"
+)
+
+func runDeadlock(args []string) error {
+	flags := flag.NewFlagSet("deadlock", flag.ContinueOnError)
+	limits := limitFlags(flags)
+	closed := flags.Bool("closed", false, "do not receive messages from the environment")
+	trace := flags.String("trace", "", "save the counterexample to this file, so that it can be replayed and shrunk")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected a single specification, got %d", flags.NArg())
+	}
+
+	_, sys, err := loadSystem(flags.Arg(0), compose.Options{Closed: *closed})
+	if err != nil {
+		return err
+	}
+
+	v, stats, err := check.Deadlock(sys, *limits)
+	if err != nil {
+		return err
+	}
+
+	if v != nil {
+		fmt.Print(v.Format(sys))
+		if *trace != "" {
+			if err := saveTrace(sys, simulate.Options{Spec: flags.Arg(0), Closed: *closed}, v, *trace); err != nil {
+				return err
+			}
+		}
+		return errViolation
+	}
+
+	printStats(stats)
+	if stats.Complete() {
+		fmt.Println("no deadlocks")
+	}
+	return nil
+}
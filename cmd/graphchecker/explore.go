@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/explore"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+func runExplore(args []string) error {
+	flags := flag.NewFlagSet("explore", flag.ContinueOnError)
+	strategy := flags.String("strategy", "bfs", "order in which states are explored, bfs or dfs")
+	limits := limitFlags(flags)
+	closed := flags.Bool("closed", false, "do not receive messages from the environment")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected a single specification, got %d", flags.NArg())
+	}
+
+	s, err := explore.ParseStrategy(*strategy)
+	if err != nil {
+		return err
+	}
+
+	_, sys, err := loadSystem(flags.Arg(0), compose.Options{Closed: *closed})
+	if err != nil {
+		return err
+	}
+
+	e := explore.New(sys, s, *limits)
+	stats, err := e.Run()
+	if err != nil {
+		return err
+	}
+
+	printStats(stats)
+	return nil
+}
+
+// limitFlags registers the flags that bound an exploration.
+func limitFlags(flags *flag.FlagSet) *explore.Limits {
+	limits := &explore.Limits{}
+	flags.IntVar(&limits.MaxStates, "max-states", 0, "stop after this many states, 0 for no limit")
+	flags.IntVar(&limits.MaxDepth, "max-depth", 0, "do not explore beyond this many steps, 0 for no limit")
+	flags.DurationVar(&limits.Timeout, "timeout", 0, "stop after this long, 0 for no limit")
+	return limits
+}
+
+func printStats(stats *explore.Stats) {
+	fmt.Printf("states:      %d\n", stats.States)
+	fmt.Printf("transitions: %d\n", stats.Transitions)
+	fmt.Printf("max depth:   %d\n", stats.MaxDepth)
+	fmt.Printf("time:        %s\n", stats.Duration)
+	if !stats.Complete() {
+		fmt.Printf("incomplete:  reached the %s\n", stats.Stopped)
+	}
+}
+
+// loadSystem loads the specification at path and composes its instances.
+func loadSystem(path string, opts compose.Options) (*model.Model, *compose.System, error) {
+	m, err := lisp.LoadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sys, err := compose.New(m, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, sys, nil
+}
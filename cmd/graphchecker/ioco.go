@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/ioco"
+	"dberk.nl/graphchecker/internal/lts"
+)
+
+func runIoco(args []string) error {
+	flags := flag.NewFlagSet("ioco", flag.ContinueOnError)
+	max := flags.Int("max-states", 100000, "fail if a process or its suspension automaton has more states than this")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		return fmt.Errorf("expected an implementation and a specification, e.g. impl.lisp:Proc spec.lisp:Proc, got %d argument(s)", flags.NArg())
+	}
+
+	impl, err := loadSuspension(flags.Arg(0), *max)
+	if err != nil {
+		return fmt.Errorf("implementation: %w", err)
+	}
+
+	spec, err := loadSuspension(flags.Arg(1), *max)
+	if err != nil {
+		return fmt.Errorf("specification: %w", err)
+	}
+
+	if f := ioco.Check(impl, spec); f != nil {
+		fmt.Printf("%s does not conform to %s\n", flags.Arg(0), flags.Arg(1))
+		fmt.Print(f)
+		return errViolation
+	}
+
+	fmt.Printf("%s ioco %s\n", flags.Arg(0), flags.Arg(1))
+	return nil
+}
+
+// loadSuspension loads the suspension automaton of the instance that is named by path:Instance.
+func loadSuspension(arg string, max int) (*lts.Suspension, error) {
+	idx := strings.LastIndex(arg, ":")
+	if idx < 0 {
+		return nil, fmt.Errorf("expected path:Instance, got %s", arg)
+	}
+
+	m, err := lisp.LoadFile(arg[:idx])
+	if err != nil {
+		return nil, err
+	}
+	return ioco.Suspend(m, arg[idx+1:], max)
+}
@@ -1,5 +1,95 @@
 package main
 
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is a subcommand of graphchecker, it receives the arguments that follow its name.
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]*command{
+	"explore":  {"explore the reachable states of a specification", runExplore},
+	"deadlock": {"check that a specification can't get stuck", runDeadlock},
+	"check":    {"check the invariants and properties of a specification", runCheck},
+	"query":    {"answer a CTL query about the states of a specification", runQuery},
+	"ioco":     {"check that an implementation process conforms to a specification process", runIoco},
+	"testgen":  {"generate ioco test cases from a specification", runTestgen},
+	"test":     {"test a running implementation against a specification", runTest},
+	"conform":  {"check a recorded trace against a specification", runConform},
+	"coverage": {"report the coverage of a specification by test runs", runCoverage},
+	"simulate": {"step through a specification interactively", runSimulate},
+	"walk":     {"check a specification along random walks", runWalk},
+	"shrink":   {"shrink a trace to a minimal trace that violates the same property", runShrink},
+}
+
+// errViolation is returned by commands that found a violation of a property, which they have already reported.
+var errViolation = errors.New("violation")
+
 func main() {
-	print("Hello, World!")
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %s\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	err := cmd.run(os.Args[2:])
+	if errors.Is(err, errViolation) {
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses the flags of a subcommand, which may also follow its arguments, e.g. testgen spec.lisp --process X.
+// The arguments end at --, the arguments that follow it are taken as they are.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		rest := flags.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if len(rest) < len(args) && args[len(args)-len(rest)-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	// Parsing -- followed by the arguments makes them the arguments of the flag set, without setting any flags.
+	return flags.Parse(append([]string{"--"}, positional...))
+}
+
+func usage() {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: graphchecker <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
 }
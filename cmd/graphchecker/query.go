@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/ctl"
+)
+
+func runQuery(args []string) error {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	limits := limitFlags(flags)
+	closed := flags.Bool("closed", false, "do not receive messages from the environment")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		return fmt.Errorf("expected a formula and a specification, got %d argument(s)", flags.NArg())
+	}
+
+	f, err := ctl.ParseQuery(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("could not parse the formula: %w", err)
+	}
+
+	_, sys, err := loadSystem(flags.Arg(1), compose.Options{Closed: *closed})
+	if err != nil {
+		return err
+	}
+
+	g, err := ctl.Explore(sys, *limits)
+	if err != nil {
+		return err
+	}
+
+	r, err := g.Check(f)
+	if err != nil {
+		return err
+	}
+
+	if !g.Stats.Complete() {
+		fmt.Printf("the answer only applies to the explored part, which reached the %s\n", g.Stats.Stopped)
+	}
+	fmt.Print(g.Format(r))
+	if !r.Holds {
+		return errViolation
+	}
+	return nil
+}
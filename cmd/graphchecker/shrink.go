@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/check"
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/simulate"
+)
+
+func runShrink(args []string) error {
+	flags := flag.NewFlagSet("shrink", flag.ContinueOnError)
+	out := flags.String("out", "", "the file to write the minimal trace to, by default the trace with .min.json")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		return fmt.Errorf("expected a specification and a trace, got %d argument(s)", flags.NArg())
+	}
+	spec, path := flags.Arg(0), flags.Arg(1)
+	if *out == "" {
+		*out = strings.TrimSuffix(path, ".json") + ".min.json"
+	}
+
+	trace, err := simulate.LoadTrace(path)
+	if err != nil {
+		return err
+	}
+
+	// The trace is shrunk on the system that it was recorded on.
+	m, sys, err := loadSystem(spec, compose.Options{Closed: trace.Closed})
+	if err != nil {
+		return err
+	}
+
+	opts := simulate.Options{Invariants: m.Invariants, Spec: spec, Closed: trace.Closed}
+	shrunk, v, err := simulate.Shrink(sys, opts, trace)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := shrunk.Save(*out); err != nil {
+		return err
+	}
+
+	fmt.Print(v.Format(sys))
+	fmt.Printf("shrunk the trace from %d to %d step(s), replay with: graphchecker simulate --replay %s %s\n", len(trace.Steps), len(shrunk.Steps), *out, spec)
+	return nil
+}
+
+// saveTrace saves the counterexample of a violation that the explorer found as a trace, so that it can be replayed
+// and shrunk.
+func saveTrace(sys *compose.System, opts simulate.Options, v *check.Violation, path string) error {
+	trace, err := simulate.Record(sys, opts, v.Trace)
+	if err != nil {
+		return err
+	}
+	trace.Violation = fmt.Sprintf("%s: %s", v.Property, strings.Join(v.Reasons, ", "))
+	if err := trace.Save(path); err != nil {
+		return err
+	}
+	fmt.Printf("saved the counterexample to %s, shrink it with: graphchecker shrink %s %s\n", path, opts.Spec, path)
+	return nil
+}
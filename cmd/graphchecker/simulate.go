@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/simulate"
+)
+
+func runSimulate(args []string) error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	opts := simulate.Options{}
+	flags.Int64Var(&opts.Seed, "seed", 0, "seed of the random steps, 0 picks one")
+	flags.BoolVar(&opts.Closed, "closed", false, "do not receive messages from the environment")
+	bias := flags.String("bias", "", "weights of the random steps, e.g. Server=2,environment=0.5,channels=0.1")
+	replay := flags.String("replay", "", "replay the steps of this trace before the session starts")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	var err error
+	if opts.Bias, err = simulate.ParseBias(*bias); err != nil {
+		return fmt.Errorf("--bias: %w", err)
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected a single specification, got %d", flags.NArg())
+	}
+	opts.Spec = flags.Arg(0)
+
+	var trace *simulate.Trace
+	if *replay != "" {
+		if trace, err = simulate.LoadTrace(*replay); err != nil {
+			return err
+		}
+		// The trace is replayed on the system that it was recorded on, and continues with its random choices.
+		opts.Closed = trace.Closed
+		if opts.Seed == 0 {
+			opts.Seed = trace.Seed
+		}
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+
+	m, sys, err := loadSystem(opts.Spec, compose.Options{Closed: opts.Closed})
+	if err != nil {
+		return err
+	}
+	opts.Invariants = m.Invariants
+
+	sim := simulate.New(sys, opts)
+	if trace != nil {
+		if err := sim.Replay(trace); err != nil {
+			return fmt.Errorf("%s: %w", *replay, err)
+		}
+		fmt.Printf("replayed %d step(s) of %s\n", len(trace.Steps), *replay)
+		if trace.Violation != "" {
+			fmt.Printf("the trace was recorded with the violation of %s\n", trace.Violation)
+		}
+	}
+
+	fmt.Printf("simulating %s (seed %d), type help for the commands\n", opts.Spec, opts.Seed)
+	return sim.Interact(os.Stdin, os.Stdout)
+}
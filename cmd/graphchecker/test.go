@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"dberk.nl/graphchecker/internal/ioco"
+)
+
+func runTest(args []string) error {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	process := flags.String("process", "", "the instance that the implementation implements, may be omitted if there is only one")
+	connect := flags.String("connect", "", "connect to the implementation on this local TCP address instead of starting it")
+	opts := ioco.OnlineOptions{}
+	flags.IntVar(&opts.Steps, "steps", 100, "the number of inputs and observations")
+	flags.Int64Var(&opts.Seed, "seed", 0, "seed of the choices of the tester, 0 picks one")
+	flags.DurationVar(&opts.Timeout, "timeout", 200*time.Millisecond, "how long to wait for an output before observing quiescence")
+	coverage := flags.String("coverage", "", "add the coverage of the specification by the run to this file")
+	max := flags.Int("max-states", 100000, "fail if the process or its suspension automaton has more states than this")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: graphchecker test [flags] spec.lisp [--] [command [args...]]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() < 1 {
+		return fmt.Errorf("expected a specification")
+	}
+
+	spec, err := loadSpec(flags.Arg(0), *process, *max)
+	if err != nil {
+		return err
+	}
+
+	command := flags.Args()[1:]
+	if len(command) != 0 && command[0] == "--" {
+		command = command[1:]
+	}
+
+	var a ioco.Adapter
+	switch {
+	case *connect != "" && len(command) != 0:
+		return fmt.Errorf("either connect to the implementation or start it, not both")
+	case *connect != "":
+		a, err = ioco.Dial(*connect)
+	case len(command) != 0:
+		a, err = ioco.Start(command[0], command[1:]...)
+	default:
+		return fmt.Errorf("expected a command that starts the implementation, or --connect")
+	}
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+
+	run, err := ioco.Online(spec, a, opts)
+	if err != nil {
+		return fmt.Errorf("seed %d: %w", opts.Seed, err)
+	}
+	if err := recordCoverage(*coverage, spec, run.Trace); err != nil {
+		return err
+	}
+
+	if run.Failure != nil {
+		fmt.Printf("fail (seed %d)\n", run.Seed)
+		fmt.Print(run.Failure)
+		return errViolation
+	}
+
+	fmt.Printf("pass after %d step(s) (seed %d)\n", len(run.Trace), run.Seed)
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/ioco"
+)
+
+func runTestgen(args []string) error {
+	flags := flag.NewFlagSet("testgen", flag.ContinueOnError)
+	process := flags.String("process", "", "the instance to generate tests for, may be omitted if there is only one")
+	opts := ioco.GenOptions{}
+	flags.IntVar(&opts.Depth, "depth", 10, "the maximum number of inputs and observations along a path of a test")
+	flags.Int64Var(&opts.Seed, "seed", 1, "seed that breaks the ties between equally useful choices")
+	flags.IntVar(&opts.Max, "tests", 0, "the maximum number of tests, 0 until every reachable transition is covered")
+	max := flags.Int("max-states", 100000, "fail if the process or its suspension automaton has more states than this")
+	goFile := flags.String("go", "", "write the tests to this Go test file instead of printing them")
+	goOpts := ioco.GoOptions{}
+	flags.StringVar(&goOpts.Package, "package", "main", "the package of the Go test file")
+	flags.StringVar(&goOpts.Prefix, "prefix", "", "the name prefix of the Go tests, Test followed by the process by default")
+	flags.StringVar(&goOpts.Adapter, "adapter", "newAdapter", "the function that connects a Go test to the implementation, func(t *testing.T) *iocotest.Adapter")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected a single specification, got %d", flags.NArg())
+	}
+
+	spec, err := loadSpec(flags.Arg(0), *process, *max)
+	if err != nil {
+		return err
+	}
+
+	tests := ioco.Generate(spec, opts)
+	if *goFile != "" {
+		if err := writeGoTests(*goFile, tests, flags.Arg(0), spec.Name, goOpts); err != nil {
+			return err
+		}
+	} else {
+		for idx, t := range tests {
+			fmt.Printf("test %d: %s", idx+1, t)
+		}
+	}
+
+	covered := spec.Covered(tests)
+	fmt.Printf("covered %d of %d transitions\n", len(covered), len(spec.Process.Transitions))
+	for _, t := range spec.Process.Transitions {
+		if !covered[t] {
+			fmt.Printf("  not covered: %s\n", t)
+		}
+	}
+	return nil
+}
+
+// writeGoTests writes the tests to a Go test file.
+func writeGoTests(path string, tests []*ioco.Test, source, instance string, opts ioco.GoOptions) error {
+	opts.Source = fmt.Sprintf("%s, process %s", filepath.Base(source), instance)
+	// The citations locate the transitions relative to the specification, like the header names it.
+	dir, err := filepath.Abs(filepath.Dir(source))
+	if err != nil {
+		return err
+	}
+	opts.Dir = dir
+	if opts.Prefix == "" {
+		// (Worker 1) becomes TestWorker1
+		opts.Prefix = "Test" + strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return r
+			}
+			return -1
+		}, instance)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := ioco.WriteGo(f, tests, opts); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	fmt.Printf("wrote %d tests to %s\n", len(tests), path)
+	return f.Close()
+}
+
+// loadSpec loads the specification at path and isolates the named instance. If the name is empty, then the model must
+// have a single instance.
+func loadSpec(path, name string, max int) (*ioco.Spec, error) {
+	m, err := lisp.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		names := []string{}
+		for _, inst := range m.System {
+			names = append(names, inst.Name)
+		}
+		if m.System == nil {
+			for _, p := range m.Processes {
+				names = append(names, p.Name)
+			}
+		}
+		if len(names) != 1 {
+			return nil, fmt.Errorf("the specification has %d instances, select one with --process", len(names))
+		}
+		name = names[0]
+	}
+
+	return ioco.NewSpec(m, name, max)
+}
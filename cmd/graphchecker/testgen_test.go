@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTestgen(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "echo.lisp")
+	assert.Nil(t, os.WriteFile(spec, []byte(`
+		(defprocess Echo
+		  (?receive :message ping)
+		  (!send :message pong))
+		(defprocess Other
+		  (?receive :message pong))`), 0o644))
+
+	var tests = []struct {
+		args   []string
+		expErr string
+	}{
+		{args: []string{"--process", "Echo", spec}},
+		{args: []string{spec, "--process", "Echo"}},
+		{args: []string{spec, "--process", "Echo", "--go", filepath.Join(dir, "echo_test.go")}},
+		{args: []string{spec}, expErr: "the specification has 2 instances, select one with --process"},
+		{args: []string{spec, "--process", "Echo", spec}, expErr: "expected a single specification, got 2"},
+		{args: []string{"--process", "Echo", "--", "--go"}, expErr: "--go: no such file or directory"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("runTestgen(%s)", strings.Join(test.args, " ")), func(t *testing.T) {
+			err := runTestgen(test.args)
+			if test.expErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.expErr)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/simulate"
+)
+
+func runWalk(args []string) error {
+	flags := flag.NewFlagSet("walk", flag.ContinueOnError)
+	opts := simulate.WalkOptions{}
+	flags.IntVar(&opts.Walks, "walks", 1000, "the number of random walks")
+	flags.IntVar(&opts.Length, "length", 1000, "the maximum number of steps of a walk")
+	flags.Int64Var(&opts.Seed, "seed", 0, "seed of the walks, 0 picks one")
+	flags.BoolVar(&opts.Closed, "closed", false, "do not receive messages from the environment")
+	bias := flags.String("bias", "", "weights of the steps, e.g. Server=2,environment=0.5,channels=0.1")
+	out := flags.String("out", ".", "the directory to write the traces of the failed walks to")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected a single specification, got %d", flags.NArg())
+	}
+	opts.Spec = flags.Arg(0)
+
+	var err error
+	if opts.Bias, err = simulate.ParseBias(*bias); err != nil {
+		return fmt.Errorf("--bias: %w", err)
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+
+	m, sys, err := loadSystem(opts.Spec, compose.Options{Closed: opts.Closed})
+	if err != nil {
+		return err
+	}
+	opts.Invariants = m.Invariants
+
+	walks, err := simulate.Walk(sys, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d walk(s), %d step(s) (seed %d)\n", walks.Walks, walks.Steps, opts.Seed)
+	if len(walks.Failures) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	for _, f := range walks.Failures {
+		path := filepath.Join(*out, fmt.Sprintf("walk-%d.json", f.Walk))
+		if err := f.Trace.Save(path); err != nil {
+			return err
+		}
+		fmt.Printf("walk %d violates %s after %d step(s), replay with: graphchecker simulate --replay %s %s\n", f.Walk, f.Violation.Property, len(f.Trace.Steps), path, opts.Spec)
+	}
+	return errViolation
+}
@@ -9,10 +9,10 @@
 (defprocess DynamoDBProcess
   (let ((tasksByKey {}))
 
-    :start
+    :idle
     (loop
       (select
         (let (({key} (?receive :message getTaskForKey)))
           (if (map-contains? tasksByKey key)
-              (!send :message getTaskForKey :task (map-get tasksByKey key))
+              (!send :message taskForKey :task (map-get tasksByKey key))
               (!send :message noTaskForKey)))))))
@@ -0,0 +1,61 @@
+package check
+
+import (
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/explore"
+)
+
+// Deadlock explores the system breadth first and returns the deadlock that is closest to the initial state, or nil if
+// no reachable state is a deadlock.
+//
+// A state is a deadlock if the system can't take any step from it, unless every instance is in a state that is marked
+// as final and no messages are in flight.
+func Deadlock(sys *compose.System, limits explore.Limits) (*Violation, *explore.Stats, error) {
+	e := explore.New(sys, explore.BFS, limits)
+	for n, err := range e.Nodes() {
+		if err != nil {
+			return nil, e.Stats(), err
+		}
+
+		if len(n.Steps) != 0 {
+			continue
+		}
+
+		if v := DeadlockViolation(sys, n.Trace(), n.State); v != nil {
+			return v, e.Stats(), nil
+		}
+	}
+
+	return nil, e.Stats(), nil
+}
+
+// DeadlockViolation returns the violation of deadlock freedom by the state without successors that the trace reaches,
+// or nil if it is an intended terminal state.
+func DeadlockViolation(sys *compose.System, trace []*compose.Step, s *compose.State) *Violation {
+	reasons := stuck(sys, s)
+	if len(reasons) == 0 {
+		return nil
+	}
+	return &Violation{Property: "deadlock freedom", Trace: trace, State: s, Reasons: reasons}
+}
+
+// stuck explains why a state without successors is not an intended terminal state, it returns nothing if it is one.
+func stuck(sys *compose.System, s *compose.State) []string {
+	reasons := []string{}
+	for i, inst := range sys.Instances {
+		if !s.Locs[i].Final {
+			reasons = append(reasons, fmt.Sprintf("%s is not in a final state", inst.Name))
+		}
+	}
+
+	inFlight := 0
+	for _, buf := range s.Chans {
+		inFlight += len(buf)
+	}
+	if inFlight != 0 {
+		reasons = append(reasons, fmt.Sprintf("%d message(s) are still in flight", inFlight))
+	}
+	return reasons
+}
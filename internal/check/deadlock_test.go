@@ -0,0 +1,111 @@
+package check
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/explore"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSystem(t *testing.T, str string) *compose.System {
+	m, err := lisp.LoadString(str)
+	assert.Nil(t, err)
+
+	sys, err := compose.New(m, compose.Options{Closed: true})
+	assert.Nil(t, err)
+	return sys
+}
+
+func TestDeadlock(t *testing.T) {
+	var tests = []struct {
+		name       string
+		str        string
+		expTrace   int
+		expReasons []string
+	}{
+		{
+			name: "no deadlock",
+			str: `(defprocess Client
+			        (loop
+			          (!send :message ping :to Server)
+			          (?receive :message pong)))
+			      (defprocess Server
+			        (loop
+			          (?receive :message ping :from client)
+			          (!send :message pong :to client)))`,
+		},
+		{
+			name: "processes end in states that are not final",
+			str: `(defprocess Client
+			        (!send :message ping :to Server)
+			        (?receive :message pong))
+			      (defprocess Server
+			        (?receive :message ping)
+			        (!send :message pong :to Client))`,
+			expTrace:   4,
+			expReasons: []string{"Client is not in a final state", "Server is not in a final state"},
+		},
+		{
+			name: "final states",
+			str: `(defprocess Client
+			        (!send :message ping :to Server)
+			        (?receive :message pong)
+			        :done :final)
+			      (defprocess Server
+			        (?receive :message ping :from client)
+			        (!send :message pong :to client)
+			        :done :final)`,
+		},
+		{
+			name: "messages in flight",
+			str: `(defprocess Client
+			        (!send :message ping :to Server)
+			        (!send :message ping :to Server)
+			        :done :final)
+			      (defprocess Server
+			        (?receive :message ping)
+			        :done :final)`,
+			expTrace:   5,
+			expReasons: []string{"1 message(s) are still in flight"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Deadlock - %s", test.name), func(t *testing.T) {
+			v, stats, err := Deadlock(newSystem(t, test.str), explore.Limits{})
+			assert.Nil(t, err)
+			assert.True(t, stats.Complete())
+
+			if test.expReasons == nil {
+				assert.Nil(t, v)
+				return
+			}
+
+			assert.NotNil(t, v)
+			assert.Equal(t, test.expTrace, len(v.Trace))
+			assert.Equal(t, test.expReasons, v.Reasons)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	sys := newSystem(t, `(defprocess Client
+  (!send :message ping :to Server)
+  (?receive :message pong))
+(defprocess Server
+  (?receive :message ping))`)
+
+	v, _, err := Deadlock(sys, explore.Limits{})
+	assert.Nil(t, err)
+	assert.Equal(t, `deadlock freedom is violated after 2 step(s)
+  0. Client@:start, Server@:start
+  1. Client !ping, to Server  (<string>:2:3)
+  2. Server ?ping, from Client  (<string>:5:3)
+state: Client@#2, Server@#2
+  - Client is not in a final state
+  - Server is not in a final state
+`, v.Format(sys))
+}
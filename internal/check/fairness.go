@@ -0,0 +1,124 @@
+package check
+
+import (
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// constraint is a fairness assumption about the steps of a single instance, or of a single channel.
+type constraint struct {
+	*model.Fairness
+	// desc names the assumption, and the instance it applies to if the assumption applies to several
+	desc    string
+	matches func(step *compose.Step) bool
+}
+
+// constraints resolves the fairness assumptions against the instances of the system. An assumption about a process
+// applies to each of its instances separately.
+func constraints(sys *compose.System, fairness []*model.Fairness) ([]*constraint, error) {
+	byName := map[string]*compose.Instance{}
+	for _, inst := range sys.Instances {
+		byName[inst.Name] = inst
+	}
+
+	cs := []*constraint{}
+	for _, f := range fairness {
+		if f.From != "" {
+			from, to := byName[f.From], byName[f.To]
+			if from == nil || to == nil {
+				return nil, fmt.Errorf("%s: unknown instance", f)
+			}
+
+			cs = append(cs, &constraint{f, f.String(), func(step *compose.Step) bool {
+				return step.Sender == from && step.Recipient == to && !step.Lost
+			}})
+			continue
+		}
+
+		insts := []*compose.Instance{}
+		for _, inst := range sys.Instances {
+			if inst.Name == f.Instance || f.Instance == "" && inst.Process.Name == f.Process {
+				insts = append(insts, inst)
+			}
+		}
+		if len(insts) == 0 {
+			return nil, fmt.Errorf("%s: no such instance", f)
+		}
+
+		for _, inst := range insts {
+			desc := f.String()
+			if len(insts) != 1 {
+				desc = fmt.Sprintf("%s for %s", f, inst.Name)
+			}
+
+			cs = append(cs, &constraint{f, desc, func(step *compose.Step) bool {
+				for _, m := range step.Moves {
+					if m.Instance == inst &&
+						(f.Receive == "" || m.Transition.Receive == f.Receive) &&
+						(f.Send == "" || m.Transition.Send == f.Send) {
+						return true
+					}
+				}
+				return false
+			}})
+		}
+	}
+	return cs, nil
+}
+
+// enabled returns whether the system can take a step of the constraint from the node.
+func (p *product) enabled(n *pnode, c *constraint) bool {
+	for _, step := range p.steps[n.state.Key()] {
+		if c.matches(step) {
+			return true
+		}
+	}
+	return false
+}
+
+// componentEdge is an edge of the product that starts at from.
+type componentEdge struct {
+	from *pnode
+	edge *pedge
+}
+
+// taken returns an edge within the component that takes a step of the constraint, or nil if there is none.
+func (p *product) taken(component map[*pnode]bool, c *constraint) *componentEdge {
+	for _, n := range p.nodes {
+		if !component[n] {
+			continue
+		}
+
+		for _, e := range n.edges {
+			if component[e.to] && c.matches(e.step) {
+				return &componentEdge{n, e}
+			}
+		}
+	}
+	return nil
+}
+
+// violated returns the constraints that a cycle through the nodes, taking the edges, violates.
+func (p *product) violated(nodes []*pnode, edges []*pedge, fair []*constraint) []string {
+	descs := []string{}
+	for _, c := range fair {
+		taken := false
+		for _, e := range edges {
+			taken = taken || c.matches(e.step)
+		}
+
+		enabled := 0
+		for _, n := range nodes {
+			if p.enabled(n, c) {
+				enabled++
+			}
+		}
+
+		if !taken && (c.Strong && enabled != 0 || !c.Strong && enabled == len(nodes)) {
+			descs = append(descs, c.desc)
+		}
+	}
+	return descs
+}
@@ -0,0 +1,63 @@
+package check
+
+import (
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/eval"
+	"dberk.nl/graphchecker/internal/explore"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Invariants explores the system breadth first and returns the violation of an invariant that is closest to the
+// initial state, or nil if every invariant holds in every reachable state.
+//
+// An invariant is only violated if it is false, an invariant whose value is unknown because it depends on input from
+// the environment may hold.
+func Invariants(sys *compose.System, invs []*model.Invariant, limits explore.Limits) (*Violation, *explore.Stats, error) {
+	e := explore.New(sys, explore.BFS, limits)
+	for n, err := range e.Nodes() {
+		if err != nil {
+			return nil, e.Stats(), err
+		}
+
+		inv, err := Violated(sys, invs, n.State)
+		if err != nil {
+			return nil, e.Stats(), err
+		}
+		if inv != nil {
+			return InvariantViolation(inv, n.Trace(), n.State), e.Stats(), nil
+		}
+	}
+
+	return nil, e.Stats(), nil
+}
+
+// Violated returns the first invariant that is false in the state, or nil if they all may hold.
+func Violated(sys *compose.System, invs []*model.Invariant, s *compose.State) (*model.Invariant, error) {
+	for _, inv := range invs {
+		val, err := eval.Eval(inv.Expr, sys.Scope(s))
+		if err != nil {
+			return nil, fmt.Errorf("invariant %s: %w", inv.Name, err)
+		}
+
+		if b, known := eval.Truthy(val); !b && known {
+			return inv, nil
+		}
+	}
+	return nil, nil
+}
+
+// InvariantViolation returns the violation of the invariant by the state that the trace reaches.
+func InvariantViolation(inv *model.Invariant, trace []*compose.Step, s *compose.State) *Violation {
+	reason := fmt.Sprintf("%s is false", inv.Expr)
+	if inv.Location != "" {
+		reason += fmt.Sprintf(" (%s)", inv.Location)
+	}
+	return &Violation{
+		Property: fmt.Sprintf("invariant %s", inv.Name),
+		Trace:    trace,
+		State:    s,
+		Reasons:  []string{reason},
+	}
+}
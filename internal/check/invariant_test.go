@@ -0,0 +1,89 @@
+package check
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/explore"
+	"github.com/stretchr/testify/assert"
+)
+
+const lock = `
+	(defprocess Lock
+	  (loop
+	    (?receive :message acquire :from owner)
+	    (!send :message granted :to owner)
+	    (?receive :message release)))
+	(defprocess Worker (id)
+	  (let ((owner false))
+	    (loop
+	      (!send :message acquire :to Lock)
+	      (?receive :message granted)
+	      (set! owner true)
+	      (set! owner false)
+	      (!send :message release :to Lock))))
+	(defsystem Lock (Worker 1) (Worker 2))
+`
+
+func TestInvariants(t *testing.T) {
+	var tests = []struct {
+		name      string
+		str       string
+		expTrace  int
+		expReason string
+		expErr    string
+	}{
+		{
+			name: "holds",
+			str: lock + `(definvariant at-most-one-owner
+			               (<= (count (var (Worker 1) :owner) (var (Worker 2) :owner)) 1))`,
+		},
+		{
+			name: "violated",
+			str: lock + `(definvariant never-owned
+			               (not (var (Worker 2) :owner)))`,
+			expTrace:  6,
+			expReason: "(not (var (Worker 2) :owner)) is false (<string>:16:1)",
+		},
+		{
+			name: "states and channels",
+			str: lock + `(definvariant granted-when-waiting
+			               (or (empty? (channel Lock (Worker 1)))
+			                   (at (Worker 1) :start)))`,
+			expTrace:  4,
+			expReason: "(or (empty? (channel Lock (Worker 1))) (at (Worker 1) :start)) is false (<string>:16:1)",
+		},
+		{
+			name:   "unknown variable",
+			str:    lock + `(definvariant typo (var Lock :count))`,
+			expErr: "invariant typo: var: Lock has no variable count",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Invariants - %s", test.name), func(t *testing.T) {
+			m, err := lisp.LoadString(test.str)
+			assert.Nil(t, err)
+
+			sys := newSystem(t, test.str)
+			v, stats, err := Invariants(sys, m.Invariants, explore.Limits{})
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.True(t, stats.Complete())
+			if test.expReason == "" {
+				assert.Nil(t, v)
+				return
+			}
+
+			assert.NotNil(t, v)
+			assert.Equal(t, test.expTrace, len(v.Trace))
+			assert.Equal(t, []string{test.expReason}, v.Reasons)
+		})
+	}
+}
@@ -0,0 +1,476 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/eval"
+	"dberk.nl/graphchecker/internal/explore"
+	"dberk.nl/graphchecker/internal/ltl"
+	"dberk.nl/graphchecker/internal/lts"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// product is the product of the system and the automaton of the negation of a property. Its runs are the runs of the
+// system that violate the property.
+type product struct {
+	sys    *compose.System
+	aut    *ltl.Automaton
+	limits explore.Limits
+	nodes  []*pnode
+	byKey  map[string]*pnode
+	steps  map[string][]*compose.Step
+	stats  explore.Stats
+}
+
+// pnode is a state of the product: a state of the system, the value of the atoms at that state, and a state of the
+// automaton.
+type pnode struct {
+	id    int
+	state *compose.State
+	q     int
+	depth int
+	// parent and step lead to the node along a shortest path from an initial node
+	parent *pnode
+	step   *compose.Step
+	edges  []*pedge
+}
+
+type pedge struct {
+	step *compose.Step
+	to   *pnode
+}
+
+// Property explores the product of the system and the negation of the property, and returns a run of the system that
+// violates the property, or nil if every run satisfies it. The violating run consists of a trace to a state, followed
+// by a cycle that is repeated forever.
+//
+// The atoms of the property are evaluated at every state of a run. (sent m) and (received m) hold if the step that led
+// to the state sent or received m, every other atom is evaluated as an invariant is, and is false if its value is
+// unknown. A run that ends in a state without successors stays in that state forever.
+//
+// Only runs that are fair with respect to the fairness assumptions violate the property. If every violating run is
+// unfair, then one of them is returned nonetheless, with the assumptions that it violates.
+func Property(sys *compose.System, prop *model.Property, fairness []*model.Fairness, limits explore.Limits) (*Violation, *explore.Stats, error) {
+	f, err := ltl.Parse(prop.Formula)
+	if err != nil {
+		return nil, nil, fmt.Errorf("property %s: %w", prop.Name, err)
+	}
+
+	fair, err := constraints(sys, fairness)
+	if err != nil {
+		return nil, nil, fmt.Errorf("property %s: %w", prop.Name, err)
+	}
+
+	p := &product{
+		sys:    sys,
+		aut:    ltl.Translate(&ltl.Formula{Op: ltl.Not, Left: f}),
+		limits: limits,
+		nodes:  []*pnode{},
+		byKey:  map[string]*pnode{},
+		steps:  map[string][]*compose.Step{},
+	}
+
+	start := time.Now()
+	err = p.build(start)
+	p.stats.Duration = time.Since(start)
+	if err != nil {
+		return nil, &p.stats, fmt.Errorf("property %s: %w", prop.Name, err)
+	}
+
+	unfair := []string{}
+	entry, cycle := p.lasso(fair)
+	if entry == nil && len(fair) != 0 {
+		entry, cycle = p.lasso(nil)
+		if entry != nil {
+			nodes := []*pnode{entry}
+			for _, e := range cycle {
+				nodes = append(nodes, e.to)
+			}
+			unfair = p.violated(nodes, cycle, fair)
+		}
+	}
+
+	if entry == nil {
+		return nil, &p.stats, nil
+	}
+
+	reason := fmt.Sprintf("%s does not hold", prop.Formula)
+	if prop.Location != "" {
+		reason += fmt.Sprintf(" (%s)", prop.Location)
+	}
+	return &Violation{
+		Property: fmt.Sprintf("property %s", prop.Name),
+		Trace:    entry.trace(),
+		Cycle:    edgeSteps(cycle),
+		State:    entry.state,
+		Reasons:  []string{reason},
+		Unfair:   unfair,
+	}, &p.stats, nil
+}
+
+// build constructs the reachable part of the product breadth first, up to the limits.
+func (p *product) build(start time.Time) error {
+	init := p.sys.Initial()
+	val, err := p.valuation(init, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, q := range p.aut.Initial {
+		if p.aut.States[q].Enabled(val) {
+			p.add(init, q, nil, nil)
+		}
+	}
+
+	for idx := 0; idx < len(p.nodes); idx++ {
+		if p.limits.Timeout != 0 && p.limits.Timeout <= time.Since(start) {
+			p.stats.Stopped = fmt.Sprintf("timeout of %s", p.limits.Timeout)
+			return nil
+		}
+
+		n := p.nodes[idx]
+		p.stats.MaxDepth = max(p.stats.MaxDepth, n.depth)
+		if p.limits.MaxDepth != 0 && p.limits.MaxDepth <= n.depth {
+			p.stats.Stopped = fmt.Sprintf("maximum depth of %d", p.limits.MaxDepth)
+			continue
+		}
+
+		steps, err := p.successors(n.state)
+		if err != nil {
+			return err
+		}
+
+		for _, step := range steps {
+			val, err := p.valuation(step.Target, step)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range p.aut.States[n.q].Succ {
+				if !p.aut.States[r].Enabled(val) {
+					continue
+				}
+
+				to, ok := p.byKey[key(step.Target, r)]
+				if !ok {
+					if p.limits.MaxStates != 0 && p.limits.MaxStates <= len(p.nodes) {
+						p.stats.Stopped = fmt.Sprintf("maximum of %d states", p.limits.MaxStates)
+						continue
+					}
+					to = p.add(step.Target, r, n, step)
+				}
+
+				n.edges = append(n.edges, &pedge{step, to})
+				p.stats.Transitions++
+			}
+		}
+	}
+	return nil
+}
+
+func key(s *compose.State, q int) string {
+	return fmt.Sprintf("%s#%d", s.Key(), q)
+}
+
+func (p *product) add(s *compose.State, q int, parent *pnode, step *compose.Step) *pnode {
+	n := &pnode{id: len(p.nodes), state: s, q: q, parent: parent, step: step}
+	if parent != nil {
+		n.depth = parent.depth + 1
+	}
+
+	p.nodes = append(p.nodes, n)
+	p.byKey[key(s, q)] = n
+	p.stats.States++
+	return n
+}
+
+// successors returns the steps of the system from the state. A state without successors gets a step to itself, so
+// that every run is infinite.
+func (p *product) successors(s *compose.State) ([]*compose.Step, error) {
+	k := s.Key()
+	if steps, ok := p.steps[k]; ok {
+		return steps, nil
+	}
+
+	steps, err := p.sys.Successors(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.sys.Describe(s), err)
+	}
+	if len(steps) == 0 {
+		steps = []*compose.Step{{Label: lts.TauLabel, Note: "no step is possible, the system stays in this state", Target: s}}
+	}
+
+	p.steps[k] = steps
+	return steps, nil
+}
+
+// valuation evaluates the atoms of the automaton at a state, step is the step that led to the state.
+func (p *product) valuation(s *compose.State, step *compose.Step) ([]bool, error) {
+	val := []bool{}
+	for _, atom := range p.aut.Atoms {
+		if name, msg, ok := actionAtom(atom); ok {
+			val = append(val, step != nil && performs(step, name, msg))
+			continue
+		}
+
+		v, err := eval.Eval(atom, p.sys.Scope(s))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", atom, err)
+		}
+		b, _ := eval.Truthy(v)
+		val = append(val, b)
+	}
+	return val, nil
+}
+
+// actionAtom recognizes the atoms (sent message) and (received message).
+func actionAtom(atom *model.Expression) (string, string, bool) {
+	if atom.Type != "lst" || len(atom.Sub) != 2 || atom.Sub[0].Type != "ref" || atom.Sub[1].Type != "ref" {
+		return "", "", false
+	}
+
+	switch atom.Sub[0].Ref {
+	case "sent", "received":
+		return atom.Sub[0].Ref, atom.Sub[1].Ref, true
+	default:
+		return "", "", false
+	}
+}
+
+func performs(step *compose.Step, action, msg string) bool {
+	for _, m := range step.Moves {
+		if action == "sent" && m.Transition.Send == msg || action == "received" && m.Transition.Receive == msg {
+			return true
+		}
+	}
+	return false
+}
+
+// lasso finds an accepting cycle of the product that is reachable from an initial node, and that is fair with respect
+// to the constraints. It returns the node at which the cycle starts and the edges of the cycle, or nil if there is no
+// such cycle. Of all such cycles, it picks one that is entered by a shortest trace.
+func (p *product) lasso(fair []*constraint) (*pnode, []*pedge) {
+	var entry *pnode
+	var component map[*pnode]bool
+	p.search(p.nodes, fair, func(c map[*pnode]bool) {
+		for n := range c {
+			if entry == nil || n.id < entry.id {
+				entry, component = n, c
+			}
+		}
+	})
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	// Visit every acceptance set, satisfy every fairness constraint, and return to the entry.
+	cycle := []*pedge{}
+	cur := entry
+	follow := func(path []*pedge) {
+		cycle = append(cycle, path...)
+		if len(path) != 0 {
+			cur = path[len(path)-1].to
+		}
+	}
+
+	for set := range p.aut.Sets {
+		follow(p.path(cur, component, false, func(n *pnode) bool { return p.aut.States[n.q].Accepting[set] }))
+	}
+
+	for _, c := range fair {
+		if e := p.taken(component, c); e != nil {
+			follow(p.path(cur, component, false, func(n *pnode) bool { return n == e.from }))
+			follow([]*pedge{e.edge})
+		} else if !c.Strong {
+			follow(p.path(cur, component, false, func(n *pnode) bool { return !p.enabled(n, c) }))
+		}
+	}
+
+	follow(p.path(cur, component, len(cycle) == 0, func(n *pnode) bool { return n == entry }))
+	return entry, cycle
+}
+
+// search calls found for every strongly connected component of the nodes that contains an accepting cycle that is fair
+// with respect to the constraints.
+//
+// A component that violates a strong fairness constraint, i.e. in which the constraint is enabled but never taken, may
+// still contain a fair cycle that avoids the nodes at which the constraint is enabled. Those nodes are removed, and the
+// rest of the component is searched again.
+func (p *product) search(nodes []*pnode, fair []*constraint, found func(component map[*pnode]bool)) {
+	for _, component := range p.components(nodes) {
+		if !p.accepting(component) {
+			continue
+		}
+
+		fairComponent := true
+		for _, c := range fair {
+			if p.taken(component, c) != nil {
+				continue
+			}
+
+			rest := []*pnode{}
+			for n := range component {
+				if !p.enabled(n, c) {
+					rest = append(rest, n)
+				}
+			}
+
+			switch {
+			case len(rest) == len(component):
+				continue
+			case !c.Strong && len(rest) != 0:
+				continue
+			case c.Strong:
+				sort.Slice(rest, func(i, j int) bool { return rest[i].id < rest[j].id })
+				p.search(rest, fair, found)
+			}
+			fairComponent = false
+			break
+		}
+
+		if fairComponent {
+			found(component)
+		}
+	}
+}
+
+// components returns the strongly connected components of the subgraph of the product that consists of the nodes.
+func (p *product) components(nodes []*pnode) []map[*pnode]bool {
+	within := map[*pnode]bool{}
+	for _, n := range nodes {
+		within[n] = true
+	}
+
+	index := map[*pnode]int{}
+	low := map[*pnode]int{}
+	onStack := map[*pnode]bool{}
+	stack := []*pnode{}
+	components := []map[*pnode]bool{}
+
+	var connect func(n *pnode)
+	connect = func(n *pnode) {
+		index[n], low[n] = len(index), len(index)
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for _, e := range n.edges {
+			if !within[e.to] {
+				continue
+			}
+
+			if _, ok := index[e.to]; !ok {
+				connect(e.to)
+				low[n] = min(low[n], low[e.to])
+			} else if onStack[e.to] {
+				low[n] = min(low[n], index[e.to])
+			}
+		}
+
+		if low[n] != index[n] {
+			return
+		}
+
+		component := map[*pnode]bool{}
+		for {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[m] = false
+			component[m] = true
+			if m == n {
+				break
+			}
+		}
+		components = append(components, component)
+	}
+
+	for _, n := range nodes {
+		if _, ok := index[n]; !ok {
+			connect(n)
+		}
+	}
+	return components
+}
+
+// accepting returns whether the component contains a cycle that visits every acceptance set.
+func (p *product) accepting(component map[*pnode]bool) bool {
+	if len(component) == 1 {
+		for n := range component {
+			cyclic := false
+			for _, e := range n.edges {
+				cyclic = cyclic || e.to == n
+			}
+			if !cyclic {
+				return false
+			}
+		}
+	}
+
+	for set := range p.aut.Sets {
+		found := false
+		for n := range component {
+			found = found || p.aut.States[n.q].Accepting[set]
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// path returns a shortest path within the component from the node to a node that satisfies the target. If nonEmpty is
+// set, then the path takes at least one step.
+func (p *product) path(from *pnode, component map[*pnode]bool, nonEmpty bool, target func(*pnode) bool) []*pedge {
+	if !nonEmpty && target(from) {
+		return []*pedge{}
+	}
+
+	via := map[*pnode]*pedge{}
+	prev := map[*pnode]*pnode{}
+	queue := []*pnode{from}
+	for len(queue) != 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		for _, e := range n.edges {
+			if !component[e.to] {
+				continue
+			}
+
+			if target(e.to) {
+				path := []*pedge{e}
+				for cur := n; cur != from; cur = prev[cur] {
+					path = append([]*pedge{via[cur]}, path...)
+				}
+				return path
+			}
+
+			if _, ok := via[e.to]; !ok && e.to != from {
+				via[e.to] = e
+				prev[e.to] = n
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	return []*pedge{}
+}
+
+func edgeSteps(path []*pedge) []*compose.Step {
+	ss := []*compose.Step{}
+	for _, e := range path {
+		ss = append(ss, e.step)
+	}
+	return ss
+}
+
+// trace returns the steps from an initial node to the node.
+func (n *pnode) trace() []*compose.Step {
+	ss := []*compose.Step{}
+	for cur := n; cur.parent != nil; cur = cur.parent {
+		ss = append([]*compose.Step{cur.step}, ss...)
+	}
+	return ss
+}
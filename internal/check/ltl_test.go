@@ -0,0 +1,199 @@
+package check
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/explore"
+	"github.com/stretchr/testify/assert"
+)
+
+const pingPong = `
+	(defprocess Client
+	  (loop
+	    (!send :message ping :to Server)
+	    (?receive :message pong)))
+	(defprocess Server
+	  (loop
+	    (?receive :message ping :from client)
+	    (!send :message pong :to client)))
+`
+
+func TestProperty(t *testing.T) {
+	var tests = []struct {
+		name     string
+		str      string
+		expTrace int
+		expCycle int
+		expErr   string
+	}{
+		{
+			name: "every ping is answered",
+			str:  pingPong + `(defproperty answered (G (-> (sent ping) (F (received pong)))))`,
+		},
+		{
+			name: "state predicates",
+			str:  pingPong + `(defproperty returns (G (F (at Client :start))))`,
+		},
+		{
+			name: "no answer",
+			str: `(defprocess Client
+			        (!send :message ping :to Server)
+			        (?receive :message pong))
+			      (defprocess Server
+			        (?receive :message ping))
+			      (defproperty answered (G (-> (sent ping) (F (received pong)))))`,
+			expTrace: 2,
+			expCycle: 1,
+		},
+		{
+			name: "pings are ignored forever",
+			str: `(defprocess Client
+			        (loop (!send :message ping :to Server)))
+			      (defprocess Server
+			        (loop (?receive :message ping)))
+			      (defproperty answered (G (F (sent pong))))`,
+			expTrace: 1,
+			expCycle: 4,
+		},
+		{
+			name:   "invalid formula",
+			str:    pingPong + `(defproperty broken (G (sent ping) (sent pong)))`,
+			expErr: "property broken: G: expected 1 argument(s), got 2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Property - %s", test.name), func(t *testing.T) {
+			m, err := lisp.LoadString(test.str)
+			assert.Nil(t, err)
+
+			v, stats, err := Property(newSystem(t, test.str), m.Properties[0], m.Fairness, explore.Limits{})
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.True(t, stats.Complete())
+			if test.expCycle == 0 {
+				assert.Nil(t, v)
+				return
+			}
+
+			assert.NotNil(t, v)
+			assert.Equal(t, test.expTrace, len(v.Trace))
+			assert.Equal(t, test.expCycle, len(v.Cycle))
+		})
+	}
+}
+
+func TestFormatLasso(t *testing.T) {
+	str := `(defprocess Client
+  (!send :message ping :to Server)
+  (?receive :message pong))
+(defprocess Server
+  (?receive :message ping))
+(defproperty answered (F (received pong)))`
+
+	m, err := lisp.LoadString(str)
+	assert.Nil(t, err)
+
+	sys := newSystem(t, str)
+	v, _, err := Property(sys, m.Properties[0], m.Fairness, explore.Limits{})
+	assert.Nil(t, err)
+	assert.Equal(t, `property answered is violated by a run of 2 step(s) that ends in a cycle of 1 step(s)
+  0. Client@:start, Server@:start
+  1. Client !ping, to Server  (<string>:2:3)
+  2. Server ?ping, from Client  (<string>:5:3)
+state: Client@#2, Server@#2
+then, repeated forever:
+  3. no step is possible, the system stays in this state
+  - (F (received pong)) does not hold (<string>:6:1)
+`, v.Format(sys))
+}
+
+func TestPropertyFairness(t *testing.T) {
+	const spinner = `
+		(defprocess Spinner
+		  (let ((n 0))
+		    (loop (set! n (- 1 n)))))
+		(defprocess Reporter
+		  (!send :message done)
+		  :done :final)
+		(defproperty reports (F (sent done)))
+	`
+	const lossy = `
+		(defprocess Client
+		  (loop (!send :message req :to Server)))
+		(defprocess Server
+		  (loop (?receive :message req)))
+		(defchannel :from Client :to Server :semantics lossy :capacity 1)
+		(defproperty delivered (G (F (received req))))
+	`
+
+	var tests = []struct {
+		name      string
+		str       string
+		expFair   bool
+		expUnfair []string
+	}{
+		{
+			name:    "without fairness",
+			str:     spinner,
+			expFair: true,
+		},
+		{
+			name:      "weakly fair process",
+			str:       spinner + "(fair :weak Reporter)",
+			expUnfair: []string{"(fair :weak Reporter)"},
+		},
+		{
+			name:      "weakly fair transition",
+			str:       spinner + "(fair :weak (Reporter !send done))",
+			expUnfair: []string{"(fair :weak (Reporter !send done))"},
+		},
+		{
+			name:    "fairness of another process",
+			str:     spinner + "(fair :weak Spinner)",
+			expFair: true,
+		},
+		{
+			name:    "lossy channel",
+			str:     lossy,
+			expFair: true,
+		},
+		{
+			name:    "weakly fair lossy channel",
+			str:     lossy + "(fair :weak (channel Client Server))",
+			expFair: true,
+		},
+		{
+			name:    "strongly fair lossy channel, but the server may stop",
+			str:     lossy + "(fair :strong (channel Client Server))",
+			expFair: true,
+		},
+		{
+			name:      "strongly fair lossy channel",
+			str:       lossy + "(fair :strong (channel Client Server)) (fair :weak Server)",
+			expUnfair: []string{"(fair :strong (channel Client Server))", "(fair :weak Server)"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Property - %s", test.name), func(t *testing.T) {
+			m, err := lisp.LoadString(test.str)
+			assert.Nil(t, err)
+
+			v, _, err := Property(newSystem(t, test.str), m.Properties[0], m.Fairness, explore.Limits{})
+			assert.Nil(t, err)
+			assert.NotNil(t, v)
+			assert.Equal(t, test.expFair, v.Fair())
+			if !test.expFair {
+				assert.Equal(t, test.expUnfair, v.Unfair)
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+// package check verifies properties of a composed system, and explains violations with the traces that lead to them
+package check
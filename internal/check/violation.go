@@ -0,0 +1,77 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/compose"
+)
+
+// Violation is a reachable state of the system that violates a property, together with a trace that reaches it.
+type Violation struct {
+	// Property names the property that is violated, e.g. deadlock freedom
+	Property string
+	Trace    []*compose.Step
+	// Cycle is repeated forever after the trace, for violations of properties of infinite runs. It is nil otherwise.
+	Cycle []*compose.Step
+	State *compose.State
+	// Reasons explain why the state violates the property
+	Reasons []string
+	// Unfair contains the fairness assumptions that the run violates. Such a run is not a counterexample, it explains
+	// why the property only holds under the assumptions.
+	Unfair []string
+}
+
+// Fair returns whether the run respects every fairness assumption, i.e. whether it is a genuine counterexample.
+func (v *Violation) Fair() bool {
+	return len(v.Unfair) == 0
+}
+
+// Format renders the violation as a numbered trace, in which every step is followed by the source locations of the
+// statements that it executes.
+func (v *Violation) Format(sys *compose.System) string {
+	var b strings.Builder
+	if v.Cycle == nil {
+		fmt.Fprintf(&b, "%s is violated after %d step(s)\n", v.Property, len(v.Trace))
+	} else {
+		fmt.Fprintf(&b, "%s is violated by a run of %d step(s) that ends in a cycle of %d step(s)\n", v.Property, len(v.Trace), len(v.Cycle))
+	}
+
+	fmt.Fprintf(&b, "  0. %s\n", sys.Describe(sys.Initial()))
+	WriteSteps(&b, v.Trace, 1)
+	fmt.Fprintf(&b, "state: %s\n", sys.Describe(v.State))
+	if v.Cycle != nil {
+		b.WriteString("then, repeated forever:\n")
+		WriteSteps(&b, v.Cycle, len(v.Trace)+1)
+	}
+	for _, reason := range v.Reasons {
+		fmt.Fprintf(&b, "  - %s\n", reason)
+	}
+	for _, f := range v.Unfair {
+		fmt.Fprintf(&b, "  - the run violates the fairness assumption %s\n", f)
+	}
+	return b.String()
+}
+
+// WriteSteps writes numbered steps, starting at first, each followed by the source locations of the statements that it
+// executes.
+func WriteSteps(b *strings.Builder, steps []*compose.Step, first int) {
+	for idx, step := range steps {
+		fmt.Fprintf(b, "  %d. %s", first+idx, step)
+		if locs := Locations(step); len(locs) != 0 {
+			fmt.Fprintf(b, "  (%s)", strings.Join(locs, ", "))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// Locations returns the source locations of the statements that the step executes.
+func Locations(step *compose.Step) []string {
+	locs := []string{}
+	for _, m := range step.Moves {
+		if m.Transition.Location != "" {
+			locs = append(locs, m.Transition.Location)
+		}
+	}
+	return locs
+}
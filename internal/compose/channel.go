@@ -0,0 +1,157 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/eval"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Message is a message in transit from one instance to another.
+type Message struct {
+	Name   string
+	From   string
+	Fields map[string]eval.Value
+}
+
+// key returns a canonical representation of the message, messages with equal keys are indistinguishable.
+func (m Message) key() string {
+	return fmt.Sprintf("%s<-%s", m.String(), m.From)
+}
+
+// String renders the message and its fields, e.g. (taskForKey :task 1).
+func (m Message) String() string {
+	if len(m.Fields) == 0 {
+		return m.Name
+	}
+
+	parts := []string{m.Name}
+	for _, name := range sortedFieldNames(m.Fields) {
+		parts = append(parts, fmt.Sprintf(":%s %s", name, m.Fields[name]))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// channel implements the semantics of a single channel. Buffers are immutable, every operation returns new buffers.
+type channel struct {
+	semantics string
+	capacity  int
+}
+
+// delivery is a message that can be received from a channel, together with the contents of the channel afterwards.
+// pos is the position of the message in the channel, kept is set if a duplicating channel keeps a copy of it.
+type delivery struct {
+	msg  Message
+	rest []Message
+	pos  int
+	kept bool
+}
+
+// transmission is the result of sending a message over a channel.
+type transmission struct {
+	buf  []Message
+	lost bool
+}
+
+// internalStep is a step that a channel takes on its own, e.g. losing a message. pos is the position of the message
+// that the step acts on.
+type internalStep struct {
+	action string
+	buf    []Message
+	pos    int
+}
+
+// newChannel returns the channel from one instance to another, as configured by the model.
+func newChannel(channels []*model.Channel, from, to string) *channel {
+	ch := &channel{semantics: model.ChannelFIFO, capacity: model.DefaultChannelCapacity}
+	for _, c := range channels {
+		if c.Default() {
+			ch = &channel{semantics: c.Semantics, capacity: c.Capacity}
+		}
+	}
+
+	for _, c := range channels {
+		if c.From == from && c.To == to {
+			ch = &channel{semantics: c.Semantics, capacity: c.Capacity}
+		}
+	}
+
+	return ch
+}
+
+// synchronous returns whether messages are handed over directly from sender to receiver.
+func (c *channel) synchronous() bool {
+	return c.semantics == model.ChannelSync
+}
+
+// send returns the possible contents of the channel after the message was sent over it. A full channel blocks the
+// sender, unless it is lossy, in which case the message may get lost.
+func (c *channel) send(buf []Message, msg Message) []transmission {
+	ts := []transmission{}
+	if len(buf) < c.capacity {
+		next := append(append([]Message{}, buf...), msg)
+		if c.semantics == model.ChannelBag {
+			sort.SliceStable(next, func(i, j int) bool {
+				return next[i].key() < next[j].key()
+			})
+		}
+		ts = append(ts, transmission{buf: next})
+	}
+
+	if c.semantics == model.ChannelLossy {
+		ts = append(ts, transmission{buf: buf, lost: true})
+	}
+
+	return ts
+}
+
+// receive returns the messages that can be received from the channel.
+func (c *channel) receive(buf []Message) []delivery {
+	if len(buf) == 0 {
+		return nil
+	}
+
+	deliveries := []delivery{}
+	switch c.semantics {
+	case model.ChannelBag:
+		for idx, msg := range buf {
+			if 0 < idx && buf[idx-1].key() == msg.key() {
+				// Receiving either copy results in the same contents
+				continue
+			}
+
+			rest := append(append([]Message{}, buf[:idx]...), buf[idx+1:]...)
+			deliveries = append(deliveries, delivery{msg: msg, rest: rest, pos: idx})
+		}
+
+	case model.ChannelDuplicating:
+		deliveries = append(deliveries,
+			delivery{msg: buf[0], rest: buf[1:]},
+			delivery{msg: buf[0], rest: buf, kept: true})
+
+	default:
+		deliveries = append(deliveries, delivery{msg: buf[0], rest: buf[1:]})
+	}
+
+	return deliveries
+}
+
+// internal returns the steps that the channel can take on its own.
+func (c *channel) internal(buf []Message) []internalStep {
+	steps := []internalStep{}
+	if c.semantics == model.ChannelReordering {
+		for idx := 1; idx < len(buf); idx++ {
+			if buf[idx-1].key() == buf[idx].key() {
+				continue
+			}
+
+			next := append([]Message{}, buf...)
+			next[idx-1], next[idx] = next[idx], next[idx-1]
+			steps = append(steps, internalStep{action: "reorder " + next[idx-1].Name, buf: next, pos: idx})
+		}
+	}
+
+	return steps
+}
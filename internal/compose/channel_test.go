@@ -0,0 +1,137 @@
+package compose
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	msgA = Message{Name: "a", From: "P"}
+	msgB = Message{Name: "b", From: "P"}
+)
+
+func TestChannelSend(t *testing.T) {
+	var tests = []struct {
+		name  string
+		ch    *channel
+		buf   []Message
+		msg   Message
+		expTs []transmission
+	}{
+		{
+			name:  "fifo appends",
+			ch:    &channel{semantics: model.ChannelFIFO, capacity: 2},
+			buf:   []Message{msgB},
+			msg:   msgA,
+			expTs: []transmission{{buf: []Message{msgB, msgA}}},
+		},
+		{
+			name:  "fifo blocks when full",
+			ch:    &channel{semantics: model.ChannelFIFO, capacity: 1},
+			buf:   []Message{msgB},
+			msg:   msgA,
+			expTs: []transmission{},
+		},
+		{
+			name:  "bag is kept in canonical order",
+			ch:    &channel{semantics: model.ChannelBag, capacity: 2},
+			buf:   []Message{msgB},
+			msg:   msgA,
+			expTs: []transmission{{buf: []Message{msgA, msgB}}},
+		},
+		{
+			name: "lossy may lose",
+			ch:   &channel{semantics: model.ChannelLossy, capacity: 2},
+			buf:  []Message{msgB},
+			msg:  msgA,
+			expTs: []transmission{
+				{buf: []Message{msgB, msgA}},
+				{buf: []Message{msgB}, lost: true},
+			},
+		},
+		{
+			name:  "lossy loses when full",
+			ch:    &channel{semantics: model.ChannelLossy, capacity: 1},
+			buf:   []Message{msgB},
+			msg:   msgA,
+			expTs: []transmission{{buf: []Message{msgB}, lost: true}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("send - %s", test.name), func(t *testing.T) {
+			assert.Equal(t, test.expTs, test.ch.send(test.buf, test.msg))
+		})
+	}
+}
+
+func TestChannelReceive(t *testing.T) {
+	var tests = []struct {
+		name          string
+		ch            *channel
+		buf           []Message
+		expDeliveries []delivery
+	}{
+		{
+			name:          "empty",
+			ch:            &channel{semantics: model.ChannelFIFO, capacity: 2},
+			buf:           []Message{},
+			expDeliveries: nil,
+		},
+		{
+			name:          "fifo delivers the oldest message",
+			ch:            &channel{semantics: model.ChannelFIFO, capacity: 2},
+			buf:           []Message{msgA, msgB},
+			expDeliveries: []delivery{{msg: msgA, rest: []Message{msgB}}},
+		},
+		{
+			name: "bag delivers any message",
+			ch:   &channel{semantics: model.ChannelBag, capacity: 3},
+			buf:  []Message{msgA, msgA, msgB},
+			expDeliveries: []delivery{
+				{msg: msgA, rest: []Message{msgA, msgB}},
+				{msg: msgB, rest: []Message{msgA, msgA}, pos: 2},
+			},
+		},
+		{
+			name: "duplicating may keep the message",
+			ch:   &channel{semantics: model.ChannelDuplicating, capacity: 2},
+			buf:  []Message{msgA, msgB},
+			expDeliveries: []delivery{
+				{msg: msgA, rest: []Message{msgB}},
+				{msg: msgA, rest: []Message{msgA, msgB}, kept: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("receive - %s", test.name), func(t *testing.T) {
+			assert.Equal(t, test.expDeliveries, test.ch.receive(test.buf))
+		})
+	}
+}
+
+func TestChannelInternal(t *testing.T) {
+	fifo := &channel{semantics: model.ChannelFIFO, capacity: 3}
+	assert.Equal(t, []internalStep{}, fifo.internal([]Message{msgA, msgB}))
+
+	reordering := &channel{semantics: model.ChannelReordering, capacity: 3}
+	assert.Equal(t, []internalStep{
+		{action: "reorder b", buf: []Message{msgB, msgA, msgA}, pos: 1},
+		{action: "reorder a", buf: []Message{msgA, msgA, msgB}, pos: 2},
+	}, reordering.internal([]Message{msgA, msgB, msgA}))
+}
+
+func TestNewChannel(t *testing.T) {
+	channels := []*model.Channel{
+		{From: "P", To: "Q", Semantics: model.ChannelLossy, Capacity: 1},
+		{Semantics: model.ChannelBag, Capacity: 3},
+	}
+
+	assert.Equal(t, &channel{semantics: model.ChannelLossy, capacity: 1}, newChannel(channels, "P", "Q"))
+	assert.Equal(t, &channel{semantics: model.ChannelBag, capacity: 3}, newChannel(channels, "Q", "P"))
+	assert.Equal(t, &channel{semantics: model.ChannelFIFO, capacity: model.DefaultChannelCapacity}, newChannel(nil, "Q", "P"))
+}
@@ -0,0 +1,43 @@
+package compose
+
+import (
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/eval"
+)
+
+// Guard evaluates the guard of the transition of the move in the state. It returns nil if the transition has no
+// guard.
+func (sys *System) Guard(s *State, m Move) (eval.Value, error) {
+	if m.Transition.Constraint == nil {
+		return nil, nil
+	}
+
+	val, err := eval.Eval(m.Transition.Constraint, sys.scope(s, sys.byName[m.Instance.Name]))
+	if err != nil {
+		return nil, fmt.Errorf("%s: guard %s: %w", m.Instance.Name, m.Transition.Constraint, err)
+	}
+	return val, nil
+}
+
+// Blocked returns the transitions from the locations of the instances that their guard disables in the state.
+func (sys *System) Blocked(s *State) ([]Move, error) {
+	moves := []Move{}
+	for i, inst := range sys.Instances {
+		for _, t := range inst.outgoing[s.Locs[i]] {
+			enabled, err := sys.enabled(s, i, t)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", inst.Name, err)
+			}
+			if !enabled {
+				moves = append(moves, Move{inst, t})
+			}
+		}
+	}
+	return moves, nil
+}
+
+// Channel returns the messages in flight from one instance to another, by their index.
+func (sys *System) Channel(s *State, from, to int) []Message {
+	return s.Chans[sys.chanIdx(from, to)]
+}
@@ -0,0 +1,2 @@
+// package compose combines the processes of a model into a single system
+package compose
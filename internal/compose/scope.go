@@ -0,0 +1,123 @@
+package compose
+
+import (
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/eval"
+)
+
+// Scope returns the scope in which properties of the state are evaluated, e.g. invariants. Next to the identities of
+// the instances, it provides:
+//   - (at instance :state), whether the instance is in the named state
+//   - (var instance :name), the value of a variable of the instance
+//   - (channel from to), the messages in flight from one instance to another, as maps with the keys :message, :from and
+//     the fields of the message
+//   - chan, the messages in flight over all channels, as maps that also contain the key :to
+func (sys *System) Scope(s *State) eval.Scope {
+	return eval.Chain{
+		eval.Vars{
+			"at":      eval.Func(func(args []eval.Value) (eval.Value, error) { return sys.at(s, args) }),
+			"var":     eval.Func(func(args []eval.Value) (eval.Value, error) { return sys.variable(s, args) }),
+			"channel": eval.Func(func(args []eval.Value) (eval.Value, error) { return sys.channel(s, args) }),
+			"chan":    sys.inFlight(s),
+		},
+		sys.globals,
+	}
+}
+
+// instance resolves the identity of an instance to its index.
+func (sys *System) instance(val eval.Value) (int, error) {
+	id, ok := val.(eval.Ident)
+	if !ok {
+		return -1, fmt.Errorf("expected the identity of an instance, got %s", val)
+	}
+
+	i, ok := sys.byName[string(id)]
+	if !ok {
+		return -1, fmt.Errorf("unknown instance %s", id)
+	}
+	return i, nil
+}
+
+func (sys *System) at(s *State, args []eval.Value) (eval.Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+	}
+
+	i, err := sys.instance(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := args[1].(eval.Keyword)
+	if !ok {
+		return nil, fmt.Errorf("expected the name of a state, got %s", args[1])
+	}
+	return eval.Bool(s.Locs[i].Name == string(name)), nil
+}
+
+func (sys *System) variable(s *State, args []eval.Value) (eval.Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+	}
+
+	i, err := sys.instance(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := args[1].(eval.Keyword)
+	if !ok {
+		return nil, fmt.Errorf("expected the name of a variable, got %s", args[1])
+	}
+
+	idx := sys.Instances[i].varIdx(string(name)[1:])
+	if idx < 0 {
+		return nil, fmt.Errorf("%s has no variable %s", sys.Instances[i].Name, string(name)[1:])
+	}
+	return s.Vars[i][idx], nil
+}
+
+func (sys *System) channel(s *State, args []eval.Value) (eval.Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+	}
+
+	from, err := sys.instance(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := sys.instance(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := eval.List{}
+	for _, msg := range s.Chans[sys.chanIdx(from, to)] {
+		msgs = append(msgs, messageValue(msg))
+	}
+	return msgs, nil
+}
+
+func (sys *System) inFlight(s *State) eval.List {
+	msgs := eval.List{}
+	for from := range sys.Instances {
+		for to, inst := range sys.Instances {
+			for _, msg := range s.Chans[sys.chanIdx(from, to)] {
+				msgs = append(msgs, messageValue(msg).Put(eval.Keyword(":to"), eval.Ident(inst.Name)))
+			}
+		}
+	}
+	return msgs
+}
+
+func messageValue(msg Message) *eval.Map {
+	m := eval.EmptyMap.
+		Put(eval.Keyword(":message"), eval.Ident(msg.Name)).
+		Put(eval.Keyword(":from"), eval.Ident(msg.From))
+	for _, name := range sortedFieldNames(msg.Fields) {
+		m = m.Put(eval.Keyword(":"+name), msg.Fields[name])
+	}
+	return m
+}
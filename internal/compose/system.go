@@ -0,0 +1,800 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/eval"
+	"dberk.nl/graphchecker/internal/lts"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Options configure how the instances of a model are composed.
+type Options struct {
+	// Closed disables inputs from the environment: messages that no instance sends are never received.
+	Closed bool
+}
+
+// System is the parallel composition of the instances of a model.
+//
+// Instances communicate over channels. Messages that are exchanged between instances are internal to the system, and
+// are labelled tau. Messages that are sent to the environment are outputs of the system, and messages that a process
+// receives but that no instance sends are inputs from the environment.
+type System struct {
+	Instances []*Instance
+	// channels contains the channel between every pair of instances, channels[from][to]
+	channels [][]*channel
+	closed   bool
+	// inputs contains the messages that only the environment sends
+	inputs map[string]bool
+	// receivers contains, per message, the instances that can receive it
+	receivers map[string][]int
+	// globals contains the identities of the instances and the constructors of the identities
+	globals eval.Vars
+	byName  map[string]int
+	// external contains the identities of the instances of the model that are not part of the system, messages to
+	// them are outputs to the environment
+	external map[string]bool
+}
+
+// Instance is a process instance of the system.
+type Instance struct {
+	Name    string
+	Process *model.Process
+	// Vars contains the names of the variables of the instance, the parameters of the process come first
+	Vars     []string
+	args     []eval.Value
+	outgoing map[*model.State][]*model.Transition
+}
+
+// New composes the instances of the model. If the model does not declare a system, then every process without
+// parameters is instantiated once.
+func New(m *model.Model, opts Options) (*System, error) {
+	declared := m.System
+	if declared == nil {
+		declared = []*model.Instance{}
+		for _, p := range m.Processes {
+			if len(p.Params) == 0 {
+				declared = append(declared, &model.Instance{Name: p.Name, Process: p})
+			}
+		}
+	}
+
+	if len(declared) == 0 {
+		return nil, fmt.Errorf("the model has no instances")
+	}
+
+	sys := &System{
+		Instances: []*Instance{},
+		closed:    opts.Closed,
+		inputs:    map[string]bool{},
+		receivers: map[string][]int{},
+		globals:   eval.Vars{},
+		byName:    map[string]int{},
+		external:  map[string]bool{},
+	}
+
+	for _, p := range m.Processes {
+		if len(p.Params) == 0 {
+			continue
+		}
+
+		name := p.Name
+		sys.globals[name] = eval.Func(func(args []eval.Value) (eval.Value, error) {
+			parts := []string{name}
+			for _, arg := range args {
+				parts = append(parts, arg.String())
+			}
+			return eval.Ident("(" + strings.Join(parts, " ") + ")"), nil
+		})
+	}
+
+	for idx, decl := range declared {
+		if decl.Name == decl.Process.Name {
+			sys.globals[decl.Name] = eval.Ident(decl.Name)
+		}
+		sys.byName[decl.Name] = idx
+	}
+
+	sent := map[string]bool{}
+	for idx, decl := range declared {
+		inst := &Instance{
+			Name:     decl.Name,
+			Process:  decl.Process,
+			Vars:     append(append([]string{}, decl.Process.Params...), decl.Process.Vars...),
+			args:     []eval.Value{},
+			outgoing: map[*model.State][]*model.Transition{},
+		}
+
+		for _, arg := range decl.Args {
+			val, err := eval.Eval(arg, sys.globals)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", decl.Name, err)
+			}
+			inst.args = append(inst.args, val)
+		}
+
+		for _, t := range decl.Process.Transitions {
+			inst.outgoing[t.From] = append(inst.outgoing[t.From], t)
+
+			if t.Action == model.ActionOutput {
+				sent[t.Send] = true
+			}
+			if t.Action == model.ActionInput && !contains(sys.receivers[t.Receive], idx) {
+				sys.receivers[t.Receive] = append(sys.receivers[t.Receive], idx)
+			}
+		}
+
+		sys.Instances = append(sys.Instances, inst)
+	}
+
+	for mess := range sys.receivers {
+		if !sent[mess] {
+			sys.inputs[mess] = true
+		}
+	}
+
+	sys.channels = [][]*channel{}
+	for _, from := range sys.Instances {
+		chs := []*channel{}
+		for _, to := range sys.Instances {
+			chs = append(chs, newChannel(m.Channels, from.Name, to.Name))
+		}
+		sys.channels = append(sys.channels, chs)
+	}
+
+	return sys, nil
+}
+
+func contains(is []int, i int) bool {
+	for _, j := range is {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}
+
+// Initial returns the initial state of the system: every instance is at the start of its process, its parameters are
+// bound to its arguments and all channels are empty.
+func (sys *System) Initial() *State {
+	s := &State{
+		Locs:  []*model.State{},
+		Vars:  [][]eval.Value{},
+		Chans: make([][]Message, len(sys.Instances)*len(sys.Instances)),
+	}
+
+	for _, inst := range sys.Instances {
+		vars := append([]eval.Value{}, inst.args...)
+		for len(vars) < len(inst.Vars) {
+			vars = append(vars, eval.Nil)
+		}
+
+		s.Locs = append(s.Locs, inst.Process.States[0])
+		s.Vars = append(s.Vars, vars)
+	}
+
+	return s
+}
+
+// Step is a transition of the system.
+type Step struct {
+	// Label is tau for steps that are internal to the system, and the input or output otherwise
+	Label lts.Label
+	// Moves contains the transitions of the instances that take part in the step. It is empty for steps that a channel
+	// takes on its own, and contains both sender and receiver for synchronous communication.
+	Moves []Move
+	// Message is the message that is sent or received, if any
+	Message *Message
+	// Note describes what happened to the message, e.g. that it was lost
+	Note string
+	// Sender and Recipient are the ends of the channel over which a message travels between instances, if any. Lost is
+	// set if the message was lost on the way.
+	Sender, Recipient *Instance
+	Lost              bool
+	// Position is the position in the channel of the message that the step receives, or that a reordering channel
+	// moves forward. Together with the message it tells apart the steps that a bag or reordering channel offers.
+	Position int
+	Target   *State
+}
+
+// Move is a transition of a single instance.
+type Move struct {
+	Instance   *Instance
+	Transition *model.Transition
+}
+
+func (s *Step) String() string {
+	parts := []string{}
+	for _, m := range s.Moves {
+		parts = append(parts, m.String())
+	}
+	if s.Note != "" {
+		parts = append(parts, s.Note)
+	}
+	if s.Label.Observable() {
+		parts = append(parts, fmt.Sprintf("(%s)", s.Label))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (m Move) String() string {
+	t := m.Transition
+	desc := ""
+	switch {
+	case t.Action == model.ActionOutput:
+		desc = fmt.Sprintf("%s !%s", m.Instance.Name, t.Send)
+	case t.Action == model.ActionInput:
+		desc = fmt.Sprintf("%s ?%s", m.Instance.Name, t.Receive)
+	case len(t.Assignments) != 0:
+		assignments := []string{}
+		for _, a := range t.Assignments {
+			assignments = append(assignments, fmt.Sprintf("%s := %s", a.Var, a.Value))
+		}
+		desc = fmt.Sprintf("%s %s", m.Instance.Name, strings.Join(assignments, ", "))
+	default:
+		desc = fmt.Sprintf("%s %s -> %s", m.Instance.Name, stateName(t.From), stateName(t.To))
+	}
+
+	if t.Constraint != nil {
+		desc += fmt.Sprintf(" [%s]", t.Constraint)
+	}
+	return desc
+}
+
+// Successors returns the steps that the system can take from the state.
+func (sys *System) Successors(s *State) ([]*Step, error) {
+	steps := []*Step{}
+	for i, inst := range sys.Instances {
+		for _, t := range inst.outgoing[s.Locs[i]] {
+			enabled, err := sys.enabled(s, i, t)
+			if err != nil {
+				return nil, err
+			}
+			if !enabled {
+				continue
+			}
+
+			var ss []*Step
+			switch t.Action {
+			case model.ActionOutput:
+				ss, err = sys.send(s, i, t)
+			case model.ActionInput:
+				ss, err = sys.receive(s, i, t)
+			default:
+				next := s.advance(i, t)
+				if err = sys.assign(next, i, t); err == nil {
+					ss = []*Step{{Label: lts.TauLabel, Moves: []Move{{inst, t}}, Target: next}}
+				}
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", inst.Name, err)
+			}
+			steps = append(steps, ss...)
+		}
+	}
+
+	for from := range sys.Instances {
+		for to := range sys.Instances {
+			idx := sys.chanIdx(from, to)
+			for _, internal := range sys.channels[from][to].internal(s.Chans[idx]) {
+				next := s.clone()
+				next.Chans[idx] = internal.buf
+				steps = append(steps, &Step{
+					Label:    lts.TauLabel,
+					Note:     fmt.Sprintf("channel %s -> %s: %s", sys.Instances[from].Name, sys.Instances[to].Name, internal.action),
+					Position: internal.pos,
+					Target:   next,
+				})
+			}
+		}
+	}
+
+	return steps, nil
+}
+
+func (sys *System) chanIdx(from, to int) int {
+	return from*len(sys.Instances) + to
+}
+
+// scope returns the scope in which the expressions of instance i are evaluated.
+func (sys *System) scope(s *State, i int) eval.Scope {
+	inst := sys.Instances[i]
+	vars := eval.Vars{"self": eval.Ident(inst.Name)}
+	for idx, name := range inst.Vars {
+		vars[name] = s.Vars[i][idx]
+	}
+	return eval.Chain{vars, sys.globals}
+}
+
+// enabled returns whether the guard of the transition holds. A guard whose value is unknown may hold.
+func (sys *System) enabled(s *State, i int, t *model.Transition) (bool, error) {
+	if t.Constraint == nil {
+		return true, nil
+	}
+
+	val, err := eval.Eval(t.Constraint, sys.scope(s, i))
+	if err != nil {
+		return false, fmt.Errorf("guard %s: %w", t.Constraint, err)
+	}
+
+	b, known := eval.Truthy(val)
+	return b || !known, nil
+}
+
+// recipients returns the instances to which the message of the transition may be sent. An empty slice means that it is
+// sent to the environment.
+func (sys *System) recipients(s *State, i int, t *model.Transition) ([]int, error) {
+	if t.Peer == nil {
+		// An instance doesn't send a message to itself
+		recipients := []int{}
+		for _, j := range sys.receivers[t.Send] {
+			if j != i {
+				recipients = append(recipients, j)
+			}
+		}
+		return recipients, nil
+	}
+
+	val, err := eval.Eval(t.Peer, sys.scope(s, i))
+	if err != nil {
+		return nil, fmt.Errorf("recipient %s: %w", t.Peer, err)
+	}
+
+	switch val {
+	case eval.Unknown, eval.Environment:
+		return []int{}, nil
+	}
+
+	id, ok := val.(eval.Ident)
+	if !ok {
+		return nil, fmt.Errorf("recipient %s: expected the identity of an instance, got %s", t.Peer, val)
+	}
+
+	j, ok := sys.byName[string(id)]
+	if !ok && sys.external[string(id)] {
+		return []int{}, nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("recipient %s: unknown instance %s", t.Peer, id)
+	}
+	return []int{j}, nil
+}
+
+func (sys *System) send(s *State, i int, t *model.Transition) ([]*Step, error) {
+	inst := sys.Instances[i]
+	msg, err := sys.message(s, i, t)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients, err := sys.recipients(s, i, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(recipients) == 0 {
+		return []*Step{{
+			Label:   lts.Label{Kind: lts.Output, Name: t.Send},
+			Moves:   []Move{{inst, t}},
+			Message: msg,
+			Target:  s.advance(i, t),
+		}}, nil
+	}
+
+	steps := []*Step{}
+	for _, j := range recipients {
+		ch := sys.channels[i][j]
+		if ch.synchronous() {
+			ss, err := sys.rendezvous(s, i, t, j, msg)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, ss...)
+			continue
+		}
+
+		idx := sys.chanIdx(i, j)
+		for _, tr := range ch.send(s.Chans[idx], *msg) {
+			next := s.advance(i, t)
+			next.Chans[idx] = tr.buf
+
+			note := fmt.Sprintf("to %s", sys.Instances[j].Name)
+			if tr.lost {
+				note += ", lost"
+			}
+
+			steps = append(steps, &Step{
+				Label:     lts.TauLabel,
+				Moves:     []Move{{inst, t}},
+				Message:   msg,
+				Note:      note,
+				Sender:    inst,
+				Recipient: sys.Instances[j],
+				Lost:      tr.lost,
+				Target:    next,
+			})
+		}
+	}
+
+	return steps, nil
+}
+
+// rendezvous returns the steps in which instance i hands over the message directly to instance j.
+func (sys *System) rendezvous(s *State, i int, send *model.Transition, j int, msg *Message) ([]*Step, error) {
+	steps := []*Step{}
+	for _, recv := range sys.Instances[j].outgoing[s.Locs[j]] {
+		if recv.Receive != msg.Name {
+			continue
+		}
+
+		enabled, err := sys.enabled(s, j, recv)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sys.Instances[j].Name, err)
+		}
+		if !enabled {
+			continue
+		}
+
+		next := s.advance(i, send)
+		next.Locs[j] = recv.To
+		sys.bind(next, j, recv, msg)
+
+		steps = append(steps, &Step{
+			Label:     lts.TauLabel,
+			Moves:     []Move{{sys.Instances[i], send}, {sys.Instances[j], recv}},
+			Message:   msg,
+			Note:      "synchronous",
+			Sender:    sys.Instances[i],
+			Recipient: sys.Instances[j],
+			Target:    next,
+		})
+	}
+
+	return steps, nil
+}
+
+func (sys *System) receive(s *State, j int, t *model.Transition) ([]*Step, error) {
+	inst := sys.Instances[j]
+	steps := []*Step{}
+	for i := range sys.Instances {
+		ch := sys.channels[i][j]
+		if ch.synchronous() {
+			// Handled by the sender
+			continue
+		}
+
+		idx := sys.chanIdx(i, j)
+		for _, d := range ch.receive(s.Chans[idx]) {
+			if d.msg.Name != t.Receive {
+				continue
+			}
+
+			msg := d.msg
+			next := s.advance(j, t)
+			next.Chans[idx] = d.rest
+			sys.bind(next, j, t, &msg)
+
+			note := fmt.Sprintf("from %s", msg.From)
+			if d.kept {
+				note += ", duplicated"
+			}
+
+			steps = append(steps, &Step{
+				Label:     lts.TauLabel,
+				Moves:     []Move{{inst, t}},
+				Message:   &msg,
+				Note:      note,
+				Sender:    sys.Instances[i],
+				Recipient: inst,
+				Position:  d.pos,
+				Target:    next,
+			})
+		}
+	}
+
+	if sys.inputs[t.Receive] && !sys.closed {
+		msg := &Message{Name: t.Receive, From: string(eval.Environment)}
+		next := s.advance(j, t)
+		sys.bind(next, j, t, msg)
+
+		steps = append(steps, &Step{
+			Label:   lts.Label{Kind: lts.Input, Name: t.Receive},
+			Moves:   []Move{{inst, t}},
+			Message: msg,
+			Target:  next,
+		})
+	}
+
+	return steps, nil
+}
+
+// message evaluates the message that is sent by the transition.
+func (sys *System) message(s *State, i int, t *model.Transition) (*Message, error) {
+	msg := &Message{Name: t.Send, From: sys.Instances[i].Name}
+	if len(t.Valuation) == 0 {
+		return msg, nil
+	}
+
+	msg.Fields = map[string]eval.Value{}
+	for name, expr := range t.Valuation {
+		val, err := eval.Eval(expr, sys.scope(s, i))
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		msg.Fields[strings.TrimPrefix(name, ":")] = val
+	}
+	return msg, nil
+}
+
+// bind assigns the sender of a received message to the variable of the receive transition, if it names one, and the
+// fields of the message to the variables that the transition binds them to. The fields of messages that are sent by
+// the environment are unknown, fields that the sender did not set are nil.
+func (sys *System) bind(s *State, j int, t *model.Transition, msg *Message) {
+	inst := sys.Instances[j]
+	if t.Peer != nil && t.Peer.Type == "ref" {
+		s.set(j, inst.varIdx(t.Peer.Ref), eval.Ident(msg.From))
+	}
+
+	for name, field := range t.Bindings {
+		val, ok := msg.Fields[field]
+		switch {
+		case msg.From == string(eval.Environment):
+			val = eval.Unknown
+		case !ok:
+			val = eval.Nil
+		}
+		s.set(j, inst.varIdx(name), val)
+	}
+}
+
+// assign performs the assignments of the transition in order, each one sees the result of the previous ones.
+func (sys *System) assign(s *State, i int, t *model.Transition) error {
+	for _, a := range t.Assignments {
+		val, err := eval.Eval(a.Value, sys.scope(s, i))
+		if err != nil {
+			return fmt.Errorf("%s: %w", a.Var, err)
+		}
+		s.set(i, sys.Instances[i].varIdx(a.Var), val)
+	}
+	return nil
+}
+
+func (inst *Instance) varIdx(name string) int {
+	for idx := len(inst.Vars) - 1; 0 <= idx; idx-- {
+		if inst.Vars[idx] == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// State is a state of the system: the location of every instance in its process, the values of the variables of
+// every instance and the contents of every channel.
+type State struct {
+	Locs  []*model.State
+	Vars  [][]eval.Value
+	Chans [][]Message
+}
+
+// clone returns a copy of the state that can be modified without affecting the original. Per instance variables are
+// copied on write by set.
+func (s *State) clone() *State {
+	return &State{
+		Locs:  append([]*model.State{}, s.Locs...),
+		Vars:  append([][]eval.Value{}, s.Vars...),
+		Chans: append([][]Message{}, s.Chans...),
+	}
+}
+
+// advance returns a copy of the state in which instance i took the transition.
+func (s *State) advance(i int, t *model.Transition) *State {
+	next := s.clone()
+	next.Locs[i] = t.To
+	return next
+}
+
+func (s *State) set(i, idx int, val eval.Value) {
+	if idx < 0 {
+		return
+	}
+
+	vars := append([]eval.Value{}, s.Vars[i]...)
+	vars[idx] = val
+	s.Vars[i] = vars
+}
+
+// Key returns a canonical representation of the state, states with equal keys are equal.
+func (s *State) Key() string {
+	var b strings.Builder
+	for i, loc := range s.Locs {
+		fmt.Fprintf(&b, "%d", loc.ID)
+		for _, v := range s.Vars[i] {
+			fmt.Fprintf(&b, ",%s", v)
+		}
+		b.WriteString(";")
+	}
+
+	for _, buf := range s.Chans {
+		b.WriteString("|")
+		for _, msg := range buf {
+			b.WriteString(msg.key())
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
+
+// Describe returns a human readable representation of the state.
+func (sys *System) Describe(s *State) string {
+	parts := []string{}
+	for i, inst := range sys.Instances {
+		vars := []string{}
+		for idx, name := range inst.Vars {
+			vars = append(vars, fmt.Sprintf("%s=%s", name, s.Vars[i][idx]))
+		}
+
+		desc := fmt.Sprintf("%s@%s", inst.Name, stateName(s.Locs[i]))
+		if len(vars) != 0 {
+			desc += fmt.Sprintf(" {%s}", strings.Join(vars, " "))
+		}
+		parts = append(parts, desc)
+	}
+
+	for from, src := range sys.Instances {
+		for to, dst := range sys.Instances {
+			buf := s.Chans[sys.chanIdx(from, to)]
+			if len(buf) == 0 {
+				continue
+			}
+
+			msgs := []string{}
+			for _, msg := range buf {
+				msgs = append(msgs, msg.String())
+			}
+			parts = append(parts, fmt.Sprintf("%s->%s [%s]", src.Name, dst.Name, strings.Join(msgs, " ")))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func stateName(s *model.State) string {
+	if s.Named() {
+		return s.Name
+	}
+	return fmt.Sprintf("#%d", s.ID)
+}
+
+// Unfold explores the system and returns it as an explicit LTS. It fails if the system has more than max states.
+func (sys *System) Unfold(max int) (*lts.LTS, error) {
+	l, _, err := sys.UnfoldSteps(max)
+	return l, err
+}
+
+// UnfoldSteps unfolds the system like Unfold does, and also returns the step that every transition of the LTS stands
+// for: steps[s][idx] is the step of the transition l.Transitions[s][idx].
+func (sys *System) UnfoldSteps(max int) (*lts.LTS, [][]*Step, error) {
+	init := sys.Initial()
+	l := lts.New(sys.Describe(init))
+	steps := [][]*Step{{}}
+	indices := map[string]int{init.Key(): l.Initial}
+
+	queue := []*State{init}
+	for len(queue) != 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		successors, err := sys.Successors(s)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		from := indices[s.Key()]
+		for _, step := range successors {
+			key := step.Target.Key()
+			to, ok := indices[key]
+			if !ok {
+				if max <= len(l.States) {
+					return nil, nil, fmt.Errorf("the system has more than %d states", max)
+				}
+
+				to = l.AddState(sys.Describe(step.Target))
+				steps = append(steps, []*Step{})
+				indices[key] = to
+				queue = append(queue, step.Target)
+			}
+
+			l.AddTransition(from, step.Label, to)
+			steps[from] = append(steps[from], step)
+		}
+	}
+
+	return l, steps, nil
+}
+
+// sortedFieldNames returns the names of the fields of a message in a canonical order.
+func sortedFieldNames(fields map[string]eval.Value) []string {
+	names := []string{}
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Isolate composes a single instance of the model as an open system, whose inputs and outputs are the messages that it
+// exchanges with the other instances and with the environment. The name is that of a declared instance, or of a process
+// without parameters.
+func Isolate(m *model.Model, name string) (*System, error) {
+	instances := m.System
+	if instances == nil {
+		instances = []*model.Instance{}
+		for _, p := range m.Processes {
+			instances = append(instances, &model.Instance{Name: p.Name, Process: p})
+		}
+	}
+
+	var isolated *model.Instance
+	for _, inst := range instances {
+		if inst.Name == name {
+			isolated = inst
+		}
+	}
+	if isolated == nil {
+		return nil, fmt.Errorf("unknown instance %s", name)
+	}
+	if m.System == nil && len(isolated.Process.Params) != 0 {
+		return nil, fmt.Errorf("%s has parameters, declare its instances with defsystem", name)
+	}
+
+	sys, err := New(&model.Model{Messages: m.Messages, Processes: m.Processes, System: []*model.Instance{isolated}}, Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	// The inputs are the messages that the other instances may send to the isolated instance, and those that no instance
+	// sends, which come from the environment.
+	sent, sentByOthers := map[string]bool{}, map[string]bool{}
+	for _, inst := range instances {
+		for _, t := range inst.Process.Transitions {
+			if t.Action != model.ActionOutput {
+				continue
+			}
+			sent[t.Send] = true
+			if inst != isolated && mayReach(t, isolated, instances) {
+				sentByOthers[t.Send] = true
+			}
+		}
+	}
+	sys.inputs = map[string]bool{}
+	for mess := range sys.receivers {
+		if sentByOthers[mess] || !sent[mess] {
+			sys.inputs[mess] = true
+		}
+	}
+
+	for _, inst := range instances {
+		if inst != isolated {
+			sys.globals[inst.Name] = eval.Ident(inst.Name)
+			sys.external[inst.Name] = true
+		}
+	}
+	return sys, nil
+}
+
+// mayReach returns whether the send transition may send its message to the instance. It may, unless it names another
+// instance as the recipient.
+func mayReach(t *model.Transition, to *model.Instance, instances []*model.Instance) bool {
+	if t.Peer == nil {
+		return true
+	}
+	for _, inst := range instances {
+		if inst != to && inst.Name == t.Peer.String() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,368 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/lts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnfold(t *testing.T) {
+	var tests = []struct {
+		name        string
+		str         string
+		opts        Options
+		expStates   int
+		expLabels   []string
+		expDeadlock bool
+		expErr      string
+	}{
+		{
+			name: "request and response",
+			str: `(defprocess Client
+			        (loop
+			          (!send :message ping :to Server)
+			          (?receive :message pong)))
+			      (defprocess Server
+			        (loop
+			          (?receive :message ping :from client)
+			          (!send :message pong :to client)))`,
+			expStates: 11,
+			expLabels: []string{"tau"},
+		},
+		{
+			name: "inputs and outputs of an open system",
+			str: `(defprocess Server
+			        (loop
+			          (?receive :message ping :from client)
+			          (!send :message pong :to client)))`,
+			expStates: 4,
+			expLabels: []string{"!pong", "?ping", "tau"},
+		},
+		{
+			name: "unaddressed send isn't delivered to the sender",
+			str: `(defprocess Node
+			        (!send :message ping)
+			        (?receive :message ping))`,
+			expStates:   2,
+			expLabels:   []string{"!ping"},
+			expDeadlock: true,
+		},
+		{
+			name: "branches of an if are internal",
+			str: `(defprocess Server
+			        (let ((n 0))
+			          (loop
+			            (?receive :message ping :from client)
+			            (if (< n 1)
+			              (!send :message pong :to client)
+			              (!send :message busy :to client))
+			            (set! n 1))))`,
+			expStates: 12,
+			expLabels: []string{"!busy", "!pong", "?ping", "tau"},
+		},
+		{
+			name: "closed system ignores the environment",
+			str: `(defprocess Server
+			        (loop
+			          (?receive :message ping :from client)
+			          (!send :message pong :to client)))`,
+			opts:        Options{Closed: true},
+			expStates:   1,
+			expLabels:   []string{},
+			expDeadlock: true,
+		},
+		{
+			name: "synchronous channel",
+			str: `(defprocess Client
+			        (!send :message ping :to Server))
+			      (defprocess Server
+			        (?receive :message ping))
+			      (defchannel :semantics sync)`,
+			expStates:   2,
+			expLabels:   []string{"tau"},
+			expDeadlock: true,
+		},
+		{
+			name: "full channel blocks the sender",
+			str: `(defprocess Client
+			        (loop (!send :message ping :to Server)))
+			      (defprocess Server
+			        :idle
+			        (goto :idle))
+			      (defchannel :semantics fifo :capacity 2)`,
+			expStates: 10,
+			expLabels: []string{"tau"},
+		},
+		{
+			name: "lossy channel",
+			str: `(defprocess Client
+			        (!send :message ping :to Server)
+			        (?receive :message pong))
+			      (defprocess Server
+			        (?receive :message ping :from client)
+			        (!send :message pong :to client))
+			      (defchannel :from Client :to Server :semantics lossy)`,
+			expStates:   6,
+			expLabels:   []string{"tau"},
+			expDeadlock: true,
+		},
+		{
+			name: "parameterised instances",
+			str: `(defprocess Worker (id)
+			        (?receive :message job :from coordinator)
+			        (!send :message done :to coordinator :id id))
+			      (defprocess Coordinator
+			        (!send :message job :to (Worker 1))
+			        (!send :message job :to (Worker 2))
+			        (?receive :message done)
+			        (?receive :message done))
+			      (defsystem (Worker 1) (Worker 2) Coordinator)`,
+			expStates:   20,
+			expLabels:   []string{"tau"},
+			expDeadlock: true,
+		},
+		{
+			name: "unknown recipient",
+			str: `(defprocess Worker (id)
+			        (?receive :message job))
+			      (defprocess Coordinator
+			        (!send :message job :to (Worker 3)))
+			      (defsystem (Worker 1) Coordinator)`,
+			expErr: "unknown instance (Worker 3)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Unfold - %s", test.name), func(t *testing.T) {
+			m, err := lisp.LoadString(test.str)
+			assert.Nil(t, err)
+
+			sys, err := New(m, test.opts)
+			assert.Nil(t, err)
+
+			l, steps, err := sys.UnfoldSteps(1000)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+			assert.Nil(t, err)
+
+			// Every transition of the LTS comes with the step that it stands for.
+			assert.Equal(t, len(l.States), len(steps))
+			for s, ts := range l.Transitions {
+				assert.Equal(t, len(ts), len(steps[s]))
+				for idx, tr := range ts {
+					assert.Equal(t, tr.Label, steps[s][idx].Label)
+				}
+			}
+
+			labels := map[string]bool{}
+			deadlock := false
+			for _, ts := range l.Transitions {
+				for _, t := range ts {
+					labels[t.Label.String()] = true
+				}
+				deadlock = deadlock || len(ts) == 0
+			}
+
+			sorted := []string{}
+			for label := range labels {
+				sorted = append(sorted, label)
+			}
+			sort.Strings(sorted)
+
+			assert.Equal(t, test.expStates, len(l.States))
+			assert.Equal(t, test.expLabels, sorted)
+			assert.Equal(t, test.expDeadlock, deadlock)
+		})
+	}
+}
+
+func TestSuccessors(t *testing.T) {
+	m, err := lisp.LoadString(`
+		(defprocess Worker (id)
+		  (?receive :message job :from coordinator)
+		  (!send :message done :to coordinator :id id))
+		(defprocess Coordinator
+		  (!send :message job :to (Worker 1))
+		  (?receive :message done))
+		(defsystem (Worker 1) Coordinator)`)
+	assert.Nil(t, err)
+
+	sys, err := New(m, Options{})
+	assert.Nil(t, err)
+
+	s := sys.Initial()
+	descs := []string{sys.Describe(s)}
+	for {
+		steps, err := sys.Successors(s)
+		assert.Nil(t, err)
+		if len(steps) == 0 {
+			break
+		}
+
+		assert.Equal(t, 1, len(steps))
+		descs = append(descs, steps[0].String(), sys.Describe(steps[0].Target))
+		s = steps[0].Target
+	}
+
+	assert.Equal(t, []string{
+		"(Worker 1)@:start {id=1 coordinator=nil}, Coordinator@:start",
+		"Coordinator !job, to (Worker 1)",
+		"(Worker 1)@:start {id=1 coordinator=nil}, Coordinator@#2, Coordinator->(Worker 1) [job]",
+		"(Worker 1) ?job, from Coordinator",
+		"(Worker 1)@#2 {id=1 coordinator=Coordinator}, Coordinator@#2",
+		"(Worker 1) !done, to Coordinator",
+		"(Worker 1)@#3 {id=1 coordinator=Coordinator}, Coordinator@#2, (Worker 1)->Coordinator [(done :id 1)]",
+		"Coordinator ?done, from (Worker 1)",
+		"(Worker 1)@#3 {id=1 coordinator=Coordinator}, Coordinator@#3",
+	}, descs)
+}
+
+func TestAssignments(t *testing.T) {
+	m, err := lisp.LoadString(`
+		(defprocess Worker
+		  (let (({key} (?receive :message job))
+		        (count 0))
+		    (set! count (+ count 1))
+		    (!send :message done :to Coordinator :key key :count count)))
+		(defprocess Coordinator
+		  (!send :message job :to Worker :key 7)
+		  (?receive :message done))`)
+	assert.Nil(t, err)
+
+	sys, err := New(m, Options{})
+	assert.Nil(t, err)
+
+	s := sys.Initial()
+	descs := []string{}
+	for {
+		steps, err := sys.Successors(s)
+		assert.Nil(t, err)
+		if len(steps) == 0 {
+			break
+		}
+
+		assert.Equal(t, 1, len(steps))
+		s = steps[0].Target
+		descs = append(descs, sys.Describe(s))
+	}
+
+	assert.Equal(t, []string{
+		"Worker@:start {count=nil key=nil}, Coordinator@#2, Coordinator->Worker [(job :key 7)]",
+		"Worker@#2 {count=nil key=7}, Coordinator@#2",
+		"Worker@#3 {count=0 key=7}, Coordinator@#2",
+		"Worker@#4 {count=1 key=7}, Coordinator@#2",
+		"Worker@#5 {count=1 key=7}, Coordinator@#2, Worker->Coordinator [(done :count 1 :key 7)]",
+		"Worker@#5 {count=1 key=7}, Coordinator@#3",
+	}, descs)
+}
+
+func TestIsolate(t *testing.T) {
+	m, err := lisp.LoadString(`
+		(defprocess Worker (id)
+		  (loop
+		    (?receive :message job :from coordinator)
+		    (!send :message done :to coordinator :id id)))
+		(defprocess Coordinator
+		  (!send :message job :to (Worker 1))
+		  (?receive :message done))
+		(defsystem (Worker 1) Coordinator)`)
+	assert.Nil(t, err)
+
+	sys, err := Isolate(m, "Coordinator")
+	assert.Nil(t, err)
+
+	l, err := sys.Unfold(100)
+	assert.Nil(t, err)
+
+	sa, err := lts.Suspend(l, 100)
+	assert.Nil(t, err)
+
+	traces := []string{}
+	for s, ts := range sa.Transitions {
+		for _, tr := range ts {
+			traces = append(traces, fmt.Sprintf("%d %s %d", s, tr.Label, tr.To))
+		}
+	}
+	assert.Equal(t, []string{"0 !job 1", "1 ?done 2", "1 δ 1", "2 δ 2"}, traces)
+
+	sys, err = Isolate(m, "(Worker 1)")
+	assert.Nil(t, err)
+
+	l, err = sys.Unfold(100)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(l.States))
+
+	// A peer may send the message that the isolated instance also sends itself
+	m, err = lisp.LoadString(`
+		(defprocess Node (id)
+		  (!send :message ping)
+		  (?receive :message ping))
+		(defsystem (Node 1) (Node 2))`)
+	assert.Nil(t, err)
+
+	sys, err = Isolate(m, "(Node 1)")
+	assert.Nil(t, err)
+
+	l, err = sys.Unfold(100)
+	assert.Nil(t, err)
+
+	labels := []string{}
+	for s, ts := range l.Transitions {
+		for _, tr := range ts {
+			labels = append(labels, fmt.Sprintf("%d %s %d", s, tr.Label, tr.To))
+		}
+	}
+	assert.Equal(t, []string{"0 !ping 1", "1 ?ping 2"}, labels)
+
+	_, err = Isolate(m, "Worker")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown instance Worker")
+}
+
+func TestInspect(t *testing.T) {
+	m, err := lisp.LoadString(`
+		(defprocess Server
+		  (let ((n 0))
+		    (loop
+		      (if (< n 1)
+		        (!send :message pong :to Client)
+		        (!send :message busy :to Client))
+		      (set! n 1))))
+		(defprocess Client
+		  :idle
+		  (goto :idle))`)
+	assert.Nil(t, err)
+
+	sys, err := New(m, Options{})
+	assert.Nil(t, err)
+
+	// Step to the if
+	s := sys.Initial()
+	steps, err := sys.Successors(s)
+	assert.Nil(t, err)
+	s = steps[0].Target
+	steps, err = sys.Successors(s)
+	assert.Nil(t, err)
+
+	val, err := sys.Guard(s, steps[0].Moves[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "Server #2 -> #3 [(< n 1)]", steps[0].Moves[0].String())
+	assert.Equal(t, "true", val.String())
+
+	blocked, err := sys.Blocked(s)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(blocked))
+	assert.Equal(t, "Server #2 -> #5 [(not (< n 1))]", blocked[0].String())
+
+	s = steps[0].Target
+	steps, err = sys.Successors(s)
+	assert.Nil(t, err)
+	assert.Equal(t, []Message{{Name: "pong", From: "Server"}}, sys.Channel(steps[0].Target, 0, 1))
+}
@@ -0,0 +1,451 @@
+package ctl
+
+import (
+	"fmt"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/check"
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/eval"
+	"dberk.nl/graphchecker/internal/explore"
+	"dberk.nl/graphchecker/internal/lts"
+)
+
+// Graph is the explored global transition system of a system. Every state has at least one successor: a state from
+// which the system can't take a step has a step to itself.
+type Graph struct {
+	sys    *compose.System
+	States []*compose.State
+	Edges  [][]Edge
+	Stats  *explore.Stats
+	sat    map[*Formula][]bool
+}
+
+// Edge is a step of the system from one state to another, by index.
+type Edge struct {
+	Step *compose.Step
+	To   int
+}
+
+// Explore explores the system up to the limits. If the limits are reached, then the graph only contains the explored
+// states and the steps between them.
+func Explore(sys *compose.System, limits explore.Limits) (*Graph, error) {
+	g := &Graph{sys: sys, States: []*compose.State{}, Edges: [][]Edge{}, sat: map[*Formula][]bool{}}
+
+	e := explore.New(sys, explore.BFS, limits)
+	nodes := []*explore.Node{}
+	for n, err := range e.Nodes() {
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	g.Stats = e.Stats()
+
+	ids := map[string]int{}
+	for _, n := range nodes {
+		ids[n.State.Key()] = len(g.States)
+		g.States = append(g.States, n.State)
+	}
+
+	for idx, n := range nodes {
+		edges := []Edge{}
+		for _, step := range n.Steps {
+			if to, ok := ids[step.Target.Key()]; ok {
+				edges = append(edges, Edge{step, to})
+			}
+		}
+		if len(n.Steps) == 0 {
+			edges = append(edges, Edge{&compose.Step{Label: lts.TauLabel, Note: "no step is possible, the system stays in this state", Target: n.State}, idx})
+		}
+		g.Edges = append(g.Edges, edges)
+	}
+
+	return g, nil
+}
+
+// Result is the answer to a query.
+type Result struct {
+	Formula *Formula
+	Holds   bool
+	// Path is a witness if the formula holds, and a counterexample if it doesn't. It contains the indices of the states
+	// along the path, starting at the initial state. If Loop is not negative, then the last state of the path equals
+	// Path[Loop], and the path continues with the same cycle forever.
+	Path []int
+	Loop int
+}
+
+// Check evaluates the formula at the initial state, and explains the answer with a path if it can.
+func (g *Graph) Check(f *Formula) (*Result, error) {
+	sat, err := g.Sat(f)
+	if err != nil {
+		return nil, err
+	}
+
+	path, loop := g.explain(f, 0, sat[0])
+	return &Result{Formula: f, Holds: sat[0], Path: path, Loop: loop}, nil
+}
+
+// Sat returns, for every state, whether it satisfies the formula.
+func (g *Graph) Sat(f *Formula) ([]bool, error) {
+	if sat, ok := g.sat[f]; ok {
+		return sat, nil
+	}
+
+	args := [][]bool{}
+	for _, arg := range f.Args {
+		sat, err := g.Sat(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, sat)
+	}
+
+	n := len(g.States)
+	sat := make([]bool, n)
+	switch f.Op {
+	case True:
+		for s := range sat {
+			sat[s] = true
+		}
+
+	case False:
+
+	case Atom:
+		for s := range sat {
+			holds, err := g.atom(f, g.States[s])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f, err)
+			}
+			sat[s] = holds
+		}
+
+	case Not:
+		for s := range sat {
+			sat[s] = !args[0][s]
+		}
+
+	case And, Or:
+		for s := range sat {
+			sat[s] = f.Op == And
+			for _, arg := range args {
+				if f.Op == And {
+					sat[s] = sat[s] && arg[s]
+				} else {
+					sat[s] = sat[s] || arg[s]
+				}
+			}
+		}
+
+	case EX, AX:
+		for s := range sat {
+			sat[s] = f.Op == AX
+			for _, e := range g.Edges[s] {
+				if f.Op == EX {
+					sat[s] = sat[s] || args[0][e.To]
+				} else {
+					sat[s] = sat[s] && args[0][e.To]
+				}
+			}
+		}
+
+	case EF:
+		sat = g.eu(all(n), args[0])
+	case EU:
+		sat = g.eu(args[0], args[1])
+	case AF:
+		sat = g.au(all(n), args[0])
+	case AU:
+		sat = g.au(args[0], args[1])
+	case EG:
+		sat = g.eg(args[0])
+	case AG:
+		// AG f = not EF not f
+		sat = not(g.eu(all(n), not(args[0])))
+
+	default:
+		return nil, fmt.Errorf("unknown operator %s", f.Op)
+	}
+
+	g.sat[f] = sat
+	return sat, nil
+}
+
+// atom evaluates an atom at a state. A keyword such as :start holds if every instance is in the state of that name,
+// every other atom is evaluated as an invariant is, and is false if its value is unknown.
+func (g *Graph) atom(f *Formula, s *compose.State) (bool, error) {
+	if f.Expr.Type == "kw" {
+		for _, loc := range s.Locs {
+			if loc.Name != f.Expr.Ref {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	val, err := eval.Eval(f.Expr, g.sys.Scope(s))
+	if err != nil {
+		return false, err
+	}
+	holds, _ := eval.Truthy(val)
+	return holds, nil
+}
+
+func all(n int) []bool {
+	sat := make([]bool, n)
+	for s := range sat {
+		sat[s] = true
+	}
+	return sat
+}
+
+func not(sat []bool) []bool {
+	neg := make([]bool, len(sat))
+	for s := range sat {
+		neg[s] = !sat[s]
+	}
+	return neg
+}
+
+// predecessors returns the predecessors of every state.
+func (g *Graph) predecessors() [][]int {
+	preds := make([][]int, len(g.States))
+	for s, edges := range g.Edges {
+		for _, e := range edges {
+			preds[e.To] = append(preds[e.To], s)
+		}
+	}
+	return preds
+}
+
+// eu returns the states that satisfy E[f U h]: the least set that contains the h states, and the f states that have a
+// successor in the set.
+func (g *Graph) eu(f, h []bool) []bool {
+	preds := g.predecessors()
+	sat := make([]bool, len(g.States))
+	queue := []int{}
+	for s := range sat {
+		if h[s] {
+			sat[s] = true
+			queue = append(queue, s)
+		}
+	}
+
+	for len(queue) != 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, p := range preds[s] {
+			if !sat[p] && f[p] {
+				sat[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	return sat
+}
+
+// au returns the states that satisfy A[f U h]: the least set that contains the h states, and the f states whose
+// successors are all in the set.
+func (g *Graph) au(f, h []bool) []bool {
+	preds := g.predecessors()
+	sat := make([]bool, len(g.States))
+	remaining := make([]int, len(g.States))
+	queue := []int{}
+	for s := range sat {
+		remaining[s] = len(g.Edges[s])
+		if h[s] {
+			sat[s] = true
+			queue = append(queue, s)
+		}
+	}
+
+	for len(queue) != 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, p := range preds[s] {
+			remaining[p]--
+			if !sat[p] && f[p] && remaining[p] == 0 {
+				sat[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	return sat
+}
+
+// eg returns the states that satisfy EG f: the greatest set of f states that each have a successor in the set.
+func (g *Graph) eg(f []bool) []bool {
+	sat := append([]bool{}, f...)
+	for changed := true; changed; {
+		changed = false
+		for s := range sat {
+			if !sat[s] {
+				continue
+			}
+
+			next := false
+			for _, e := range g.Edges[s] {
+				next = next || sat[e.To]
+			}
+			if !next {
+				sat[s], changed = false, true
+			}
+		}
+	}
+	return sat
+}
+
+// explain returns a path from the state that shows why the formula holds, or why it doesn't hold. Formulas that can't
+// be explained by a single path, e.g. an AG that holds, are explained by the state itself.
+func (g *Graph) explain(f *Formula, s int, holds bool) ([]int, int) {
+	sat := g.sat[f]
+	arg := func(idx int) []bool { return g.sat[f.Args[idx]] }
+	then := func(path []int, next *Formula, nextHolds bool) ([]int, int) {
+		rest, loop := g.explain(next, path[len(path)-1], nextHolds)
+		if loop >= 0 {
+			loop += len(path) - 1
+		}
+		return append(path, rest[1:]...), loop
+	}
+
+	switch {
+	case f.Op == Not:
+		return g.explain(f.Args[0], s, !holds)
+
+	case f.Op == And || f.Op == Or:
+		// Explain the first operand that decides the answer.
+		for idx, a := range f.Args {
+			if arg(idx)[s] == holds {
+				return g.explain(a, s, holds)
+			}
+		}
+
+	case f.Op == EX && holds, f.Op == AX && !holds:
+		for _, e := range g.Edges[s] {
+			if arg(0)[e.To] == holds {
+				return then([]int{s, e.To}, f.Args[0], holds)
+			}
+		}
+
+	case f.Op == EF && holds, f.Op == AG && !holds:
+		path := g.search(s, all(len(g.States)), func(t int) bool { return arg(0)[t] == holds })
+		return then(path, f.Args[0], holds)
+
+	case f.Op == EU && holds:
+		path := g.search(s, arg(0), func(t int) bool { return arg(1)[t] })
+		return then(path, f.Args[1], true)
+
+	case f.Op == AU && !holds:
+		// Either the f states lead to a state in which neither f nor h holds, or they go on forever without h.
+		within := not(sat)
+		if path := g.search(s, within, func(t int) bool { return !arg(0)[t] && !arg(1)[t] }); path != nil {
+			return path, -1
+		}
+		return g.lasso(s, within)
+
+	case f.Op == EG && holds:
+		return g.lasso(s, sat)
+
+	case f.Op == AF && !holds:
+		return g.lasso(s, not(sat))
+	}
+
+	return []int{s}, -1
+}
+
+// search returns a shortest path from the state to a target state, through states that are within the set. It returns
+// nil if there is no such path.
+func (g *Graph) search(from int, within []bool, target func(int) bool) []int {
+	prev := map[int]int{from: -1}
+	queue := []int{from}
+	for len(queue) != 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		if target(s) {
+			path := []int{}
+			for cur := s; cur != -1; cur = prev[cur] {
+				path = append([]int{cur}, path...)
+			}
+			return path
+		}
+
+		if !within[s] {
+			continue
+		}
+		for _, e := range g.Edges[s] {
+			if _, ok := prev[e.To]; !ok {
+				prev[e.To] = s
+				queue = append(queue, e.To)
+			}
+		}
+	}
+	return nil
+}
+
+// lasso returns a path from the state that stays within the set forever, given that every state of the set has a
+// successor in the set.
+func (g *Graph) lasso(from int, within []bool) ([]int, int) {
+	visited := map[int]int{}
+	path := []int{}
+	for s := from; ; {
+		if idx, ok := visited[s]; ok {
+			return append(path, s), idx
+		}
+		visited[s] = len(path)
+		path = append(path, s)
+
+		for _, e := range g.Edges[s] {
+			if within[e.To] {
+				s = e.To
+				break
+			}
+		}
+	}
+}
+
+// Format renders the answer, followed by the path that explains it in the layout of a violation.
+func (g *Graph) Format(r *Result) string {
+	var b strings.Builder
+	if r.Holds {
+		fmt.Fprintf(&b, "%s holds\n", r.Formula)
+	} else {
+		fmt.Fprintf(&b, "%s does not hold\n", r.Formula)
+	}
+
+	switch {
+	case len(r.Path) == 1:
+	case r.Holds:
+		b.WriteString("witness:\n")
+	default:
+		b.WriteString("counterexample:\n")
+	}
+
+	last := len(r.Path) - 1
+	if r.Loop >= 0 {
+		last = r.Loop
+	}
+
+	fmt.Fprintf(&b, "  0. %s\n", g.sys.Describe(g.States[r.Path[0]]))
+	check.WriteSteps(&b, g.steps(r.Path[:last+1]), 1)
+	fmt.Fprintf(&b, "state: %s\n", g.sys.Describe(g.States[r.Path[last]]))
+	if r.Loop >= 0 {
+		b.WriteString("then, repeated forever:\n")
+		check.WriteSteps(&b, g.steps(r.Path[last:]), last+1)
+	}
+	return b.String()
+}
+
+// steps returns the steps that take the system along the path.
+func (g *Graph) steps(path []int) []*compose.Step {
+	steps := []*compose.Step{}
+	for idx := 1; idx < len(path); idx++ {
+		for _, e := range g.Edges[path[idx-1]] {
+			if e.To == path[idx] {
+				steps = append(steps, e.Step)
+				break
+			}
+		}
+	}
+	return steps
+}
@@ -0,0 +1,104 @@
+package ctl
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/explore"
+	"github.com/stretchr/testify/assert"
+)
+
+const jobs = `
+	(defprocess Worker
+	  (?receive :message job)
+	  (!send :message done :to Coordinator)
+	  :done
+	  (goto :done))
+	(defprocess Coordinator
+	  (!send :message job :to Worker)
+	  (?receive :message done)
+	  :done
+	  (goto :done))
+`
+
+func TestCheck(t *testing.T) {
+	var tests = []struct {
+		query    string
+		expHolds bool
+		expPath  int
+		expLoop  int
+		expErr   string
+	}{
+		{query: ":start", expHolds: true, expPath: 1, expLoop: -1},
+		{query: "EF (and (at Worker :done) (empty chan))", expHolds: true, expPath: 6, expLoop: -1},
+		{query: "AF :done", expHolds: false, expPath: 6, expLoop: 4},
+		{query: "AG EF :done", expHolds: true, expPath: 1, expLoop: -1},
+		{query: "AG EF :start", expHolds: false, expPath: 2, expLoop: -1},
+		{query: "AG (not (at Worker :done))", expHolds: false, expPath: 5, expLoop: -1},
+		{query: "EX (at Worker :start)", expHolds: true, expPath: 2, expLoop: -1},
+		{query: "AX (at Coordinator :start)", expHolds: false, expPath: 2, expLoop: -1},
+		{query: "EG (not (at Coordinator :done))", expHolds: true, expPath: 6, expLoop: 4},
+		{query: "AU (empty? (channel Worker Coordinator)) (at Worker :done)", expHolds: false, expPath: 4, expLoop: -1},
+		{query: "EU (not (at Worker :done)) (not (empty chan))", expHolds: true, expPath: 2, expLoop: -1},
+		{query: "EF (var Worker :count)", expErr: "Worker has no variable count"},
+	}
+
+	m, err := lisp.LoadString(jobs)
+	assert.Nil(t, err)
+
+	sys, err := compose.New(m, compose.Options{Closed: true})
+	assert.Nil(t, err)
+
+	g, err := Explore(sys, explore.Limits{})
+	assert.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Check(%s)", test.query), func(t *testing.T) {
+			f, err := ParseQuery(test.query)
+			assert.Nil(t, err)
+
+			r, err := g.Check(f)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.expHolds, r.Holds)
+			assert.Equal(t, test.expPath, len(r.Path))
+			assert.Equal(t, test.expLoop, r.Loop)
+			assert.Equal(t, 0, r.Path[0])
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	m, err := lisp.LoadString(jobs)
+	assert.Nil(t, err)
+
+	sys, err := compose.New(m, compose.Options{Closed: true})
+	assert.Nil(t, err)
+
+	g, err := Explore(sys, explore.Limits{})
+	assert.Nil(t, err)
+
+	f, err := ParseQuery("AF (at Coordinator :done)")
+	assert.Nil(t, err)
+
+	r, err := g.Check(f)
+	assert.Nil(t, err)
+	assert.Equal(t, `(AF (at Coordinator :done)) does not hold
+counterexample:
+  0. Worker@:start, Coordinator@:start
+  1. Coordinator !job, to Worker  (<string>:8:4)
+  2. Worker ?job, from Coordinator  (<string>:3:4)
+  3. Worker !done, to Coordinator  (<string>:4:4)
+  4. Worker #3 -> :done  (<string>:5:4)
+state: Worker@:done, Coordinator@#2, Worker->Coordinator [done]
+then, repeated forever:
+  5. Worker :done -> :done  (<string>:6:4)
+`, g.Format(r))
+}
@@ -0,0 +1,120 @@
+package ctl
+
+import (
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Op is the operator of a formula.
+type Op string
+
+const (
+	True  Op = "true"
+	False Op = "false"
+	// Atom is a predicate over a single state
+	Atom Op = "atom"
+	Not  Op = "not"
+	And  Op = "and"
+	Or   Op = "or"
+	EX   Op = "EX"
+	AX   Op = "AX"
+	EF   Op = "EF"
+	AF   Op = "AF"
+	EG   Op = "EG"
+	AG   Op = "AG"
+	EU   Op = "EU"
+	AU   Op = "AU"
+)
+
+var arity = map[Op]int{Not: 1, EX: 1, AX: 1, EF: 1, AF: 1, EG: 1, AG: 1, EU: 2, AU: 2}
+
+// Formula is a formula of computation tree logic.
+type Formula struct {
+	Op Op
+	// Expr is the predicate of an atom
+	Expr *model.Expression
+	Args []*Formula
+}
+
+func (f *Formula) String() string {
+	switch f.Op {
+	case True, False:
+		return string(f.Op)
+	case Atom:
+		return f.Expr.String()
+	}
+
+	s := "(" + string(f.Op)
+	for _, arg := range f.Args {
+		s += " " + arg.String()
+	}
+	return s + ")"
+}
+
+// ParseQuery parses a formula as it is written on the command line. The outermost operators don't need parentheses,
+// e.g. AG EF :start is read as (AG (EF :start)).
+func ParseQuery(s string) (*Formula, error) {
+	expr, err := lisp.ParseExpression("(" + s + ")")
+	if err != nil {
+		return nil, err
+	}
+
+	for len(expr.Sub) == 1 {
+		expr = expr.Sub[0]
+	}
+	return Parse(unfold(expr))
+}
+
+// unfold nests a sequence of unary operators that is followed by their operand.
+func unfold(expr *model.Expression) *model.Expression {
+	if expr.Type != "lst" || len(expr.Sub) <= 2 || expr.Sub[0].Type != "ref" || arity[Op(expr.Sub[0].Ref)] != 1 {
+		return expr
+	}
+
+	return &model.Expression{
+		Type: "lst",
+		Sub:  []*model.Expression{expr.Sub[0], unfold(&model.Expression{Type: "lst", Sub: expr.Sub[1:]})},
+	}
+}
+
+// Parse converts an expression into a formula. Next to not, and, or and (-> f g), the operators are (EX f), (AX f),
+// (EF f), (AF f), (EG f), (AG f), (EU f g) and (AU f g). Every other expression is an atom.
+func Parse(expr *model.Expression) (*Formula, error) {
+	switch {
+	case expr.Type == "ref" && expr.Ref == "true":
+		return &Formula{Op: True}, nil
+	case expr.Type == "ref" && expr.Ref == "false":
+		return &Formula{Op: False}, nil
+	case expr.Type != "lst" || len(expr.Sub) == 0 || expr.Sub[0].Type != "ref":
+		return &Formula{Op: Atom, Expr: expr}, nil
+	}
+
+	op, args := Op(expr.Sub[0].Ref), expr.Sub[1:]
+	n, ok := arity[op]
+	switch {
+	case op == And || op == Or || op == "->":
+	case !ok:
+		return &Formula{Op: Atom, Expr: expr}, nil
+	case len(args) != n:
+		return nil, fmt.Errorf("%s: expected %d argument(s), got %d", op, n, len(args))
+	}
+
+	fs := []*Formula{}
+	for _, arg := range args {
+		f, err := Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+
+	if op == "->" {
+		if len(fs) != 2 {
+			return nil, fmt.Errorf("->: expected 2 argument(s), got %d", len(fs))
+		}
+		return &Formula{Op: Or, Args: []*Formula{{Op: Not, Args: fs[:1]}, fs[1]}}, nil
+	}
+	return &Formula{Op: op, Args: fs}, nil
+}
@@ -0,0 +1,40 @@
+package ctl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	var tests = []struct {
+		str    string
+		expStr string
+		expErr string
+	}{
+		{str: ":start", expStr: ":start"},
+		{str: "(at Worker :done)", expStr: "(at Worker :done)"},
+		{str: "AG EF :start", expStr: "(AG (EF :start))"},
+		{str: "(AG (EF :start))", expStr: "(AG (EF :start))"},
+		{str: "EF (and (at Worker :done) (empty chan))", expStr: "(EF (and (at Worker :done) (empty chan)))"},
+		{str: "AU true (not :start)", expStr: "(AU true (not :start))"},
+		{str: "-> :start (AX false)", expStr: "(or (not :start) (AX false))"},
+		{str: "EU :start", expErr: "EU: expected 2 argument(s), got 1"},
+		{str: "AG (", expErr: "failed to parse token"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("ParseQuery(%s)", test.str), func(t *testing.T) {
+			f, err := ParseQuery(test.str)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.expStr, f.String())
+		})
+	}
+}
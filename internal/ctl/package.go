@@ -0,0 +1,2 @@
+// package ctl checks formulas of computation tree logic against the global transition system of a model
+package ctl
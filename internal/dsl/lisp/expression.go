@@ -6,6 +6,25 @@ import (
 	"dberk.nl/graphchecker/internal/model"
 )
 
+// ParseExpression parses a single expression, e.g. a guard that is passed on the command line.
+func ParseExpression(s string) (*model.Expression, error) {
+	tokens, err := Tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := ParseTokenStream(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ns) != 1 {
+		return nil, fmt.Errorf("expected a single expression, got %d", len(ns))
+	}
+
+	return parseExpression(ns[0])
+}
+
 func parseExpression(n node) (*model.Expression, error) {
 	switch n := n.(type) {
 	case listNode:
@@ -23,6 +42,25 @@ func parseExpression(n node) (*model.Expression, error) {
 			Type: "lst",
 			Sub: exprs,
 		}, nil
+	case mapNode:
+		if len(n.nodes)%2 != 0 {
+			return nil, fmt.Errorf("map literal has a key without a value")
+		}
+
+		exprs := []*model.Expression{}
+		for _, cn := range n.nodes {
+			expr, err := parseExpression(cn)
+			if err != nil {
+				return nil, err
+			}
+
+			exprs = append(exprs, expr)
+		}
+
+		return &model.Expression{
+			Type: "map",
+			Sub: exprs,
+		}, nil
 	case symbolNode:
 	  return &model.Expression{
 			Type: "ref",
@@ -33,6 +71,11 @@ func parseExpression(n node) (*model.Expression, error) {
 			Type: "int",
 			Int: n.int,
 		}, nil
+	case keywordNode:
+		return &model.Expression{
+			Type: "kw",
+			Ref: n.name,
+		}, nil
 	default:
 		return nil, fmt.Errorf("unhandled type: %s", n.Kind())
 	}
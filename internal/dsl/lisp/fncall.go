@@ -61,6 +61,15 @@ func (c *fnCall) nextUnnamedParam() *param {
 	return &param{n: c.args[c.aIdx-1]}
 }
 
+// peek returns the next parameter without consuming it.
+func (c *fnCall) peek() (node, bool) {
+	if c.aIdx == len(c.args) {
+		return nil, false
+	}
+
+	return c.args[c.aIdx], true
+}
+
 func (c *fnCall) isDone() bool {
 	return c.aIdx == len(c.args)
 }
@@ -2,21 +2,34 @@ package lisp
 
 import (
 	"fmt"
+	"strings"
 
 	"dberk.nl/graphchecker/internal/model"
 )
 
 // Interpret converts takes an AST and constructs the ioco-model.
 // - it interprets the defmessage calls and generates model.Message objects
+// - it interprets the defsubprocess calls, whose bodies are expanded inline in the processes that follow them
 // - it interprets the defprocess calls and constructs the graphs
 func Interpret(as []ast) (*model.Model, error) {
 	// TODO: resolve all message references, and state transitions
-	return interpretToplevel(as)
+	return interpretToplevel(as, nil)
 }
 
-func interpretToplevel(ns []ast) (*model.Model, error) {
+// interpretToplevel interprets the forms, srcs are the files that they were read from and are used to attach source
+// locations to the model.
+func interpretToplevel(ns []ast, srcs sources) (*model.Model, error) {
 	messages := []*model.Message{}
 	processes := []*model.Process{}
+	subprocesses := map[string]*subprocess{}
+	var system *fnCall
+	channels := []*model.Channel{}
+	invariants := []*model.Invariant{}
+	properties := []*model.Property{}
+	fairness := []*model.Fairness{}
+	// implicit contains the processes whose parameters are not marked with :params, a misspelled statement reads the
+	// same
+	implicit := map[*model.Process]bool{}
 
 	for _, n := range ns {
 		switch n := n.(type) {
@@ -34,20 +47,238 @@ func interpretToplevel(ns []ast) (*model.Model, error) {
 				}
 				messages = append(messages, mess)
 
+			case "defsubprocess":
+				sub, err := defsubprocess(fnCall)
+				if err != nil {
+					return nil, fmt.Errorf("defsubprocess: %w", err)
+				}
+				if _, ok := subprocesses[sub.name]; ok {
+					return nil, fmt.Errorf("defsubprocess: %s is already defined", sub.name)
+				}
+				subprocesses[sub.name] = sub
+
 			case "defprocess":
-				proc, err := defprocess(fnCall)
+				proc, explicit, err := defprocess(fnCall, subprocesses, srcs)
 				if err != nil {
 					return nil, fmt.Errorf("defprocess: %w", err)
 				}
+				if len(proc.Params) != 0 && !explicit {
+					implicit[proc] = true
+				}
 				processes = append(processes, proc)
 
+			case "defsystem":
+				if system != nil {
+					return nil, fmt.Errorf("defsystem: system is already defined")
+				}
+				// Interpreted once all processes are known.
+				system = fnCall
+
+			case "defchannel":
+				ch, err := defchannel(fnCall)
+				if err != nil {
+					return nil, fmt.Errorf("defchannel: %w", err)
+				}
+				channels = append(channels, ch)
+
+			case "definvariant":
+				inv, err := definvariant(fnCall)
+				if err != nil {
+					return nil, fmt.Errorf("definvariant: %w", err)
+				}
+				for _, other := range invariants {
+					if other.Name == inv.Name {
+						return nil, fmt.Errorf("definvariant: %s is already defined", inv.Name)
+					}
+				}
+				inv.Location = srcs.location(n.pos)
+				invariants = append(invariants, inv)
+
+			case "defproperty":
+				prop, err := defproperty(fnCall)
+				if err != nil {
+					return nil, fmt.Errorf("defproperty: %w", err)
+				}
+				for _, other := range properties {
+					if other.Name == prop.Name {
+						return nil, fmt.Errorf("defproperty: %s is already defined", prop.Name)
+					}
+				}
+				prop.Location = srcs.location(n.pos)
+				properties = append(properties, prop)
+
+			case "fair":
+				f, err := fair(fnCall)
+				if err != nil {
+					return nil, fmt.Errorf("fair: %w", err)
+				}
+				f.Location = srcs.location(n.pos)
+				fairness = append(fairness, f)
+
+			case "import":
+				return nil, fmt.Errorf("import: imports are resolved when loading files, use LoadFile")
+
 			default:
 				return nil, fmt.Errorf("unknown fn: %s", fnCall.fName)
 			}
 		}
 	}
 
-	return &model.Model{Messages: messages, Processes: processes}, nil
+	m := &model.Model{Messages: messages, Processes: processes, Invariants: invariants, Properties: properties, Fairness: fairness}
+	if system != nil {
+		instances, err := defsystem(system, processes)
+		if err != nil {
+			return nil, fmt.Errorf("defsystem: %w", err)
+		}
+		m.System = instances
+	}
+
+	if err := checkInstantiated(processes, implicit, m.System); err != nil {
+		return nil, fmt.Errorf("defprocess: %w", err)
+	}
+
+	if err := checkChannels(channels, m); err != nil {
+		return nil, fmt.Errorf("defchannel: %w", err)
+	}
+	m.Channels = channels
+
+	return m, nil
+}
+
+// definvariant interprets (definvariant name expression). The expression is evaluated in every reachable state of the
+// system, and may refer to the instances, their states and variables, and the contents of the channels.
+func definvariant(call *fnCall) (*model.Invariant, error) {
+	name, err := call.nextParam(":name").symbol()
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := call.nextParam(":expr").expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if !call.isDone() {
+		return nil, fmt.Errorf("trailing parameters")
+	}
+
+	return &model.Invariant{Name: name, Expr: expr}, nil
+}
+
+// defproperty interprets (defproperty name formula), where the formula is a formula of linear temporal logic. Its atoms
+// are (sent message), (received message) and expressions over the state of the system, as in definvariant.
+func defproperty(call *fnCall) (*model.Property, error) {
+	name, err := call.nextParam(":name").symbol()
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := call.nextParam(":formula").expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if !call.isDone() {
+		return nil, fmt.Errorf("trailing parameters")
+	}
+
+	return &model.Property{Name: name, Formula: expr}, nil
+}
+
+// fair interprets a fairness assumption, (fair :weak target) or (fair :strong target). The target is one of:
+// - Worker, the steps of every instance of the process Worker
+// - (Worker 1), the steps of a single instance
+// - (Worker ?receive ack) or ((Worker 1) !send req), the steps of the instances that receive or send the message
+// - (channel Client Server), the steps that deliver messages over the channel from Client to Server
+func fair(call *fnCall) (*model.Fairness, error) {
+	n, err := call.nextUnnamedParam().node()
+	if err != nil {
+		return nil, fmt.Errorf("expected :weak or :strong")
+	}
+
+	f := &model.Fairness{}
+	switch kind, _ := n.(keywordNode); kind.name {
+	case ":weak":
+	case ":strong":
+		f.Strong = true
+	default:
+		return nil, fmt.Errorf("expected :weak or :strong")
+	}
+
+	target, err := call.nextUnnamedParam().node()
+	if err != nil {
+		return nil, fmt.Errorf("missing target")
+	}
+
+	if !call.isDone() {
+		return nil, fmt.Errorf("trailing parameters")
+	}
+
+	switch target := target.(type) {
+	case symbolNode:
+		f.Process = target.name
+		return f, nil
+
+	case listNode:
+		if len(target.nodes) == 0 {
+			return nil, fmt.Errorf("empty target")
+		}
+
+		if head, ok := target.nodes[0].(symbolNode); ok && head.name == "channel" {
+			if len(target.nodes) != 3 {
+				return nil, fmt.Errorf("channel: expected 2 instances, got %d", len(target.nodes)-1)
+			}
+
+			for idx, end := range []*string{&f.From, &f.To} {
+				process, args, err := instanceRef(target.nodes[idx+1])
+				if err != nil {
+					return nil, fmt.Errorf("channel: %w", err)
+				}
+				*end = instanceName(process, args)
+			}
+			return f, nil
+		}
+
+		action, isAction := "", false
+		if len(target.nodes) == 3 {
+			sym, _ := target.nodes[1].(symbolNode)
+			action, isAction = sym.name, sym.name == "?receive" || sym.name == "!send"
+		}
+
+		if !isAction {
+			process, args, err := instanceRef(target)
+			if err != nil {
+				return nil, err
+			}
+			f.Process, f.Instance = process, instanceName(process, args)
+			return f, nil
+		}
+
+		msg, ok := target.nodes[2].(symbolNode)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a message, got %s", action, target.nodes[2].Kind())
+		}
+		if action == "?receive" {
+			f.Receive = msg.name
+		} else {
+			f.Send = msg.name
+		}
+
+		switch who := target.nodes[0].(type) {
+		case symbolNode:
+			f.Process = who.name
+		default:
+			process, args, err := instanceRef(who)
+			if err != nil {
+				return nil, err
+			}
+			f.Process, f.Instance = process, instanceName(process, args)
+		}
+		return f, nil
+
+	default:
+		return nil, fmt.Errorf("expected a process, an instance or a channel, got %s", target.Kind())
+	}
 }
 
 func defmessage(defCall *fnCall) (*model.Message, error) {
@@ -85,10 +316,324 @@ func defmessage(defCall *fnCall) (*model.Message, error) {
 	return &model.Message{Name: name, Fields: fieldNames}, nil
 }
 
-func defprocess(call *fnCall) (*model.Process, error) {
-	// b := newProcessBuilder()
+// defprocess interprets a process definition, and returns whether its parameters were marked with :params.
+func defprocess(call *fnCall, subprocesses map[string]*subprocess, srcs sources) (*model.Process, bool, error) {
+	name, err := call.nextParam(":name").symbol()
+	if err != nil {
+		return nil, false, err
+	}
+
+	params, explicit, err := defprocess_params(call, subprocesses)
+	if err != nil {
+		return nil, false, fmt.Errorf("params: %w", err)
+	}
+
+	body := []node{}
+	for !call.isDone() {
+		n, err := call.nextUnnamedParam().node()
+		if err != nil {
+			return nil, false, err
+		}
+		body = append(body, n)
+	}
+
+	b := newProcessBuilder()
+	b.subprocesses = subprocesses
+	b.sources = srcs
+	if err := defprocess_body(body, b); err != nil {
+		return nil, false, err
+	}
+
+	proc := b.build(name)
+	proc.Params = params
+	return proc, explicit, nil
+}
+
+// defprocess_params interprets the optional parameter list of a process, either as (defprocess Name (a b) ...) or as
+// (defprocess Name :params (a b) ...). It returns whether the parameters were marked with :params.
+//
+// A list of symbols is only taken as the parameter list if it can't be a statement of the body.
+func defprocess_params(call *fnCall, subprocesses map[string]*subprocess) ([]string, bool, error) {
+	n, ok := call.peek()
+	if !ok {
+		return nil, false, nil
+	}
+
+	explicit := false
+	if key, ok := n.(keywordNode); ok && key.name == ":params" {
+		explicit = true
+	} else if !isParamList(n, subprocesses) {
+		return nil, false, nil
+	}
+
+	ns, err := call.nextParam(":params").list()
+	if err != nil {
+		return nil, false, err
+	}
+
+	params := []string{}
+	for _, n := range ns {
+		sym, ok := n.(symbolNode)
+		if !ok {
+			return nil, false, fmt.Errorf("expected symbolNode, got %s", n.Kind())
+		}
+
+		for _, p := range params {
+			if p == sym.name {
+				return nil, false, fmt.Errorf("parameter %s is declared twice", p)
+			}
+		}
+		params = append(params, sym.name)
+	}
+
+	if len(params) == 0 && !explicit {
+		return nil, false, nil
+	}
+
+	return params, explicit, nil
+}
+
+// isParamList returns whether a list can be the parameter list of a process: a list of plain symbols, none of which
+// is a statement or a subprocess.
+func isParamList(n node, subprocesses map[string]*subprocess) bool {
+	list, ok := n.(listNode)
+	if !ok {
+		return false
+	}
+
+	for _, n := range list.nodes {
+		sym, ok := n.(symbolNode)
+		if !ok {
+			return false
+		}
+
+		if _, isSub := subprocesses[sym.name]; bodyForms[sym.name] || isSub {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkInstantiated verifies that defsystem instantiates every process whose parameters are not marked with :params.
+// Such a parameter list reads the same as a statement with an unknown name, e.g. (defprocess Client (sned) ...).
+func checkInstantiated(processes []*model.Process, implicit map[*model.Process]bool, system []*model.Instance) error {
+	instantiated := map[*model.Process]bool{}
+	for _, inst := range system {
+		instantiated[inst.Process] = true
+	}
+
+	for _, p := range processes {
+		if implicit[p] && !instantiated[p] {
+			return fmt.Errorf("%s: unknown fn call %s, or parameters (%s) of a process that no defsystem instantiates",
+				p.Name, p.Params[0], strings.Join(p.Params, " "))
+		}
+	}
+	return nil
+}
+
+// defsystem interprets (defsystem instance...), where an instance is either the name of a process without parameters,
+// or a list of the name of a process followed by its arguments, e.g. (Worker 1).
+func defsystem(call *fnCall, processes []*model.Process) ([]*model.Instance, error) {
+	byName := map[string]*model.Process{}
+	for _, p := range processes {
+		byName[p.Name] = p
+	}
+
+	instances := []*model.Instance{}
+	names := map[string]bool{}
+	for !call.isDone() {
+		n, err := call.nextUnnamedParam().node()
+		if err != nil {
+			return nil, err
+		}
+
+		name, args, err := instanceRef(n)
+		if err != nil {
+			return nil, err
+		}
+
+		proc, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown process %s", name)
+		}
+
+		if len(proc.Params) != len(args) {
+			return nil, fmt.Errorf("%s expects %d argument(s), got %d", name, len(proc.Params), len(args))
+		}
+
+		inst := &model.Instance{Name: instanceName(name, args), Process: proc, Args: args}
+		if names[inst.Name] {
+			return nil, fmt.Errorf("instance %s is declared twice", inst.Name)
+		}
+		names[inst.Name] = true
+
+		instances = append(instances, inst)
+	}
+
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("system has no instances")
+	}
+
+	return instances, nil
+}
+
+// instanceRef interprets a reference to an instance, either the name of a process or a list of the name of a process
+// followed by its arguments.
+func instanceRef(n node) (string, []*model.Expression, error) {
+	switch n := n.(type) {
+	case symbolNode:
+		return n.name, []*model.Expression{}, nil
+	case listNode:
+		call, err := parseFnCall(n.nodes)
+		if err != nil {
+			return "", nil, err
+		}
+
+		args := []*model.Expression{}
+		for !call.isDone() {
+			arg, err := call.nextUnnamedParam().expression()
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %w", call.fnName(), err)
+			}
+			args = append(args, arg)
+		}
+		return call.fnName(), args, nil
+	default:
+		return "", nil, fmt.Errorf("expected process instance, got %s", n.Kind())
+	}
+}
+
+// defchannel interprets (defchannel :from instance :to instance :semantics fifo :capacity 2). Every parameter is
+// optional: without :from and :to it configures the default channel of the system.
+func defchannel(call *fnCall) (*model.Channel, error) {
+	ch := &model.Channel{Semantics: model.ChannelFIFO, Capacity: -1}
+	for !call.isDone() {
+		key, err := call.nextUnnamedParam().keyword()
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := call.nextUnnamedParam().node()
+		if err != nil {
+			return nil, fmt.Errorf("missing value for arg %s", key)
+		}
+
+		switch key {
+		case ":from", ":to":
+			name, args, err := instanceRef(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+
+			if key == ":from" {
+				ch.From = instanceName(name, args)
+			} else {
+				ch.To = instanceName(name, args)
+			}
+
+		case ":semantics":
+			sym, ok := val.(symbolNode)
+			if !ok {
+				return nil, fmt.Errorf("expected symbolNode, got %s", val.Kind())
+			}
+
+			switch sym.name {
+			case model.ChannelSync, model.ChannelFIFO, model.ChannelBag, model.ChannelLossy, model.ChannelDuplicating, model.ChannelReordering:
+				ch.Semantics = sym.name
+			default:
+				return nil, fmt.Errorf("unknown channel semantics %s", sym.name)
+			}
+
+		case ":capacity":
+			i, ok := val.(intNode)
+			if !ok || i.int < 0 {
+				return nil, fmt.Errorf("expected a non-negative capacity")
+			}
+			ch.Capacity = int(i.int)
+
+		default:
+			return nil, fmt.Errorf("unknown arg %s", key)
+		}
+	}
+
+	if (ch.From == "") != (ch.To == "") {
+		return nil, fmt.Errorf("expected both :from and :to, or neither")
+	}
+
+	switch {
+	case ch.Semantics == model.ChannelSync && 0 < ch.Capacity:
+		return nil, fmt.Errorf("sync channels have no capacity")
+	case ch.Semantics == model.ChannelSync:
+		ch.Capacity = 0
+	case ch.Capacity == -1:
+		ch.Capacity = model.DefaultChannelCapacity
+	case ch.Capacity == 0:
+		return nil, fmt.Errorf("%s channels need a capacity of at least 1", ch.Semantics)
+	}
+
+	return ch, nil
+}
+
+// checkChannels verifies that every channel connects known instances, and that no channel is configured twice.
+func checkChannels(channels []*model.Channel, m *model.Model) error {
+	instances := map[string]bool{}
+	if m.System != nil {
+		for _, inst := range m.System {
+			instances[inst.Name] = true
+		}
+	} else {
+		for _, p := range m.Processes {
+			if len(p.Params) == 0 {
+				instances[p.Name] = true
+			}
+		}
+	}
+
+	seen := map[[2]string]bool{}
+	for _, ch := range channels {
+		if !ch.Default() {
+			for _, name := range []string{ch.From, ch.To} {
+				if !instances[name] {
+					return fmt.Errorf("unknown instance %s", name)
+				}
+			}
+		}
+
+		if seen[[2]string{ch.From, ch.To}] {
+			if ch.Default() {
+				return fmt.Errorf("default channel is already defined")
+			}
+			return fmt.Errorf("channel from %s to %s is already defined", ch.From, ch.To)
+		}
+		seen[[2]string{ch.From, ch.To}] = true
+	}
 
-	return nil, nil
+	return nil
+}
+
+// instanceName returns the identity of an instance: the name of its process, or the process and its arguments as they
+// are written in defsystem, e.g. (Worker 1).
+func instanceName(process string, args []*model.Expression) string {
+	if len(args) == 0 {
+		return process
+	}
+
+	call := &model.Expression{Type: "lst", Sub: []*model.Expression{{Type: "ref", Ref: process}}}
+	call.Sub = append(call.Sub, args...)
+	return call.String()
+}
+
+// bodyForms contains the names of the forms that can be used as statements in the body of a process.
+var bodyForms = map[string]bool{
+	"!send":    true,
+	"?receive": true,
+	"let":      true,
+	"if":       true,
+	"select":   true,
+	"loop":     true,
+	"goto":     true,
+	"set!":     true,
 }
 
 // TODO: body is a list of nodes, evaluate each one by one
@@ -105,17 +650,25 @@ func defprocess_body(ns []node, b *processBuilder) error {
 func defprocess_body_expression(n node, b *processBuilder) error {
 	switch n := n.(type) {
 	case keywordNode:
-		state, err := b.allocNamedState(n.name)
-		if err != nil {
+		if n.name == ":final" {
+			return defprocess_final(b)
+		}
+
+		defer func(pos int) { b.pos = pos }(b.pos)
+		b.pos = n.pos
+
+		if err := defprocess_nameCurrentState(n.name, b); err != nil {
 			return err
 		}
-		b.addTransition(&model.Transition{From: b.curState, To: state})
-		b.curState = state
 	case listNode:
 		if b.curState == nil {
 			return fmt.Errorf("unreachable")
 		}
 
+		// Transitions are attributed to the innermost statement that creates them.
+		defer func(pos int) { b.pos = pos }(b.pos)
+		b.pos = n.pos
+
 		call, err := parseFnCall(n.nodes)
 		if err != nil {
 			return err
@@ -135,9 +688,16 @@ func defprocess_body_expression(n node, b *processBuilder) error {
 			}
 
 		case "let":
-		// transition that initializes variables
-		// recurse on contains
-		// close scope
+			err := defprocess_let(call, b)
+			if err != nil {
+				return fmt.Errorf("let: %w", err)
+			}
+
+		case "set!":
+			err := defprocess_set(call, b)
+			if err != nil {
+				return fmt.Errorf("set!: %w", err)
+			}
 
 		case "if":
 			err := defprocess_if(call, b)
@@ -146,6 +706,16 @@ func defprocess_body_expression(n node, b *processBuilder) error {
 			}
 
 		case "select":
+			err := defprocess_select(call, b)
+			if err != nil {
+				return fmt.Errorf("select: %w", err)
+			}
+
+		case "loop":
+			err := defprocess_loop(call, b)
+			if err != nil {
+				return fmt.Errorf("loop: %w", err)
+			}
 
 		case "goto":
 			err := defprocess_goto(call, b)
@@ -154,7 +724,14 @@ func defprocess_body_expression(n node, b *processBuilder) error {
 			}
 
 		default:
-			return fmt.Errorf("unknown fn call %s", call.fName)
+			sub, ok := b.subprocesses[call.fnName()]
+			if !ok {
+				return fmt.Errorf("unknown fn call %s", call.fName)
+			}
+
+			if err := sub.expand(call, b); err != nil {
+				return fmt.Errorf("%s: %w", call.fnName(), err)
+			}
 		}
 
 	default:
@@ -170,6 +747,7 @@ func defprocess_send(call *fnCall, b *processBuilder) error {
 		return err
 	}
 
+	var peer *model.Expression
 	valuation := map[string]*model.Expression{}
 	for {
 		if call.isDone() {
@@ -186,6 +764,11 @@ func defprocess_send(call *fnCall, b *processBuilder) error {
 			return err
 		}
 
+		if name == ":to" {
+			peer = expr
+			continue
+		}
+
 		valuation[name] = expr
 	}
 
@@ -197,7 +780,9 @@ func defprocess_send(call *fnCall, b *processBuilder) error {
 	t := &model.Transition{
 		From: b.curState,
 		To: to,
+		Action: model.ActionOutput,
 		Send: mess,
+		Peer: peer,
 		Valuation: valuation,
 	}
 	b.addTransition(t)
@@ -206,6 +791,7 @@ func defprocess_send(call *fnCall, b *processBuilder) error {
 }
 
 func defprocess_nameCurrentState(name string, b *processBuilder) error {
+	name = b.scopedStateName(name)
 	if name == b.initState.Name {
 		if b.curState == b.initState {
 			// start state was named explicitly, this is fine
@@ -215,9 +801,18 @@ func defprocess_nameCurrentState(name string, b *processBuilder) error {
 		return fmt.Errorf("name collision, %s is already taken", name)
 	}
 
-	s, err := b.allocNamedState(name)
-	if err != nil {
-		return err
+	if b.placedStates[name] {
+		return fmt.Errorf("state already known")
+	}
+	b.placedStates[name] = true
+
+	// A goto that precedes the label has already allocated the state.
+	s, ok := b.stateForName(name)
+	if !ok {
+		var err error
+		if s, err = b.allocNamedState(name); err != nil {
+			return err
+		}
 	}
 
 	if b.curState != nil {
@@ -233,17 +828,54 @@ func defprocess_nameCurrentState(name string, b *processBuilder) error {
 	return nil
 }
 
+// defprocess_final marks the current state as an intended terminal state, e.g. :done :final. The composed system may
+// come to a halt when every instance is in a final state.
+func defprocess_final(b *processBuilder) error {
+	if b.curState == nil || !b.curState.Named() {
+		return fmt.Errorf("only named states can be final")
+	}
+
+	b.curState.Final = true
+	return nil
+}
+
 func defprocess_receive(call *fnCall, b *processBuilder) error {
+	return defprocess_receiveBinding(call, nil, b)
+}
+
+// defprocess_receiveBinding interprets a receive whose fields are assigned to variables.
+func defprocess_receiveBinding(call *fnCall, bindings map[string]string, b *processBuilder) error {
 	mess, err := call.nextParam(":message").symbol()
 	if err != nil {
 		return err
 	}
 
+	var peer *model.Expression
+	if !call.isDone() {
+		// The sender is bound to a variable.
+		sender, err := call.nextParam(":from").symbol()
+		if err != nil {
+			return err
+		}
+
+		if err := b.allocVariable(sender); err != nil {
+			return err
+		}
+		peer = &model.Expression{Type: "ref", Ref: sender}
+	}
+
+	if !call.isDone() {
+		return fmt.Errorf("trailing parameters")
+	}
+
 	to := b.allocUnnamedState()
 	t := &model.Transition{
 		From: b.curState,
 		To: to,
+		Action: model.ActionInput,
 		Receive: mess,
+		Peer: peer,
+		Bindings: bindings,
 	}
 	b.addTransition(t)
 	b.curState = to
@@ -255,6 +887,7 @@ func defprocess_goto(call *fnCall, b *processBuilder) error {
 	if err != nil {
 		return err
 	}
+	name = b.scopedStateName(name)
 
 	to, ok := b.stateForName(name)
 	if !ok {
@@ -291,14 +924,17 @@ func defprocess_if(call *fnCall, b *processBuilder) error {
 		// No else defined.
 	}
 
+	// The branches share the number of the if, so that coverage can pair their outcomes.
+	b.guardCounter++
 	ifStart := b.curState
 	thenStart := b.allocUnnamedState()
 	b.addTransition(&model.Transition{
 		From: ifStart,
 		To: thenStart,
-		Send: "then",
 		Constraint: guard,
+		Guard: b.guardCounter,
 	})
+	id := b.guardCounter
 
  	b.curState = thenStart
 	if err := defprocess_body_expression(then, b); err != nil {
@@ -315,6 +951,8 @@ func defprocess_if(call *fnCall, b *processBuilder) error {
 			From: ifStart,
 			To: elseStart,
 			Constraint: negateExpression(guard),
+			Guard: id,
+			Else: true,
 		}
 		b.addTransition(t)
 
@@ -335,7 +973,6 @@ func defprocess_if(call *fnCall, b *processBuilder) error {
 		b.addTransition(&model.Transition{
 			From: thenEnd,
 			To: ifEnd,
-			Send: "ifend",
 		})
 	}
 
@@ -343,7 +980,6 @@ func defprocess_if(call *fnCall, b *processBuilder) error {
 		b.addTransition(&model.Transition{
 			From: elseEnd,
 			To: ifEnd,
-			Send: "elseend",
 		})
 	}
 
@@ -352,3 +988,194 @@ func defprocess_if(call *fnCall, b *processBuilder) error {
 	return nil
 }
 
+// defprocess_select interprets (select branch...). Every branch is a statement that starts in the current state, so
+// the first steps of the branches are alternatives of each other. The branches that end join in a single state.
+func defprocess_select(call *fnCall, b *processBuilder) error {
+	if call.isDone() {
+		return fmt.Errorf("expected at least one branch")
+	}
+
+	selectStart := b.curState
+	ends := []*model.State{}
+	for !call.isDone() {
+		branch, err := call.nextUnnamedParam().node()
+		if err != nil {
+			return err
+		}
+
+		b.curState = selectStart
+		if err := defprocess_body_expression(branch, b); err != nil {
+			return err
+		}
+
+		if b.curState != nil {
+			ends = append(ends, b.curState)
+		}
+	}
+
+	if len(ends) == 0 {
+		b.curState = nil
+		return nil
+	}
+
+	selectEnd := b.allocUnnamedState()
+	for _, end := range ends {
+		b.addTransition(&model.Transition{
+			From: end,
+			To: selectEnd,
+		})
+	}
+
+	b.curState = selectEnd
+	return nil
+}
+
+// defprocess_loop interprets (loop statement...). The statements are repeated forever, a loop can only be left through
+// a goto.
+func defprocess_loop(call *fnCall, b *processBuilder) error {
+	loopStart := b.curState
+
+	body := []node{}
+	for !call.isDone() {
+		n, err := call.nextUnnamedParam().node()
+		if err != nil {
+			return err
+		}
+		body = append(body, n)
+	}
+
+	if err := defprocess_body(body, b); err != nil {
+		return err
+	}
+
+	if b.curState != nil {
+		b.addTransition(&model.Transition{
+			From: b.curState,
+			To: loopStart,
+		})
+	}
+
+	b.curState = nil
+	return nil
+}
+
+// defprocess_let interprets (let (binding...) statement...). A binding is either (variable expression), or
+// ({field...} (?receive ...)) which receives a message and assigns its fields to variables of the same name. Bindings
+// are made in order, and the variables are only visible within the statements of the let.
+func defprocess_let(call *fnCall, b *processBuilder) error {
+	bindings, err := call.nextParam(":bindings").list()
+	if err != nil {
+		return err
+	}
+
+	b.openLexicalScope()
+	defer b.closeLexicalScope()
+
+	assignments := []*model.Assignment{}
+	assign := func() {
+		if len(assignments) == 0 {
+			return
+		}
+
+		to := b.allocUnnamedState()
+		b.addTransition(&model.Transition{
+			From: b.curState,
+			To: to,
+			Assignments: assignments,
+		})
+		b.curState = to
+		assignments = []*model.Assignment{}
+	}
+
+	for _, binding := range bindings {
+		list, ok := binding.(listNode)
+		if !ok || len(list.nodes) != 2 {
+			return fmt.Errorf("expected a binding of a pattern and a value")
+		}
+		pattern, init := list.nodes[0], list.nodes[1]
+
+		if fields, ok := pattern.(mapNode); ok {
+			recv, ok := init.(listNode)
+			if !ok {
+				return fmt.Errorf("only the fields of a received message can be destructured")
+			}
+			recvCall, err := parseFnCall(recv.nodes)
+			if err != nil || recvCall.fnName() != "?receive" {
+				return fmt.Errorf("only the fields of a received message can be destructured")
+			}
+
+			vars := map[string]string{}
+			for _, field := range fields.nodes {
+				sym, ok := field.(symbolNode)
+				if !ok {
+					return fmt.Errorf("expected symbolNode as field, got %s", field.Kind())
+				}
+				vars[sym.name] = sym.name
+			}
+
+			assign()
+			if err := defprocess_receiveBinding(recvCall, vars, b); err != nil {
+				return fmt.Errorf("?receive: %w", err)
+			}
+			for name := range vars {
+				if err := b.allocVariable(name); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		sym, ok := pattern.(symbolNode)
+		if !ok {
+			return fmt.Errorf("expected symbolNode as variable, got %s", pattern.Kind())
+		}
+
+		expr, err := parseExpression(init)
+		if err != nil {
+			return err
+		}
+
+		if err := b.allocVariable(sym.name); err != nil {
+			return err
+		}
+		assignments = append(assignments, &model.Assignment{Var: sym.name, Value: expr})
+	}
+	assign()
+
+	body := []node{}
+	for !call.isDone() {
+		n, err := call.nextUnnamedParam().node()
+		if err != nil {
+			return err
+		}
+		body = append(body, n)
+	}
+
+	return defprocess_body(body, b)
+}
+
+// defprocess_set interprets (set! variable expression).
+func defprocess_set(call *fnCall, b *processBuilder) error {
+	name, err := call.nextParam(":var").symbol()
+	if err != nil {
+		return err
+	}
+
+	if _, err := b.resolveVariable(name); err != nil {
+		return err
+	}
+
+	expr, err := call.nextParam(":value").expression()
+	if err != nil {
+		return err
+	}
+
+	to := b.allocUnnamedState()
+	b.addTransition(&model.Transition{
+		From: b.curState,
+		To: to,
+		Assignments: []*model.Assignment{{Var: name, Value: expr}},
+	})
+	b.curState = to
+	return nil
+}
@@ -123,8 +123,8 @@ func TestSend(t *testing.T) {
 				b.addTransition(&model.Transition{
 					From: b.initState,
 					To: to,
+					Action: model.ActionOutput,
 					Send: "MessageName",
-					Valuation: map[string]*model.Expression{},
 				})
 				b.curState = to
 				return b
@@ -144,8 +144,8 @@ func TestSend(t *testing.T) {
 				b.addTransition(&model.Transition{
 					From: b.initState,
 					To: to,
+					Action: model.ActionOutput,
 					Send: "MessageName",
-					Valuation: map[string]*model.Expression{},
 				})
 				b.curState = to
 				return b
@@ -166,6 +166,7 @@ func TestSend(t *testing.T) {
 				b.addTransition(&model.Transition{
 					From: b.initState,
 					To: to,
+					Action: model.ActionOutput,
 					Send: "MessageName",
 					Valuation: map[string]*model.Expression{
 						":fieldOne": {
@@ -195,6 +196,33 @@ func TestSend(t *testing.T) {
 				return b
 			},
 		},
+		{
+			name: "addressed message",
+			str: "(!send :message MessageName :to peer :fieldOne 1)",
+			inProcessBuilder: newProcessBuilder,
+			expProcessBuilder: func() *processBuilder {
+				b := newProcessBuilder()
+				to := b.allocUnnamedState()
+				b.addTransition(&model.Transition{
+					From: b.initState,
+					To: to,
+					Action: model.ActionOutput,
+					Send: "MessageName",
+					Peer: &model.Expression{Type: "ref", Ref: "peer"},
+					Valuation: map[string]*model.Expression{
+						":fieldOne": {Type: "int", Int: 1},
+					},
+				})
+				b.curState = to
+				return b
+			},
+		},
+		{
+			name: "addressed message, missing recipient",
+			str: "(!send :message MessageName :to)",
+			inProcessBuilder: newProcessBuilder,
+			expErr: "EOF",
+		},
 	}
 
 	for _, test := range tests {
@@ -241,6 +269,7 @@ func TestReceive(t *testing.T) {
 				b.addTransition(&model.Transition{
 					From: b.initState,
 					To: to,
+					Action: model.ActionInput,
 					Receive: "MessageName",
 				})
 				b.curState = to
@@ -257,12 +286,38 @@ func TestReceive(t *testing.T) {
 				b.addTransition(&model.Transition{
 					From: b.initState,
 					To: to,
+					Action: model.ActionInput,
 					Receive: "MessageName",
 				})
 				b.curState = to
 				return b
 			},
 		},
+		{
+			name: "bind the sender",
+			str: "(?receive :message MessageName :from sender)",
+			inProcessBuilder: newProcessBuilder,
+			expProcessBuilder: func() *processBuilder {
+				b := newProcessBuilder()
+				b.allocVariable("sender")
+				to := b.allocUnnamedState()
+				b.addTransition(&model.Transition{
+					From: b.initState,
+					To: to,
+					Action: model.ActionInput,
+					Receive: "MessageName",
+					Peer: &model.Expression{Type: "ref", Ref: "sender"},
+				})
+				b.curState = to
+				return b
+			},
+		},
+		{
+			name: "sender is not a symbol",
+			str: "(?receive :message MessageName :from 1)",
+			inProcessBuilder: newProcessBuilder,
+			expErr: "expected symbolNode",
+		},
 	}
 
 	for _, test := range tests {
@@ -380,10 +435,39 @@ func TestNameCurrentState(t *testing.T) {
 			expProcessBuilder: func () *processBuilder {
 				b := newProcessBuilder()
 				st, _ := b.allocNamedState(":some-state")
+				b.placedStates[":some-state"] = true
 				b.curState = st
 				return b
 			},
 		},
+		{
+			name: "target of a forward goto",
+			str: ":some-state",
+			inProcessBuilder: func() *processBuilder {
+				b := newProcessBuilder()
+				b.allocNamedState(":some-state")
+				b.curState = nil
+				return b
+			},
+			expProcessBuilder: func () *processBuilder {
+				b := newProcessBuilder()
+				st, _ := b.allocNamedState(":some-state")
+				b.placedStates[":some-state"] = true
+				b.curState = st
+				return b
+			},
+		},
+		{
+			name: "duplicate label",
+			str: ":some-state",
+			inProcessBuilder: func() *processBuilder {
+				b := newProcessBuilder()
+				b.allocNamedState(":some-state")
+				b.placedStates[":some-state"] = true
+				return b
+			},
+			expErr: "state already known",
+		},
 	}
 
 	for _, test := range tests {
@@ -407,6 +491,59 @@ func TestNameCurrentState(t *testing.T) {
 	}
 }
 
+func TestLabels(t *testing.T) {
+	var tests = []struct {
+		name      string
+		str       string
+		expStates []string
+		expErr    string
+	}{
+		{
+			name:      "forward goto",
+			str:       "(defprocess P (goto :x) :x (!send :message ping) :done :final)",
+			expStates: []string{":start", ":x", "", ":done"},
+		},
+		{
+			name:      "backward goto",
+			str:       "(defprocess P :x (!send :message ping) (goto :x))",
+			expStates: []string{":start", ":x", ""},
+		},
+		{
+			name:   "duplicate label",
+			str:    "(defprocess P :x (!send :message ping) :x)",
+			expErr: "state already known",
+		},
+		{
+			name:   "duplicate label after a forward goto",
+			str:    "(defprocess P (goto :x) :x (!send :message ping) :x)",
+			expErr: "state already known",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("labels - %s", test.name), func(t *testing.T) {
+			m, err := LoadString(test.str)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Equal(t, nil, err, "expected err to be nil, got %v")
+			p := m.Processes[0]
+			assert.Equal(t, test.expStates, stateNames(p))
+
+			// The statement that follows a label starts in the labelled state.
+			for _, tr := range p.Transitions {
+				if tr.Send == "ping" {
+					assert.Equal(t, ":x", tr.From.Name)
+				}
+			}
+		})
+	}
+}
+
 func TestIf(t *testing.T) {
 	var tests = []struct {
 		name string
@@ -421,6 +558,7 @@ func TestIf(t *testing.T) {
 			inProcessBuilder: newProcessBuilder,
 			expProcessBuilder: func() *processBuilder {
 				b := newProcessBuilder()
+				b.guardCounter = 1
 				ifStart := b.curState
 				thenStart := b.allocUnnamedState()
 				thenEnd := b.allocUnnamedState()
@@ -431,7 +569,6 @@ func TestIf(t *testing.T) {
 				b.addTransition(&model.Transition{
 					From: ifStart,
 					To: thenStart,
-					Send: "then",
 					Constraint: &model.Expression{
 						Type: "lst",
 						Sub: []*model.Expression{
@@ -440,10 +577,12 @@ func TestIf(t *testing.T) {
 							{Type: "int", Int: 1},
 						},
 					},
+					Guard: 1,
 				})
 				b.addTransition(&model.Transition{
 					From: thenStart,
 					To: thenEnd,
+					Action: model.ActionOutput,
 					Send: "MessageA",
 				})
 
@@ -463,22 +602,23 @@ func TestIf(t *testing.T) {
 							},
 						},
 					},
+					Guard: 1,
+					Else: true,
 				})
 				b.addTransition(&model.Transition{
 					From: elseStart,
 					To: elseEnd,
+					Action: model.ActionOutput,
 					Send: "MessageB",
 				})
 
 				b.addTransition(&model.Transition{
 					From: thenEnd,
 					To: ifEnd,
-					Send: "ifend",
 				})
 				b.addTransition(&model.Transition{
 					From: elseEnd,
 					To: ifEnd,
-					Send: "elseend",
 				})
 				b.curState = ifEnd
 				return b
@@ -531,3 +671,590 @@ func asFnCall(s string) (*fnCall, error) {
 
 	return parseFnCall(listNode.nodes)
 }
+
+func TestDefprocessParams(t *testing.T) {
+	var tests = []struct {
+		name      string
+		str       string
+		expParams []string
+		expErr    string
+	}{
+		{
+			name:      "no parameters",
+			str:       "(defprocess Coordinator (!send :message ping))",
+			expParams: nil,
+		},
+		{
+			name:      "implicit parameters",
+			str:       "(defprocess Worker (id peer) (!send :message ping)) (defsystem (Worker 1 2))",
+			expParams: []string{"id", "peer"},
+		},
+		{
+			name:      "explicit parameters",
+			str:       "(defprocess Worker :params (id) (!send :message ping))",
+			expParams: []string{"id"},
+		},
+		{
+			name: "subprocess call is not a parameter list",
+			str: `(defsubprocess request-response (req resp)
+			        (!send :message req)
+			        (?receive :message resp))
+			      (defprocess Client (request-response ping pong))`,
+			expParams: nil,
+		},
+		{
+			name:   "misspelled statement",
+			str:    "(defprocess Client (foo) (!send :message ping))",
+			expErr: "Client: unknown fn call foo, or parameters (foo) of a process that no defsystem instantiates",
+		},
+		{
+			name:   "misspelled statement with arguments",
+			str:    "(defprocess Client (sned :message ping))",
+			expErr: "unknown fn call sned",
+		},
+		{
+			name:   "statement among the symbols",
+			str:    "(defprocess Client (forever loop))",
+			expErr: "unknown fn call forever",
+		},
+		{
+			name:   "parameter declared twice",
+			str:    "(defprocess Worker (id id) (!send :message ping))",
+			expErr: "parameter id is declared twice",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("defprocess - %s", test.name), func(t *testing.T) {
+			m, err := interpretString(test.str)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			} else {
+				assert.Equal(t, nil, err, "expected err to be nil, got %v")
+				assert.Equal(t, test.expParams, m.Processes[0].Params)
+			}
+		})
+	}
+}
+
+func TestDefsystem(t *testing.T) {
+	var tests = []struct {
+		name         string
+		str          string
+		expInstances []string
+		expErr       string
+	}{
+		{
+			name:         "no system",
+			str:          "(defprocess Coordinator (!send :message ping))",
+			expInstances: []string{},
+		},
+		{
+			name: "multiple instances",
+			str: `(defprocess Worker (id) (?receive :message ping))
+			      (defprocess Coordinator (!send :message ping))
+			      (defsystem (Worker 1) (Worker 2) Coordinator)`,
+			expInstances: []string{"(Worker 1)", "(Worker 2)", "Coordinator"},
+		},
+		{
+			name: "unknown process",
+			str: `(defprocess Coordinator (!send :message ping))
+			      (defsystem Worker)`,
+			expErr: "unknown process Worker",
+		},
+		{
+			name: "wrong number of arguments",
+			str: `(defprocess Worker (id) (?receive :message ping))
+			      (defsystem Worker)`,
+			expErr: "Worker expects 1 argument(s), got 0",
+		},
+		{
+			name: "duplicate identity",
+			str: `(defprocess Worker (id) (?receive :message ping))
+			      (defsystem (Worker 1) (Worker 1))`,
+			expErr: "instance (Worker 1) is declared twice",
+		},
+		{
+			name: "defined twice",
+			str: `(defprocess Coordinator (!send :message ping))
+			      (defsystem Coordinator)
+			      (defsystem Coordinator)`,
+			expErr: "system is already defined",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("defsystem - %s", test.name), func(t *testing.T) {
+			m, err := interpretString(test.str)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			} else {
+				assert.Equal(t, nil, err, "expected err to be nil, got %v")
+
+				instances := []string{}
+				for _, inst := range m.System {
+					instances = append(instances, inst.Name)
+				}
+				assert.Equal(t, test.expInstances, instances)
+			}
+		})
+	}
+}
+
+func TestDefchannel(t *testing.T) {
+	var tests = []struct {
+		name        string
+		str         string
+		expChannels []*model.Channel
+		expErr      string
+	}{
+		{
+			name: "default channel",
+			str:  "(defchannel :semantics bag :capacity 3)",
+			expChannels: []*model.Channel{
+				{Semantics: model.ChannelBag, Capacity: 3},
+			},
+		},
+		{
+			name: "default capacity",
+			str:  "(defchannel :semantics lossy)",
+			expChannels: []*model.Channel{
+				{Semantics: model.ChannelLossy, Capacity: model.DefaultChannelCapacity},
+			},
+		},
+		{
+			name: "channel between instances",
+			str: `(defprocess Worker (id) (?receive :message ping))
+			      (defprocess Coordinator (!send :message ping :to (Worker 1)))
+			      (defsystem (Worker 1) Coordinator)
+			      (defchannel :from Coordinator :to (Worker 1) :semantics sync)`,
+			expChannels: []*model.Channel{
+				{From: "Coordinator", To: "(Worker 1)", Semantics: model.ChannelSync, Capacity: 0},
+			},
+		},
+		{
+			name:   "unknown semantics",
+			str:    "(defchannel :semantics carrier-pigeon)",
+			expErr: "unknown channel semantics carrier-pigeon",
+		},
+		{
+			name:   "sync with capacity",
+			str:    "(defchannel :semantics sync :capacity 1)",
+			expErr: "sync channels have no capacity",
+		},
+		{
+			name:   "buffered without capacity",
+			str:    "(defchannel :semantics fifo :capacity 0)",
+			expErr: "fifo channels need a capacity of at least 1",
+		},
+		{
+			name:   "only one end",
+			str:    "(defprocess Coordinator (!send :message ping)) (defchannel :from Coordinator)",
+			expErr: "expected both :from and :to, or neither",
+		},
+		{
+			name:   "unknown instance",
+			str:    "(defprocess Coordinator (!send :message ping)) (defchannel :from Coordinator :to Worker)",
+			expErr: "unknown instance Worker",
+		},
+		{
+			name:   "defined twice",
+			str:    "(defchannel :semantics fifo) (defchannel :semantics bag)",
+			expErr: "default channel is already defined",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("defchannel - %s", test.name), func(t *testing.T) {
+			m, err := interpretString(test.str)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			} else {
+				assert.Equal(t, nil, err, "expected err to be nil, got %v")
+				assert.Equal(t, test.expChannels, m.Channels)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	var tests = []struct {
+		name              string
+		str               string
+		inProcessBuilder  func() *processBuilder
+		expProcessBuilder func() *processBuilder
+		expErr            string
+	}{
+		{
+			name:             "branches start in the same state",
+			str:              "(select (?receive :message MessageA) (?receive :message MessageB))",
+			inProcessBuilder: newProcessBuilder,
+			expProcessBuilder: func() *processBuilder {
+				b := newProcessBuilder()
+				selectStart := b.curState
+				endA := b.allocUnnamedState()
+				endB := b.allocUnnamedState()
+				selectEnd := b.allocUnnamedState()
+
+				b.addTransition(&model.Transition{From: selectStart, To: endA, Action: model.ActionInput, Receive: "MessageA"})
+				b.addTransition(&model.Transition{From: selectStart, To: endB, Action: model.ActionInput, Receive: "MessageB"})
+				b.addTransition(&model.Transition{From: endA, To: selectEnd})
+				b.addTransition(&model.Transition{From: endB, To: selectEnd})
+				b.curState = selectEnd
+				return b
+			},
+		},
+		{
+			name:             "branches that don't end",
+			str:              "(select (goto :start) (goto :start))",
+			inProcessBuilder: newProcessBuilder,
+			expProcessBuilder: func() *processBuilder {
+				b := newProcessBuilder()
+				b.addTransition(&model.Transition{From: b.initState, To: b.initState})
+				b.addTransition(&model.Transition{From: b.initState, To: b.initState})
+				b.curState = nil
+				return b
+			},
+		},
+		{
+			name:             "no branches",
+			str:              "(select)",
+			inProcessBuilder: newProcessBuilder,
+			expErr:           "expected at least one branch",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("select - %s", test.name), func(t *testing.T) {
+			call, err := asFnCall(test.str)
+			if err != nil {
+				t.Errorf("didn't expect to fail: %v", err)
+			}
+
+			b := test.inProcessBuilder()
+			err = defprocess_select(call, b)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			} else {
+				assert.Equal(t, nil, err, "expected err to be nil, got %v")
+				assert.Equal(t, test.expProcessBuilder(), b, "expected builder %v to be equal to %v")
+			}
+		})
+	}
+}
+
+func TestLoop(t *testing.T) {
+	var tests = []struct {
+		name              string
+		str               string
+		inProcessBuilder  func() *processBuilder
+		expProcessBuilder func() *processBuilder
+		expErr            string
+	}{
+		{
+			name:             "body is repeated",
+			str:              "(loop (!send :message MessageA) (?receive :message MessageB))",
+			inProcessBuilder: newProcessBuilder,
+			expProcessBuilder: func() *processBuilder {
+				b := newProcessBuilder()
+				loopStart := b.curState
+				sent := b.allocUnnamedState()
+				received := b.allocUnnamedState()
+
+				b.addTransition(&model.Transition{From: loopStart, To: sent, Action: model.ActionOutput, Send: "MessageA"})
+				b.addTransition(&model.Transition{From: sent, To: received, Action: model.ActionInput, Receive: "MessageB"})
+				b.addTransition(&model.Transition{From: received, To: loopStart})
+				b.curState = nil
+				return b
+			},
+		},
+		{
+			name: "unreachable",
+			str:  "(loop (!send :message MessageA))",
+			inProcessBuilder: func() *processBuilder {
+				b := newProcessBuilder()
+				b.curState = nil
+				return b
+			},
+			expErr: "unreachable",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("loop - %s", test.name), func(t *testing.T) {
+			call, err := asFnCall(test.str)
+			if err != nil {
+				t.Errorf("didn't expect to fail: %v", err)
+			}
+
+			b := test.inProcessBuilder()
+			err = defprocess_loop(call, b)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			} else {
+				assert.Equal(t, nil, err, "expected err to be nil, got %v")
+				assert.Equal(t, test.expProcessBuilder(), b, "expected builder %v to be equal to %v")
+			}
+		})
+	}
+}
+
+func TestLet(t *testing.T) {
+	var tests = []struct {
+		name           string
+		str            string
+		expVars        []string
+		expTransitions []*model.Transition
+		expErr         string
+	}{
+		{
+			name:    "variables are assigned in order",
+			str:     "(defprocess P (let ((x 0) (y x)) (set! x (+ y 1))))",
+			expVars: []string{"x", "y"},
+			expTransitions: []*model.Transition{
+				{Action: model.ActionTau, Assignments: []*model.Assignment{
+					{Var: "x", Value: &model.Expression{Type: "int", Int: 0}},
+					{Var: "y", Value: &model.Expression{Type: "ref", Ref: "x"}},
+				}},
+				{Action: model.ActionTau, Assignments: []*model.Assignment{
+					{Var: "x", Value: &model.Expression{Type: "lst", Sub: []*model.Expression{
+						{Type: "ref", Ref: "+"}, {Type: "ref", Ref: "y"}, {Type: "int", Int: 1},
+					}}},
+				}},
+			},
+		},
+		{
+			name:    "fields of a received message",
+			str:     "(defprocess P (let (({key} (?receive :message job))) (!send :message done :key key)))",
+			expVars: []string{"key"},
+			expTransitions: []*model.Transition{
+				{Action: model.ActionInput, Receive: "job", Bindings: map[string]string{"key": "key"}},
+				{Action: model.ActionOutput, Send: "done", Valuation: map[string]*model.Expression{":key": {Type: "ref", Ref: "key"}}},
+			},
+		},
+		{
+			name:   "variables are local to the let",
+			str:    "(defprocess P (let ((x 0))) (set! x 1))",
+			expErr: "could not resolve variable x",
+		},
+		{
+			name:    "sibling lets share a variable",
+			str:     "(defprocess P (let ((x 0)) (set! x 1)) (let ((x 2)) (set! x 3)))",
+			expVars: []string{"x"},
+			expTransitions: []*model.Transition{
+				{Action: model.ActionTau, Assignments: []*model.Assignment{{Var: "x", Value: &model.Expression{Type: "int", Int: 0}}}},
+				{Action: model.ActionTau, Assignments: []*model.Assignment{{Var: "x", Value: &model.Expression{Type: "int", Int: 1}}}},
+				{Action: model.ActionTau, Assignments: []*model.Assignment{{Var: "x", Value: &model.Expression{Type: "int", Int: 2}}}},
+				{Action: model.ActionTau, Assignments: []*model.Assignment{{Var: "x", Value: &model.Expression{Type: "int", Int: 3}}}},
+			},
+		},
+		{
+			name:   "shadowing",
+			str:    "(defprocess P (let ((x 0)) (let ((x 1)) (set! x 2)) (set! x 3)))",
+			expErr: "x shadows a variable of an enclosing scope",
+		},
+		{
+			name:   "shadowing by a field",
+			str:    "(defprocess P (let ((key 0)) (let (({key} (?receive :message job))))))",
+			expErr: "key shadows a variable of an enclosing scope",
+		},
+		{
+			name:   "only received messages are destructured",
+			str:    "(defprocess P (let (({key} {key 1}))))",
+			expErr: "only the fields of a received message can be destructured",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("let - %s", test.name), func(t *testing.T) {
+			m, err := LoadString(test.str)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Equal(t, nil, err, "expected err to be nil, got %v")
+			p := m.Processes[0]
+			assert.Equal(t, test.expVars, p.Vars)
+			assert.Equal(t, len(test.expTransitions), len(p.Transitions))
+			for idx, exp := range test.expTransitions {
+				act := p.Transitions[idx]
+				assert.Equal(t, exp.Action, act.Action)
+				assert.Equal(t, exp.Receive, act.Receive)
+				assert.Equal(t, exp.Send, act.Send)
+				assert.Equal(t, exp.Bindings, act.Bindings)
+				assert.Equal(t, exp.Valuation, act.Valuation)
+				assert.Equal(t, exp.Assignments, act.Assignments)
+			}
+		})
+	}
+}
+
+func TestFinal(t *testing.T) {
+	var tests = []struct {
+		name     string
+		str      string
+		expFinal []string
+		expErr   string
+	}{
+		{
+			name:     "named state",
+			str:      "(defprocess P (!send :message ping) :done :final)",
+			expFinal: []string{":done"},
+		},
+		{
+			name:     "start state",
+			str:      "(defprocess P :start :final (?receive :message ping))",
+			expFinal: []string{":start"},
+		},
+		{
+			name:   "unnamed state",
+			str:    "(defprocess P (!send :message ping) :final)",
+			expErr: "only named states can be final",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("final - %s", test.name), func(t *testing.T) {
+			m, err := LoadString(test.str)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Equal(t, nil, err, "expected err to be nil, got %v")
+			final := []string{}
+			for _, s := range m.Processes[0].States {
+				if s.Final {
+					final = append(final, s.Name)
+				}
+			}
+			assert.Equal(t, test.expFinal, final)
+		})
+	}
+}
+
+func TestDefinvariant(t *testing.T) {
+	var tests = []struct {
+		name    string
+		str     string
+		expInvs []string
+		expErr  string
+	}{
+		{
+			name:    "expression over instances",
+			str:     "(definvariant idle (at Worker :start))",
+			expInvs: []string{"idle (at Worker :start)"},
+		},
+		{
+			name:   "defined twice",
+			str:    "(definvariant idle true) (definvariant idle false)",
+			expErr: "idle is already defined",
+		},
+		{
+			name:   "trailing parameters",
+			str:    "(definvariant idle true false)",
+			expErr: "trailing parameters",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("definvariant - %s", test.name), func(t *testing.T) {
+			m, err := LoadString(test.str)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Equal(t, nil, err, "expected err to be nil, got %v")
+			invs := []string{}
+			for _, inv := range m.Invariants {
+				invs = append(invs, fmt.Sprintf("%s %s", inv.Name, inv.Expr))
+			}
+			assert.Equal(t, test.expInvs, invs)
+			assert.Equal(t, "<string>:1:1", m.Invariants[0].Location)
+		})
+	}
+}
+
+func TestDefproperty(t *testing.T) {
+	m, err := LoadString("(defproperty answered (G (-> (received ping) (F (sent pong)))))")
+	assert.Equal(t, nil, err, "expected err to be nil, got %v")
+	assert.Equal(t, 1, len(m.Properties))
+	assert.Equal(t, "answered", m.Properties[0].Name)
+	assert.Equal(t, "(G (-> (received ping) (F (sent pong))))", m.Properties[0].Formula.String())
+
+	_, err = LoadString("(defproperty answered true) (defproperty answered false)")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "answered is already defined")
+}
+
+func TestFair(t *testing.T) {
+	var tests = []struct {
+		str    string
+		exp    *model.Fairness
+		expErr string
+	}{
+		{
+			str: "(fair :weak Worker)",
+			exp: &model.Fairness{Process: "Worker"},
+		},
+		{
+			str: "(fair :strong (Worker 1))",
+			exp: &model.Fairness{Strong: true, Process: "Worker", Instance: "(Worker 1)"},
+		},
+		{
+			str: "(fair :strong (Worker ?receive ack))",
+			exp: &model.Fairness{Strong: true, Process: "Worker", Receive: "ack"},
+		},
+		{
+			str: "(fair :weak ((Worker 1) !send req))",
+			exp: &model.Fairness{Process: "Worker", Instance: "(Worker 1)", Send: "req"},
+		},
+		{
+			str: "(fair :strong (channel Client (Server 2)))",
+			exp: &model.Fairness{Strong: true, From: "Client", To: "(Server 2)"},
+		},
+		{
+			str:    "(fair :sometimes Worker)",
+			expErr: "expected :weak or :strong",
+		},
+		{
+			str:    "(fair :weak (channel Client))",
+			expErr: "channel: expected 2 instances, got 1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("fair - %s", test.str), func(t *testing.T) {
+			m, err := LoadString(test.str)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Equal(t, nil, err, "expected err to be nil, got %v")
+			test.exp.Location = "<string>:1:1"
+			assert.Equal(t, []*model.Fairness{test.exp}, m.Fairness)
+			assert.Equal(t, test.str, m.Fairness[0].String())
+		})
+	}
+}
@@ -0,0 +1,463 @@
+package lisp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// LoadFile reads the spec at path together with every spec that it imports, and interprets them into a single model.
+//
+// Imports are resolved relative to the directory of the importing file:
+// - (import "messages.lisp") makes the definitions of messages.lisp available under their own names
+// - (import "messages.lisp" :as msg) makes them available as msg.name
+//
+// Namespaced definitions are named after the path through which they were imported, e.g. the message getTaskForKey
+// in messages.lisp is called msg.getTaskForKey in the model.
+func LoadFile(path string) (*model.Model, error) {
+	l := &loader{
+		stack:       []string{},
+		modules:     map[string]map[string]string{},
+		forms:       []ast{},
+		definitions: map[string]*definition{},
+	}
+
+	if _, err := l.load(path, ""); err != nil {
+		return nil, err
+	}
+
+	return interpretToplevel(l.forms, l.sources)
+}
+
+// LoadString interprets a spec that is contained in a single string. Such a spec can't import other specs.
+func LoadString(s string) (*model.Model, error) {
+	tokens, err := Tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := ParseTokenStream(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return interpretToplevel(ns, sources{{path: "<string>", text: s}})
+}
+
+type loader struct {
+	// stack contains the files that are being loaded, and is used to detect import cycles
+	stack []string
+	// modules maps the files that were loaded, keyed by path and namespace, to the names they export
+	modules map[string]map[string]string
+	// forms contains the toplevel forms of all loaded files, in the order in which they should be interpreted
+	forms []ast
+	// definitions contains the definitions of all loaded files, by their name in the model
+	definitions map[string]*definition
+	// sources contains the loaded files, the offsets of their nodes are unique across files
+	sources sources
+	size    int
+}
+
+type definition struct {
+	kind     string
+	location string
+}
+
+// load reads the file at path, and every file it imports, and appends their forms to the loader. The definitions in the
+// file are prefixed with the namespace.
+//
+// It returns the names that the file exports: those it defines itself and those it imports without a namespace,
+// mapped to their names in the model.
+func (l *loader) load(path, namespace string) (map[string]string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, p := range l.stack {
+		if p == path {
+			cycle := append(append([]string{}, l.stack[idx:]...), path)
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	key := path + "#" + namespace
+	if exports, ok := l.modules[key]; ok {
+		return exports, nil
+	}
+
+	l.stack = append(l.stack, path)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	src := &source{path: path, text: string(text), base: l.size}
+	l.sources = append(l.sources, src)
+	l.size += len(text) + 1
+
+	tokens, err := Tokenize(src.text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for idx := range tokens {
+		tokens[idx].index += src.base
+	}
+
+	ns, err := ParseTokenStream(tokens)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	exports := map[string]string{}
+	visible := map[string]string{}
+	forms := []node{}
+	for _, n := range ns {
+		call, ok := toplevelCall(n)
+		if !ok || call.fnName() != "import" {
+			forms = append(forms, n)
+			continue
+		}
+
+		imported, alias, err := parseImport(call)
+		if err != nil {
+			return nil, fmt.Errorf("%s: import: %w", src.location(n.(listNode).pos), err)
+		}
+
+		if !filepath.IsAbs(imported) {
+			imported = filepath.Join(filepath.Dir(path), imported)
+		}
+
+		if alias == "" {
+			names, err := l.load(imported, namespace)
+			if err != nil {
+				return nil, err
+			}
+
+			for name, global := range names {
+				exports[name] = global
+				visible[name] = global
+			}
+		} else {
+			names, err := l.load(imported, namespace+alias+".")
+			if err != nil {
+				return nil, err
+			}
+
+			for name, global := range names {
+				visible[alias+"."+name] = global
+			}
+		}
+	}
+
+	r := &renamer{names: visible, subprocesses: map[string]*subprocess{}}
+	for name, global := range visible {
+		if def, ok := l.definitions[global]; ok && def.kind == "defsubprocess" {
+			r.subprocesses[name] = nil
+		}
+	}
+	for _, n := range forms {
+		if call, ok := toplevelCall(n); ok {
+			if kind, name, ok := definitionName(call); ok {
+				exports[name] = namespace + name
+				visible[name] = namespace + name
+				if kind == "defsubprocess" {
+					r.subprocesses[name] = nil
+				}
+			}
+		}
+	}
+
+	for _, n := range forms {
+		n = r.toplevel(n)
+
+		if call, ok := toplevelCall(n); ok {
+			if kind, name, ok := definitionName(call); ok {
+				loc := src.location(n.(listNode).pos)
+				if def, ok := l.definitions[name]; ok {
+					return nil, fmt.Errorf("duplicate definition of %s: %s at %s and %s at %s", name, def.kind, def.location, kind, loc)
+				}
+				l.definitions[name] = &definition{kind: kind, location: loc}
+			}
+		}
+
+		l.forms = append(l.forms, n)
+	}
+
+	l.modules[key] = exports
+	return exports, nil
+}
+
+func toplevelCall(n node) (*fnCall, bool) {
+	list, ok := n.(listNode)
+	if !ok {
+		return nil, false
+	}
+
+	call, err := parseFnCall(list.nodes)
+	if err != nil {
+		return nil, false
+	}
+
+	return call, true
+}
+
+// parseImport interprets (import "path") and (import "path" :as alias).
+func parseImport(call *fnCall) (string, string, error) {
+	path, err := call.nextParam(":path").string()
+	if err != nil {
+		return "", "", err
+	}
+	path = strings.Trim(path, "\"")
+
+	alias := ""
+	if !call.isDone() {
+		alias, err = call.nextParam(":as").symbol()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if !call.isDone() {
+		return "", "", fmt.Errorf("trailing parameters")
+	}
+
+	return path, alias, nil
+}
+
+// definitionName returns the kind and name of a toplevel form that defines a named entity.
+func definitionName(call *fnCall) (string, string, bool) {
+	switch call.fnName() {
+	case "defmessage", "defprocess", "defsubprocess", "definvariant", "defproperty":
+		// Read from a copy, the call itself is interpreted later on.
+		c := *call
+		name, err := c.nextParam(":name").symbol()
+		if err != nil {
+			return "", "", false
+		}
+		return call.fnName(), name, true
+
+	default:
+		return "", "", false
+	}
+}
+
+// renamer replaces the symbols that refer to definitions by the names of those definitions in the model. Symbols that
+// name local variables, parameters or fields keep their names, even if a definition of the same name is visible.
+type renamer struct {
+	names map[string]string
+	// subprocesses contains the visible subprocesses, by the names under which they are visible
+	subprocesses map[string]*subprocess
+	// params contains the parameters of the subprocess that is being renamed, which are substituted by their arguments
+	params map[string]bool
+}
+
+// toplevel renames the references in a toplevel form.
+func (r *renamer) toplevel(n node) node {
+	list, ok := n.(listNode)
+	if !ok || len(list.nodes) < 2 {
+		return n
+	}
+	head, ok := list.nodes[0].(symbolNode)
+	if !ok {
+		return n
+	}
+
+	nodes := append([]node{head}, list.nodes[1:]...)
+	switch head.name {
+	case "defmessage", "definvariant", "defproperty":
+		// The fields of a message are not references, the expression of an invariant or property refers to instances
+		// and messages.
+		nodes[1] = r.symbol(nodes[1], nil)
+		if head.name != "defmessage" {
+			for idx := 2; idx < len(nodes); idx++ {
+				nodes[idx] = r.expression(nodes[idx], nil)
+			}
+		}
+
+	case "defprocess", "defsubprocess":
+		nodes[1] = r.symbol(nodes[1], nil)
+
+		locals := map[string]bool{}
+		idx, explicit := 2, head.name == "defsubprocess"
+		if idx < len(nodes) {
+			if key, ok := nodes[idx].(keywordNode); ok && key.name == ":params" {
+				idx, explicit = idx+1, true
+			}
+		}
+		if idx < len(nodes) && (explicit || isParamList(nodes[idx], r.subprocesses)) {
+			if params, ok := nodes[idx].(listNode); ok {
+				for _, p := range params.nodes {
+					if sym, ok := p.(symbolNode); ok {
+						locals[sym.name] = true
+					}
+				}
+				idx++
+			}
+		}
+		if head.name == "defsubprocess" {
+			r.params = locals
+			defer func() { r.params = nil }()
+		}
+		r.statements(nodes[idx:], locals)
+
+	case "defsystem", "fair":
+		for idx := 1; idx < len(nodes); idx++ {
+			nodes[idx] = r.expression(nodes[idx], nil)
+		}
+
+	case "defchannel":
+		for idx := 1; idx+1 < len(nodes); idx += 2 {
+			if key, ok := nodes[idx].(keywordNode); ok && (key.name == ":from" || key.name == ":to") {
+				nodes[idx+1] = r.expression(nodes[idx+1], nil)
+			}
+		}
+	}
+
+	return listNode{nodes, list.pos}
+}
+
+// statements renames the references in the statements of a body, in place. The variables that the statements bind
+// are added to locals, as the interpreter adds them to the innermost scope.
+func (r *renamer) statements(ns []node, locals map[string]bool) {
+	for idx := range ns {
+		ns[idx] = r.statement(ns[idx], locals)
+	}
+}
+
+func (r *renamer) statement(n node, locals map[string]bool) node {
+	list, ok := n.(listNode)
+	if !ok || len(list.nodes) == 0 {
+		return n
+	}
+	head, ok := list.nodes[0].(symbolNode)
+	if !ok {
+		return n
+	}
+
+	nodes := append([]node{head}, list.nodes[1:]...)
+	switch head.name {
+	case "!send", "?receive":
+		for idx := 1; idx < len(nodes); idx++ {
+			key, ok := nodes[idx].(keywordNode)
+			switch {
+			case !ok && idx == 1:
+				// The message, without its keyword. A message is never a variable, but may be a parameter of a
+				// subprocess.
+				nodes[idx] = r.symbol(nodes[idx], r.params)
+			case !ok || idx+1 == len(nodes):
+			case key.name == ":message":
+				idx++
+				nodes[idx] = r.symbol(nodes[idx], r.params)
+			case key.name == ":from" && head.name == "?receive":
+				// The sender is bound to a variable
+				idx++
+				if sym, ok := nodes[idx].(symbolNode); ok {
+					locals[sym.name] = true
+				}
+			default:
+				idx++
+				nodes[idx] = r.expression(nodes[idx], locals)
+			}
+		}
+
+	case "let":
+		scope := map[string]bool{}
+		for name := range locals {
+			scope[name] = true
+		}
+
+		if len(nodes) < 2 {
+			break
+		}
+		if bindings, ok := nodes[1].(listNode); ok {
+			renamed := []node{}
+			for _, binding := range bindings.nodes {
+				b, ok := binding.(listNode)
+				if !ok || len(b.nodes) != 2 {
+					renamed = append(renamed, binding)
+					continue
+				}
+
+				switch pattern := b.nodes[0].(type) {
+				case symbolNode:
+					b = listNode{[]node{pattern, r.expression(b.nodes[1], scope)}, b.pos}
+					scope[pattern.name] = true
+				case mapNode:
+					b = listNode{[]node{pattern, r.statement(b.nodes[1], scope)}, b.pos}
+					for _, field := range pattern.nodes {
+						if sym, ok := field.(symbolNode); ok {
+							scope[sym.name] = true
+						}
+					}
+				}
+				renamed = append(renamed, b)
+			}
+			nodes[1] = listNode{renamed, bindings.pos}
+		}
+		r.statements(nodes[2:], scope)
+
+	case "set!":
+		for idx := 2; idx < len(nodes); idx++ {
+			nodes[idx] = r.expression(nodes[idx], locals)
+		}
+
+	case "if":
+		nodes[1] = r.expression(nodes[1], locals)
+		r.statements(nodes[2:], locals)
+
+	case "select", "loop":
+		r.statements(nodes[1:], locals)
+
+	case "goto":
+
+	default:
+		// A call of a subprocess, its arguments are substituted into its body
+		nodes[0] = r.symbol(head, locals)
+		for idx := 1; idx < len(nodes); idx++ {
+			nodes[idx] = r.expression(nodes[idx], locals)
+		}
+	}
+
+	return listNode{nodes, list.pos}
+}
+
+// expression renames the symbols of an expression that aren't local, e.g. the instance (Worker 1) or a message.
+func (r *renamer) expression(n node, locals map[string]bool) node {
+	switch n := n.(type) {
+	case symbolNode:
+		return r.symbol(n, locals)
+
+	case listNode:
+		nodes := []node{}
+		for _, cn := range n.nodes {
+			nodes = append(nodes, r.expression(cn, locals))
+		}
+		return listNode{nodes, n.pos}
+
+	case mapNode:
+		nodes := []node{}
+		for _, cn := range n.nodes {
+			nodes = append(nodes, r.expression(cn, locals))
+		}
+		return mapNode{nodes, n.pos}
+
+	default:
+		return n
+	}
+}
+
+func (r *renamer) symbol(n node, locals map[string]bool) node {
+	sym, ok := n.(symbolNode)
+	if !ok || locals[sym.name] {
+		return n
+	}
+	if global, ok := r.names[sym.name]; ok {
+		return symbolNode{global}
+	}
+	return n
+}
@@ -0,0 +1,195 @@
+package lisp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFile(t *testing.T) {
+	var tests = []struct {
+		name         string
+		files        map[string]string
+		expMessages  []string
+		expProcesses []string
+		expErr       []string
+	}{
+		{
+			name: "single file",
+			files: map[string]string{
+				"spec.lisp": "(defmessage ping)",
+			},
+			expMessages:  []string{"ping"},
+			expProcesses: []string{},
+		},
+		{
+			name: "import without namespace",
+			files: map[string]string{
+				"spec.lisp":     "(import \"messages.lisp\")\n(defprocess Client (!send :message ping))",
+				"messages.lisp": "(defmessage ping)",
+			},
+			expMessages:  []string{"ping"},
+			expProcesses: []string{"Client"},
+		},
+		{
+			name: "import with namespace",
+			files: map[string]string{
+				"spec.lisp":     "(import \"messages.lisp\" :as msg)\n(defprocess Client (!send :message msg.ping))",
+				"messages.lisp": "(defmessage ping)\n(defprocess Server (?receive :message ping))",
+			},
+			expMessages:  []string{"msg.ping"},
+			expProcesses: []string{"msg.Server", "Client"},
+		},
+		{
+			name: "nested namespaces",
+			files: map[string]string{
+				"spec.lisp":         "(import \"lib/server.lisp\" :as srv)",
+				"lib/server.lisp":   "(import \"messages.lisp\" :as msg)\n(defprocess Server (?receive :message msg.ping))",
+				"lib/messages.lisp": "(defmessage ping)",
+			},
+			expMessages:  []string{"srv.msg.ping"},
+			expProcesses: []string{"srv.Server"},
+		},
+		{
+			name: "diamond imports are loaded once",
+			files: map[string]string{
+				"spec.lisp":     "(import \"client.lisp\")\n(import \"server.lisp\")",
+				"client.lisp":   "(import \"messages.lisp\")\n(defprocess Client (!send :message ping))",
+				"server.lisp":   "(import \"messages.lisp\")\n(defprocess Server (?receive :message ping))",
+				"messages.lisp": "(defmessage ping)",
+			},
+			expMessages:  []string{"ping"},
+			expProcesses: []string{"Client", "Server"},
+		},
+		{
+			name: "import cycle",
+			files: map[string]string{
+				"spec.lisp": "(import \"a.lisp\")",
+				"a.lisp":    "(import \"b.lisp\")",
+				"b.lisp":    "(import \"a.lisp\")",
+			},
+			expErr: []string{"import cycle", "a.lisp -> ", "b.lisp -> ", "a.lisp"},
+		},
+		{
+			name: "duplicate definitions",
+			files: map[string]string{
+				"spec.lisp":     "(import \"messages.lisp\")\n\n  (defmessage ping)",
+				"messages.lisp": "(defmessage pong)\n(defmessage ping)",
+			},
+			expErr: []string{"duplicate definition of ping", "messages.lisp:2:1", "spec.lisp:3:3"},
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"spec.lisp": "(import \"messages.lisp\")",
+			},
+			expErr: []string{"messages.lisp"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("LoadFile - %s", test.name), func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				path := filepath.Join(dir, name)
+				assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+			}
+
+			m, err := LoadFile(filepath.Join(dir, "spec.lisp"))
+
+			if test.expErr != nil {
+				assert.Error(t, err)
+				for _, expErr := range test.expErr {
+					assert.Contains(t, err.Error(), expErr)
+				}
+			} else {
+				assert.Equal(t, nil, err, "expected err to be nil, got %v")
+
+				messages := []string{}
+				for _, m := range m.Messages {
+					messages = append(messages, m.Name)
+				}
+				processes := []string{}
+				for _, p := range m.Processes {
+					processes = append(processes, p.Name)
+				}
+
+				assert.Equal(t, test.expMessages, messages)
+				assert.Equal(t, test.expProcesses, processes)
+			}
+		})
+	}
+}
+
+func TestLoadFileRenamesReferences(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "spec.lisp"), []byte("(import \"messages.lisp\" :as msg)\n(defprocess Client (!send :message msg.ping))"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "messages.lisp"), []byte("(defmessage ping)\n(defprocess Server (?receive :message ping))"), 0o644))
+
+	m, err := LoadFile(filepath.Join(dir, "spec.lisp"))
+	assert.Equal(t, nil, err, "expected err to be nil, got %v")
+
+	assert.Equal(t, "msg.ping", m.Processes[0].Transitions[0].Receive)
+	assert.Equal(t, "msg.ping", m.Processes[1].Transitions[0].Send)
+}
+
+func TestLoadFileLocations(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "spec.lisp"), []byte("(import \"server.lisp\")\n(defprocess Client\n  (!send :message ping))"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "server.lisp"), []byte("(defprocess Server\n  (?receive :message ping)\n  (if (= 1 1)\n      (!send :message pong)))"), 0o644))
+
+	m, err := LoadFile(filepath.Join(dir, "spec.lisp"))
+	assert.Equal(t, nil, err, "expected err to be nil, got %v")
+
+	server, client := m.Processes[0], m.Processes[1]
+	assert.Equal(t, filepath.Join(dir, "server.lisp")+":2:3", server.Transitions[0].Location)
+	assert.Equal(t, filepath.Join(dir, "server.lisp")+":3:3", server.Transitions[1].Location)
+	assert.Equal(t, filepath.Join(dir, "server.lisp")+":4:7", server.Transitions[2].Location)
+	assert.Equal(t, filepath.Join(dir, "spec.lisp")+":3:3", client.Transitions[0].Location)
+}
+
+func TestLoadFileKeepsLocalNames(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "spec.lisp"), []byte("(import \"server.lisp\" :as srv)"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "server.lisp"), []byte(`
+		(defmessage count)
+		(defprocess Server
+		  (let ((count 0))
+		    (loop
+		      (?receive :message count :from client)
+		      (set! count (+ count 1))
+		      (!send :message count :to client :count count))))
+		(defprocess Worker :params (count)
+		  (!send :message count :to Server :count count))`), 0o644))
+
+	m, err := LoadFile(filepath.Join(dir, "spec.lisp"))
+	assert.Equal(t, nil, err, "expected err to be nil, got %v")
+
+	server, worker := m.Processes[0], m.Processes[1]
+	assert.Equal(t, "srv.Server", server.Name)
+	assert.Equal(t, []string{"client", "count"}, server.Vars)
+
+	sends := []string{}
+	for _, t := range append(server.Transitions, worker.Transitions...) {
+		switch {
+		case t.Receive != "":
+			sends = append(sends, fmt.Sprintf("?%s", t.Receive))
+		case t.Send != "":
+			sends = append(sends, fmt.Sprintf("!%s %s %s", t.Send, t.Peer, t.Valuation[":count"]))
+		case len(t.Assignments) != 0:
+			sends = append(sends, fmt.Sprintf("%s = %s", t.Assignments[0].Var, t.Assignments[0].Value))
+		}
+	}
+	assert.Equal(t, []string{
+		"count = 0",
+		"?srv.count",
+		"count = (+ count 1)",
+		"!srv.count client count",
+		"!srv.count srv.Server count",
+	}, sends)
+	assert.Equal(t, []string{"count"}, worker.Params)
+}
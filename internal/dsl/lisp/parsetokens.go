@@ -34,6 +34,10 @@ func readNode(ts *tokenStream) (node, bool) {
 		return node, ok
 	}
 
+	if node, ok := readMapNode(ts); ok {
+		return node, ok
+	}
+
 	if node, ok := readIntNode(ts); ok {
 		return node, ok
 	}
@@ -59,7 +63,7 @@ func readListNode(ts *tokenStream) (node, bool) {
 	if !ts.nextTokenIs(tokenTypePunctuation, "(") {
 		return nil, false
 	}
-	ts.next()
+	open, _ := ts.next()
 
 	nodes := []node{}
 	for {
@@ -82,7 +86,36 @@ func readListNode(ts *tokenStream) (node, bool) {
 	}
 
 done:
-	return listNode{nodes}, true
+	return listNode{nodes, open.index}, true
+}
+
+func readMapNode(ts *tokenStream) (node, bool) {
+	idx := ts.position()
+
+	if !ts.nextTokenIs(tokenTypePunctuation, "{") {
+		return nil, false
+	}
+	open, _ := ts.next()
+
+	nodes := []node{}
+	for {
+		switch {
+		case ts.eof():
+			ts.seek(idx)
+			return nil, false
+
+		case ts.nextTokenIs(tokenTypePunctuation, "}"):
+			ts.next()
+			return mapNode{nodes, open.index}, true
+
+		default:
+			node, ok := readNode(ts)
+			if !ok {
+				return nil, false
+			}
+			nodes = append(nodes, node)
+		}
+	}
 }
 
 func readStringNode(ts *tokenStream) (node, bool) {
@@ -118,7 +151,7 @@ func readKeyword(ts *tokenStream) (node, bool) {
 		return nil, false
 	}
 	ts.next()
-	return keywordNode{t.val}, true
+	return keywordNode{t.val, t.index}, true
 }
 
 func readSymbol(ts *tokenStream) (node, bool) {
@@ -15,7 +15,7 @@ func TestParseTokenStream(t *testing.T) {
 	}{
 		{
 			str:     "()",
-			expNode: []node{listNode{[]node{}}},
+			expNode: []node{listNode{[]node{}, 0}},
 		},
 		{
 			str: "(+ 12 34)",
@@ -25,7 +25,7 @@ func TestParseTokenStream(t *testing.T) {
 						symbolNode{"+"},
 						intNode{12},
 						intNode{34},
-					}},
+					}, 0},
 			},
 		},
 		{
@@ -35,7 +35,7 @@ func TestParseTokenStream(t *testing.T) {
 					[]node{
 						symbolNode{"print"},
 						stringNode{"\"Hello, World!\""},
-					},
+					}, 0,
 				},
 			},
 		},
@@ -45,11 +45,11 @@ func TestParseTokenStream(t *testing.T) {
 				listNode{
 					[]node{
 						symbolNode{"!send"},
-						keywordNode{":channel"},
+						keywordNode{":channel", 7},
 						symbolNode{"channel"},
-						keywordNode{":message"},
+						keywordNode{":message", 24},
 						symbolNode{"noResult"},
-					}},
+					}, 0},
 			},
 		},
 		{
@@ -62,7 +62,7 @@ func TestParseTokenStream(t *testing.T) {
 						listNode{
 							[]node{
 								symbolNode{"n"},
-							},
+							}, 9,
 						},
 						listNode{
 							[]node{
@@ -72,7 +72,7 @@ func TestParseTokenStream(t *testing.T) {
 										symbolNode{"<="},
 										symbolNode{"n"},
 										intNode{1},
-									},
+									}, 17,
 								},
 								intNode{1},
 								listNode{
@@ -83,16 +83,46 @@ func TestParseTokenStream(t *testing.T) {
 												symbolNode{"-"},
 												symbolNode{"n"},
 												intNode{1},
-											},
+											}, 31,
 										},
-									},
+									}, 28,
 								},
-							},
+							}, 13,
 						},
-					},
+					}, 0,
 				},
 			},
 		},
+		{
+			str: "(let (({key} (?receive)) (m {})))",
+			expNode: []node{
+				listNode{
+					[]node{
+						symbolNode{"let"},
+						listNode{
+							[]node{
+								listNode{
+									[]node{
+										mapNode{[]node{symbolNode{"key"}}, 7},
+										listNode{[]node{symbolNode{"?receive"}}, 13},
+									}, 6,
+								},
+								listNode{
+									[]node{
+										symbolNode{"m"},
+										mapNode{[]node{}, 28},
+									}, 25,
+								},
+							}, 5,
+						},
+					}, 0,
+				},
+			},
+		},
+		{
+			str:     "\n  (foo)",
+			expNode: []node{listNode{[]node{symbolNode{"foo"}}, 3}},
+		},
 	}
 
 	for _, test := range tests {
@@ -2,6 +2,7 @@ package lisp
 
 import (
 	"fmt"
+	"sort"
 
 	"dberk.nl/graphchecker/internal/model"
 )
@@ -13,8 +14,23 @@ type processBuilder struct {
 	states                        []*model.State
 	transitions                   []*model.Transition
 	namedStates                   map[string]*model.State
+	// placedStates contains the names of the states whose label was reached, a goto may name a state before that
+	placedStates                  map[string]bool
 	variables                     map[string]*model.Variable
 	scopes                        []map[string]*model.Variable
+	subprocesses                  map[string]*subprocess
+	stateScopes                   []*stateScope
+	expansionCounter              int
+	guardCounter                  int
+	// sources and pos locate the statement that is being interpreted
+	sources sources
+	pos     int
+}
+
+// stateScope renames the states that are local to a single expansion of a subprocess.
+type stateScope struct {
+	subprocess *subprocess
+	suffix     string
 }
 
 func newProcessBuilder() *processBuilder {
@@ -25,10 +41,14 @@ func newProcessBuilder() *processBuilder {
 		states:       []*model.State{},
 		transitions:  []*model.Transition{},
 		namedStates:  map[string]*model.State{},
+		placedStates: map[string]bool{},
+		variables:    map[string]*model.Variable{},
+		scopes:       []map[string]*model.Variable{{}},
 	}
 
 
 	p.initState, _ = p.allocNamedState(":start");
+	p.placedStates[":start"] = true
 	p.curState = p.initState
 	return p
 }
@@ -53,6 +73,13 @@ func (b *processBuilder) allocNamedState(name string) (*model.State, error) {
 }
 
 func (b *processBuilder) addTransition(t *model.Transition) {
+	if t.Action == "" {
+		// Control steps that the interpreter generates are internal.
+		t.Action = model.ActionTau
+	}
+	if b.sources != nil {
+		t.Location = b.sources.location(b.pos)
+	}
 	b.transitions = append(b.transitions, t)
 }
 
@@ -73,11 +100,21 @@ func (b *processBuilder) closeLexicalScope() {
 	b.scopes = b.scopes[:len(b.scopes)-1]
 }
 
-func (b *processBuilder) allocVariable(name string) {
+// allocVariable binds a variable in the innermost lexical scope. The variables of a process are named after the
+// variables in the DSL, so a variable may not shadow a variable of an enclosing scope. Binding it again in the same
+// scope, or in a scope that was closed, refers to the same variable of the process.
+func (b *processBuilder) allocVariable(name string) error {
+	for idx := len(b.scopes) - 2; 0 <= idx; idx-- {
+		if _, ok := b.scopes[idx][name]; ok {
+			return fmt.Errorf("%s shadows a variable of an enclosing scope", name)
+		}
+	}
+
 	v := &model.Variable{ID: b.variableCounter, Name: name}
 	b.variables[name] = v
 	b.variableCounter++
 	b.scopes[len(b.scopes)-1][name] = v
+	return nil
 }
 
 func (b *processBuilder) resolveVariable(name string) (*model.Variable, error) {
@@ -89,3 +126,45 @@ func (b *processBuilder) resolveVariable(name string) (*model.Variable, error) {
 
 	return nil, fmt.Errorf("could not resolve variable %s", name)
 }
+
+func (b *processBuilder) openStateScope(s *subprocess) {
+	b.expansionCounter++
+	b.stateScopes = append(b.stateScopes, &stateScope{
+		subprocess: s,
+		suffix:     fmt.Sprintf("@%s.%d", s.name, b.expansionCounter),
+	})
+}
+
+func (b *processBuilder) closeStateScope() {
+	b.stateScopes = b.stateScopes[:len(b.stateScopes)-1]
+}
+
+// scopedStateName maps the name of a state as it is written in the DSL onto the name of the state in the process.
+//
+// Names of states that are local to a subprocess are suffixed with the identity of the expansion, names that are not
+// local to the innermost expansion are resolved in the enclosing expansions, up to the process itself.
+func (b *processBuilder) scopedStateName(name string) string {
+	for idx := len(b.stateScopes) - 1; 0 <= idx; idx-- {
+		scope := b.stateScopes[idx]
+		if scope.subprocess.labels[name] {
+			return name + scope.suffix
+		}
+	}
+
+	return name
+}
+
+func (b *processBuilder) build(name string) *model.Process {
+	vars := []string{}
+	for name := range b.variables {
+		vars = append(vars, name)
+	}
+	sort.Strings(vars)
+
+	return &model.Process{
+		Name:        name,
+		Vars:        vars,
+		States:      b.states,
+		Transitions: b.transitions,
+	}
+}
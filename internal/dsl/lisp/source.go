@@ -0,0 +1,44 @@
+package lisp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// source is a file that is being interpreted, it is used to translate offsets of nodes into human readable locations.
+//
+// When several files are loaded, every file gets its own range of offsets that starts at its base, so that an offset
+// identifies both the file and the position within it.
+type source struct {
+	path string
+	text string
+	base int
+}
+
+// location returns the path, line and column of the offset as path:line:column.
+func (s *source) location(pos int) string {
+	if s == nil {
+		return fmt.Sprintf("offset %d", pos)
+	}
+
+	pos -= s.base
+	before := s.text[:max(0, min(pos, len(s.text)))]
+	line := strings.Count(before, "\n") + 1
+	col := pos - strings.LastIndex(before, "\n")
+
+	return fmt.Sprintf("%s:%d:%d", s.path, line, col)
+}
+
+// sources are the files of a spec, ordered by base.
+type sources []*source
+
+// location returns the location of the offset in the file that contains it, or an empty string if the offset is not
+// part of any file.
+func (ss sources) location(pos int) string {
+	for idx := len(ss) - 1; 0 <= idx; idx-- {
+		if ss[idx].base <= pos {
+			return ss[idx].location(pos)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package lisp
+
+import (
+	"fmt"
+)
+
+// subprocess is a parameterised body fragment defined by defsubprocess. It is expanded inline wherever a process body
+// calls it by name.
+//
+// Expansion is hygienic with respect to named states: every state that is named within the fragment body is local to a
+// single expansion, so expanding the same fragment twice does not collide. States that are not named within the
+// fragment, such as :start, refer to the states of the enclosing process.
+type subprocess struct {
+	name   string
+	params []string
+	body   []node
+	labels map[string]bool
+}
+
+// defsubprocess interprets (defsubprocess name (params...) body...).
+func defsubprocess(call *fnCall) (*subprocess, error) {
+	name, err := call.nextParam(":name").symbol()
+	if err != nil {
+		return nil, err
+	}
+
+	paramNodes, err := call.nextParam(":params").list()
+	if err != nil {
+		return nil, err
+	}
+
+	params := []string{}
+	for _, n := range paramNodes {
+		sym, ok := n.(symbolNode)
+		if !ok {
+			return nil, fmt.Errorf("expected symbolNode as parameter, got %s", n.Kind())
+		}
+		params = append(params, sym.name)
+	}
+
+	body := []node{}
+	for !call.isDone() {
+		n, err := call.nextUnnamedParam().node()
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, n)
+	}
+
+	return &subprocess{
+		name:   name,
+		params: params,
+		body:   body,
+		labels: collectLabels(body, map[string]bool{}),
+	}, nil
+}
+
+// collectLabels returns the names of the states that are named in the statements of a body.
+func collectLabels(ns []node, labels map[string]bool) map[string]bool {
+	for _, n := range ns {
+		switch n := n.(type) {
+		case keywordNode:
+			if n.name != ":final" {
+				labels[n.name] = true
+			}
+
+		case listNode:
+			if len(n.nodes) == 0 {
+				continue
+			}
+
+			sym, ok := n.nodes[0].(symbolNode)
+			switch {
+			case !ok:
+			case sym.name == "if" && 2 < len(n.nodes):
+				// (if guard then else), only the branches are statements
+				collectLabels(n.nodes[2:], labels)
+			case sym.name == "select" || sym.name == "loop":
+				collectLabels(n.nodes[1:], labels)
+			case sym.name == "let" && 2 < len(n.nodes):
+				// (let bindings statement...)
+				collectLabels(n.nodes[2:], labels)
+			}
+		}
+	}
+
+	return labels
+}
+
+// expand interprets a call of the subprocess in the body of the process that is being built.
+func (s *subprocess) expand(call *fnCall, b *processBuilder) error {
+	args := map[string]node{}
+	for _, p := range s.params {
+		arg, err := call.nextUnnamedParam().node()
+		if err != nil {
+			return fmt.Errorf("expected %d argument(s), got %d", len(s.params), len(args))
+		}
+		args[p] = arg
+	}
+
+	if !call.isDone() {
+		return fmt.Errorf("expected %d argument(s), got more", len(s.params))
+	}
+
+	for _, scope := range b.stateScopes {
+		if scope.subprocess == s {
+			return fmt.Errorf("recursive expansion of %s", s.name)
+		}
+	}
+
+	body := []node{}
+	for _, n := range s.body {
+		body = append(body, substitute(n, args))
+	}
+
+	b.openStateScope(s)
+	defer b.closeStateScope()
+
+	return defprocess_body(body, b)
+}
+
+// substitute replaces every symbol that names a parameter with the argument that was passed for it.
+func substitute(n node, args map[string]node) node {
+	switch n := n.(type) {
+	case symbolNode:
+		if arg, ok := args[n.name]; ok {
+			return arg
+		}
+		return n
+
+	case listNode:
+		nodes := []node{}
+		for _, cn := range n.nodes {
+			nodes = append(nodes, substitute(cn, args))
+		}
+		return listNode{nodes, n.pos}
+
+	case mapNode:
+		nodes := []node{}
+		for _, cn := range n.nodes {
+			nodes = append(nodes, substitute(cn, args))
+		}
+		return mapNode{nodes, n.pos}
+
+	default:
+		return n
+	}
+}
@@ -0,0 +1,125 @@
+package lisp
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubprocess(t *testing.T) {
+	var tests = []struct {
+		name      string
+		str       string
+		expStates []string
+		expErr    string
+	}{
+		{
+			name: "expanded twice",
+			str: `(defsubprocess request-response (req resp)
+			        :request
+			        (!send :message req)
+			        (?receive :message resp))
+			      (defprocess Client
+			        (request-response getTaskForKey taskForKey)
+			        (request-response getTaskForKey taskForKey))`,
+			expStates: []string{":start", ":request@request-response.1", "", "", ":request@request-response.2", "", ""},
+		},
+		{
+			name: "goto a local state",
+			str: `(defsubprocess retry (req)
+			        :retry
+			        (!send :message req)
+			        (goto :retry))
+			      (defprocess Client
+			        (retry ping))`,
+			expStates: []string{":start", ":retry@retry.1", ""},
+		},
+		{
+			name: "goto a state of the enclosing process",
+			str: `(defsubprocess restart ()
+			        (goto :start))
+			      (defprocess Client
+			        (!send :message ping)
+			        (restart))`,
+			expStates: []string{":start", ""},
+		},
+		{
+			name: "nested expansion",
+			str: `(defsubprocess inner ()
+			        :step
+			        (!send :message ping))
+			      (defsubprocess outer ()
+			        :step
+			        (inner))
+			      (defprocess Client
+			        (outer))`,
+			expStates: []string{":start", ":step@outer.1", ":step@inner.2", ""},
+		},
+		{
+			name: "wrong number of arguments",
+			str: `(defsubprocess request-response (req resp)
+			        (!send :message req)
+			        (?receive :message resp))
+			      (defprocess Client
+			        (request-response getTaskForKey))`,
+			expErr: "expected 2 argument(s), got 1",
+		},
+		{
+			name: "recursive expansion",
+			str: `(defsubprocess forever ()
+			        (forever))
+			      (defprocess Client
+			        (forever))`,
+			expErr: "recursive expansion of forever",
+		},
+		{
+			name: "defined twice",
+			str: `(defsubprocess restart () (goto :start))
+			      (defsubprocess restart () (goto :start))`,
+			expErr: "restart is already defined",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("subprocess - %s", test.name), func(t *testing.T) {
+			m, err := interpretString(test.str)
+
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			} else {
+				assert.Equal(t, nil, err, "expected err to be nil, got %v")
+				assert.Equal(t, test.expStates, stateNames(m.Processes[0]))
+			}
+		})
+	}
+}
+
+func TestSubprocessSubstitution(t *testing.T) {
+	m, err := interpretString(`
+		(defsubprocess request-response (req resp)
+		  (!send :message req)
+		  (?receive :message resp))
+		(defprocess Client
+		  (request-response getTaskForKey taskForKey))`)
+	assert.Equal(t, nil, err, "expected err to be nil, got %v")
+
+	transitions := m.Processes[0].Transitions
+	assert.Equal(t, 2, len(transitions))
+	assert.Equal(t, "getTaskForKey", transitions[0].Send)
+	assert.Equal(t, "taskForKey", transitions[1].Receive)
+}
+
+func interpretString(s string) (*model.Model, error) {
+	return LoadString(s)
+}
+
+func stateNames(p *model.Process) []string {
+	names := []string{}
+	for _, s := range p.States {
+		names = append(names, s.Name)
+	}
+	return names
+}
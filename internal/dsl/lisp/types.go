@@ -8,7 +8,7 @@ const (
 	tokenTypeWord                    = "word"
 )
 
-type ast node
+type ast = node
 
 type token struct {
 	typ   tokenType
@@ -20,8 +20,10 @@ type node interface {
 	Kind() string
 }
 
+// listNode is a list, pos is the offset of its opening parenthesis in the source.
 type listNode struct {
 	nodes []node
+	pos   int
 }
 
 func (_ listNode) Kind() string {
@@ -30,6 +32,18 @@ func (_ listNode) Kind() string {
 
 var _ node = (*listNode)(nil)
 
+// mapNode is a list between braces, e.g. a map literal {key value} or a destructuring pattern {field}.
+type mapNode struct {
+	nodes []node
+	pos   int
+}
+
+func (_ mapNode) Kind() string {
+	return "map"
+}
+
+var _ node = (*mapNode)(nil)
+
 type stringNode struct {
 	str string
 }
@@ -50,8 +64,10 @@ func (_ intNode) Kind() string {
 
 var _ node = (*intNode)(nil)
 
+// keywordNode is a keyword such as :start, pos is its offset in the source.
 type keywordNode struct {
 	name string
+	pos  int
 }
 
 func (_ keywordNode) Kind() string {
@@ -0,0 +1,415 @@
+package eval
+
+import (
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Scope resolves the names that are referred to by an expression, e.g. variables and functions.
+type Scope interface {
+	Lookup(name string) (Value, bool)
+}
+
+// Vars is a scope that is backed by a map.
+type Vars map[string]Value
+
+func (v Vars) Lookup(name string) (Value, bool) {
+	val, ok := v[name]
+	return val, ok
+}
+
+// Chain is a scope that resolves names in its scopes, in order.
+type Chain []Scope
+
+func (c Chain) Lookup(name string) (Value, bool) {
+	for _, s := range c {
+		if val, ok := s.Lookup(name); ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+var constants = Vars{
+	"true":  Bool(true),
+	"false": Bool(false),
+	"nil":   Nil,
+}
+
+// Eval evaluates the expression, names that are not built in are resolved in the scope.
+func Eval(expr *model.Expression, scope Scope) (Value, error) {
+	switch expr.Type {
+	case "int":
+		return Int(expr.Int), nil
+
+	case "kw":
+		return Keyword(expr.Ref), nil
+
+	case "ref":
+		if val, ok := scope.Lookup(expr.Ref); ok {
+			return val, nil
+		}
+		if val, ok := constants[expr.Ref]; ok {
+			return val, nil
+		}
+		return nil, fmt.Errorf("could not resolve %s", expr.Ref)
+
+	case "map":
+		m := EmptyMap
+		for idx := 0; idx+1 < len(expr.Sub); idx += 2 {
+			key, err := Eval(expr.Sub[idx], scope)
+			if err != nil {
+				return nil, err
+			}
+
+			val, err := Eval(expr.Sub[idx+1], scope)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Put(key, val)
+		}
+		return m, nil
+
+	case "lst":
+		if len(expr.Sub) == 0 || expr.Sub[0].Type != "ref" {
+			return nil, fmt.Errorf("expected a function call, got %s", expr)
+		}
+
+		name := expr.Sub[0].Ref
+		args := expr.Sub[1:]
+		if special, ok := specialForms[name]; ok {
+			return special(args, scope)
+		}
+
+		vals := []Value{}
+		for _, arg := range args {
+			val, err := Eval(arg, scope)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+
+		if fn, ok := builtins[name]; ok {
+			for _, val := range vals {
+				if val == Unknown {
+					return Unknown, nil
+				}
+			}
+
+			val, err := fn(vals)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			return val, nil
+		}
+
+		val, ok := scope.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown function %s", name)
+		}
+
+		fn, ok := val.(Func)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a function", name)
+		}
+
+		val, err := fn(vals)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return val, nil
+
+	default:
+		return nil, fmt.Errorf("unhandled expression type %s", expr.Type)
+	}
+}
+
+// specialForms are evaluated before their arguments, so that they can short-circuit.
+var specialForms map[string]func(args []*model.Expression, scope Scope) (Value, error)
+
+func init() {
+	specialForms = map[string]func(args []*model.Expression, scope Scope) (Value, error){
+		"and": evalAnd,
+		"or":  evalOr,
+		"if":  evalIf,
+	}
+}
+
+func evalAnd(args []*model.Expression, scope Scope) (Value, error) {
+	result := Value(Bool(true))
+	for _, arg := range args {
+		val, err := Eval(arg, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		b, known := Truthy(val)
+		switch {
+		case !known:
+			result = Unknown
+		case !b:
+			return Bool(false), nil
+		}
+	}
+	return result, nil
+}
+
+func evalOr(args []*model.Expression, scope Scope) (Value, error) {
+	result := Value(Bool(false))
+	for _, arg := range args {
+		val, err := Eval(arg, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		b, known := Truthy(val)
+		switch {
+		case !known:
+			result = Unknown
+		case b:
+			return Bool(true), nil
+		}
+	}
+	return result, nil
+}
+
+func evalIf(args []*model.Expression, scope Scope) (Value, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("if: expected 3 arguments, got %d", len(args))
+	}
+
+	guard, err := Eval(args[0], scope)
+	if err != nil {
+		return nil, err
+	}
+
+	b, known := Truthy(guard)
+	switch {
+	case !known:
+		return Unknown, nil
+	case b:
+		return Eval(args[1], scope)
+	default:
+		return Eval(args[2], scope)
+	}
+}
+
+var builtins = map[string]func(args []Value) (Value, error){
+	"+":   arithmetic(func(a, b int64) (int64, error) { return a + b, nil }),
+	"-":   arithmetic(func(a, b int64) (int64, error) { return a - b, nil }),
+	"*":   arithmetic(func(a, b int64) (int64, error) { return a * b, nil }),
+	"/":   arithmetic(divide),
+	"mod": arithmetic(modulo),
+
+	"=":   equal,
+	"!=":  notEqual,
+	"<":   comparison(func(a, b int64) bool { return a < b }),
+	"<=":  comparison(func(a, b int64) bool { return a <= b }),
+	">":   comparison(func(a, b int64) bool { return a > b }),
+	">=":  comparison(func(a, b int64) bool { return a >= b }),
+	"not": not,
+
+	"map-get":       mapGet,
+	"map-contains?": mapContains,
+	"map-put":       mapPut,
+	"map-remove":    mapRemove,
+
+	"list":   list,
+	"len":    length,
+	"empty?": empty,
+	"empty":  empty,
+	"count":  count,
+}
+
+func ints(args []Value) ([]int64, error) {
+	is := []int64{}
+	for _, arg := range args {
+		i, ok := arg.(Int)
+		if !ok {
+			return nil, fmt.Errorf("expected an integer, got %s", arg)
+		}
+		is = append(is, int64(i))
+	}
+	return is, nil
+}
+
+func arithmetic(op func(a, b int64) (int64, error)) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		is, err := ints(args)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(is) == 0 {
+			return nil, fmt.Errorf("expected at least one argument")
+		}
+
+		result := is[0]
+		for _, i := range is[1:] {
+			if result, err = op(result, i); err != nil {
+				return nil, err
+			}
+		}
+		return Int(result), nil
+	}
+}
+
+func divide(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return a / b, nil
+}
+
+func modulo(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return a % b, nil
+}
+
+func comparison(op func(a, b int64) bool) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		is, err := ints(args)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(is) != 2 {
+			return nil, fmt.Errorf("expected 2 arguments, got %d", len(is))
+		}
+		return Bool(op(is[0], is[1])), nil
+	}
+}
+
+func equal(args []Value) (Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("expected at least 2 arguments, got %d", len(args))
+	}
+
+	for _, arg := range args[1:] {
+		if arg.String() != args[0].String() {
+			return Bool(false), nil
+		}
+	}
+	return Bool(true), nil
+}
+
+func notEqual(args []Value) (Value, error) {
+	eq, err := equal(args)
+	if err != nil {
+		return nil, err
+	}
+	return Bool(!bool(eq.(Bool))), nil
+}
+
+func not(args []Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected 1 argument, got %d", len(args))
+	}
+
+	b, _ := Truthy(args[0])
+	return Bool(!b), nil
+}
+
+func asMap(v Value) (*Map, error) {
+	m, ok := v.(*Map)
+	if !ok {
+		return nil, fmt.Errorf("expected a map, got %s", v)
+	}
+	return m, nil
+}
+
+func mapGet(args []Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+	}
+
+	m, err := asMap(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if val, ok := m.Get(args[1]); ok {
+		return val, nil
+	}
+	return Nil, nil
+}
+
+func mapContains(args []Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+	}
+
+	m, err := asMap(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	_, ok := m.Get(args[1])
+	return Bool(ok), nil
+}
+
+func mapPut(args []Value) (Value, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("expected 3 arguments, got %d", len(args))
+	}
+
+	m, err := asMap(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return m.Put(args[1], args[2]), nil
+}
+
+func mapRemove(args []Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+	}
+
+	m, err := asMap(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return m.Remove(args[1]), nil
+}
+
+func list(args []Value) (Value, error) {
+	return List(append([]Value{}, args...)), nil
+}
+
+func length(args []Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected 1 argument, got %d", len(args))
+	}
+
+	switch v := args[0].(type) {
+	case List:
+		return Int(len(v)), nil
+	case *Map:
+		return Int(v.Len()), nil
+	default:
+		return nil, fmt.Errorf("expected a list or a map, got %s", v)
+	}
+}
+
+func empty(args []Value) (Value, error) {
+	n, err := length(args)
+	if err != nil {
+		return nil, err
+	}
+	return Bool(n == Int(0)), nil
+}
+
+// count returns the number of arguments that are true, e.g. the number of workers that own a resource.
+func count(args []Value) (Value, error) {
+	n := 0
+	for _, arg := range args {
+		if b, _ := Truthy(arg); b {
+			n++
+		}
+	}
+	return Int(n), nil
+}
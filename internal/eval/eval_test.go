@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEval(t *testing.T) {
+	scope := Vars{
+		"x":       Int(3),
+		"unknown": Unknown,
+		"m":       EmptyMap.Put(Int(1), Ident("(Worker 1)")),
+		"Worker": Func(func(args []Value) (Value, error) {
+			return Ident(fmt.Sprintf("(Worker %s)", args[0])), nil
+		}),
+	}
+
+	var tests = []struct {
+		str    string
+		expVal Value
+		expErr string
+	}{
+		{str: "42", expVal: Int(42)},
+		{str: "x", expVal: Int(3)},
+		{str: "true", expVal: Bool(true)},
+		{str: "(+ x 1 2)", expVal: Int(6)},
+		{str: "(- x 1)", expVal: Int(2)},
+		{str: "(mod 7 x)", expVal: Int(1)},
+		{str: "(/ 1 0)", expErr: "division by zero"},
+		{str: "(< x 4)", expVal: Bool(true)},
+		{str: "(= x 3 3)", expVal: Bool(true)},
+		{str: "(!= x 3)", expVal: Bool(false)},
+		{str: "(not (= x 3))", expVal: Bool(false)},
+		{str: "(and (= x 3) (< x 2))", expVal: Bool(false)},
+		{str: "(or (= x 3) unknown)", expVal: Bool(true)},
+		{str: "(and (= x 3) unknown)", expVal: Unknown},
+		{str: "(and false unknown)", expVal: Bool(false)},
+		{str: "(+ x unknown)", expVal: Unknown},
+		{str: "(if (< x 4) 1 2)", expVal: Int(1)},
+		{str: "(if unknown 1 2)", expVal: Unknown},
+		{str: "{}", expVal: EmptyMap},
+		{str: "(map-get {1 x 2 4} 1)", expVal: Int(3)},
+		{str: "(map-get m 1)", expVal: Ident("(Worker 1)")},
+		{str: "(map-get m 2)", expVal: Nil},
+		{str: "(map-contains? m 1)", expVal: Bool(true)},
+		{str: "(len (map-put m 2 x))", expVal: Int(2)},
+		{str: "(len (map-remove m 1))", expVal: Int(0)},
+		{str: "(len (list 1 2 3))", expVal: Int(3)},
+		{str: "(empty? (list))", expVal: Bool(true)},
+		{str: "(empty? m)", expVal: Bool(false)},
+		{str: "(empty (list 1))", expVal: Bool(false)},
+		{str: "(count true nil (< x 4))", expVal: Int(2)},
+		{str: "(count true unknown)", expVal: Unknown},
+		{str: ":done", expVal: Keyword(":done")},
+		{str: "(= (Worker 1) (map-get m 1))", expVal: Bool(true)},
+		{str: "y", expErr: "could not resolve y"},
+		{str: "(frobnicate x)", expErr: "unknown function frobnicate"},
+		{str: "(x 1)", expErr: "x is not a function"},
+		{str: "(+ m 1)", expErr: "expected an integer"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Eval(%s)", test.str), func(t *testing.T) {
+			expr, err := lisp.ParseExpression(test.str)
+			assert.Nil(t, err)
+
+			val, err := Eval(expr, scope)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, test.expVal, val)
+			}
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	m := EmptyMap.Put(Int(2), Int(20)).Put(Int(1), Int(10)).Put(Int(2), Int(21))
+	assert.Equal(t, "{1 10, 2 21}", m.String())
+	assert.Equal(t, "{}", EmptyMap.String())
+	assert.Equal(t, "{2 21}", m.Remove(Int(1)).String())
+	assert.Equal(t, "{1 10, 2 21}", m.Remove(Int(3)).String())
+}
@@ -0,0 +1,2 @@
+// package eval evaluates the expressions of the DSL
+package eval
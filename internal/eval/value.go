@@ -0,0 +1,163 @@
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Value is the result of evaluating an expression. Values are immutable, and two values are equal if and only if their
+// string representations are equal.
+type Value interface {
+	String() string
+}
+
+// Int is an integer.
+type Int int64
+
+func (i Int) String() string {
+	return fmt.Sprintf("%d", int64(i))
+}
+
+// Bool is a boolean.
+type Bool bool
+
+func (b Bool) String() string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// Ident is the identity of a process instance, e.g. (Worker 1).
+type Ident string
+
+func (i Ident) String() string {
+	return string(i)
+}
+
+// Environment is the identity of the environment of the system. It is the sender of every message that does not
+// originate from an instance.
+const Environment = Ident("env")
+
+// Keyword is a keyword, e.g. the name of a state such as :done.
+type Keyword string
+
+func (k Keyword) String() string {
+	return string(k)
+}
+
+// Nil is the value of variables that have not been assigned yet.
+var Nil Value = nilValue{}
+
+type nilValue struct{}
+
+func (nilValue) String() string {
+	return "nil"
+}
+
+// Unknown is a value that can't be known, e.g. a field of a message that was sent by the environment. Every operation
+// on an unknown value results in an unknown value, and an unknown guard may be either true or false.
+var Unknown Value = unknownValue{}
+
+type unknownValue struct{}
+
+func (unknownValue) String() string {
+	return "?"
+}
+
+// Func is a function that can be called from expressions, e.g. the constructor of the identity of a process instance.
+type Func func(args []Value) (Value, error)
+
+func (Func) String() string {
+	return "<fn>"
+}
+
+// Map maps values onto values. Its entries are kept sorted by key, so that equal maps have equal representations.
+type Map struct {
+	entries []entry
+}
+
+type entry struct {
+	key, val Value
+}
+
+// EmptyMap is the map without any entries.
+var EmptyMap = &Map{}
+
+func (m *Map) String() string {
+	parts := []string{}
+	for _, e := range m.entries {
+		parts = append(parts, e.key.String()+" "+e.val.String())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (m *Map) find(key Value) (int, bool) {
+	k := key.String()
+	idx := sort.Search(len(m.entries), func(i int) bool {
+		return k <= m.entries[i].key.String()
+	})
+	return idx, idx < len(m.entries) && m.entries[idx].key.String() == k
+}
+
+// Get returns the value of the key, and whether the map contains it.
+func (m *Map) Get(key Value) (Value, bool) {
+	if idx, ok := m.find(key); ok {
+		return m.entries[idx].val, true
+	}
+	return nil, false
+}
+
+// Put returns a copy of the map in which the key is mapped onto the value.
+func (m *Map) Put(key, val Value) *Map {
+	idx, ok := m.find(key)
+	entries := append([]entry{}, m.entries[:idx]...)
+	entries = append(entries, entry{key, val})
+	if ok {
+		idx++
+	}
+	entries = append(entries, m.entries[idx:]...)
+	return &Map{entries}
+}
+
+// Remove returns a copy of the map without the key.
+func (m *Map) Remove(key Value) *Map {
+	idx, ok := m.find(key)
+	if !ok {
+		return m
+	}
+	entries := append([]entry{}, m.entries[:idx]...)
+	entries = append(entries, m.entries[idx+1:]...)
+	return &Map{entries}
+}
+
+// Len returns the number of entries.
+func (m *Map) Len() int {
+	return len(m.entries)
+}
+
+// List is a sequence of values.
+type List []Value
+
+func (l List) String() string {
+	parts := []string{}
+	for _, v := range l {
+		parts = append(parts, v.String())
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// Truthy returns whether a value counts as true. The second result is false if that can't be known.
+func Truthy(v Value) (bool, bool) {
+	switch v := v.(type) {
+	case Bool:
+		return bool(v), true
+	case unknownValue:
+		return false, false
+	case nilValue:
+		return false, true
+	default:
+		return true, true
+	}
+}
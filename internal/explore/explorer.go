@@ -0,0 +1,195 @@
+package explore
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"iter"
+	"time"
+
+	"dberk.nl/graphchecker/internal/compose"
+)
+
+// Strategy is the order in which states are explored.
+type Strategy int
+
+const (
+	// BFS explores states in order of their distance to the initial state, so that the trace to every state is a
+	// shortest one.
+	BFS Strategy = iota
+	// DFS explores the successors of a state before its siblings.
+	DFS
+)
+
+func (s Strategy) String() string {
+	if s == DFS {
+		return "dfs"
+	}
+	return "bfs"
+}
+
+// ParseStrategy parses the name of a strategy, bfs or dfs.
+func ParseStrategy(name string) (Strategy, error) {
+	switch name {
+	case "bfs":
+		return BFS, nil
+	case "dfs":
+		return DFS, nil
+	default:
+		return BFS, fmt.Errorf("unknown strategy %s, expected bfs or dfs", name)
+	}
+}
+
+// Limits bound the exploration, a zero value means unbounded.
+type Limits struct {
+	MaxStates int
+	MaxDepth  int
+	Timeout   time.Duration
+}
+
+// Node is an explored state of the system.
+type Node struct {
+	// ID numbers the nodes in the order in which they were discovered, the initial state is 0
+	ID    int
+	State *compose.State
+	// Depth is the number of steps from the initial state to the node along its trace
+	Depth int
+	// Parent is the node from which this node was discovered, and Step the step that was taken to get here. Both are nil
+	// for the initial state.
+	Parent *Node
+	Step   *compose.Step
+	// Steps contains the steps that the system can take from this node, including those to states that were not
+	// explored because of the limits
+	Steps []*compose.Step
+}
+
+// Trace returns the steps from the initial state to the node.
+func (n *Node) Trace() []*compose.Step {
+	steps := []*compose.Step{}
+	for cur := n; cur.Parent != nil; cur = cur.Parent {
+		steps = append(steps, cur.Step)
+	}
+
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+	return steps
+}
+
+// Stats summarizes an exploration.
+type Stats struct {
+	States      int
+	Transitions int
+	MaxDepth    int
+	Duration    time.Duration
+	// Stopped is the reason why the exploration did not visit every reachable state, empty if it did
+	Stopped string
+}
+
+// Complete returns whether every reachable state was explored.
+func (s *Stats) Complete() bool {
+	return s.Stopped == ""
+}
+
+// Explorer enumerates the reachable states of a system, every state is visited once.
+type Explorer struct {
+	sys      *compose.System
+	strategy Strategy
+	limits   Limits
+	stats    Stats
+}
+
+// New returns an explorer of the system.
+func New(sys *compose.System, strategy Strategy, limits Limits) *Explorer {
+	return &Explorer{sys: sys, strategy: strategy, limits: limits}
+}
+
+// Stats returns the statistics of the last exploration.
+func (e *Explorer) Stats() *Stats {
+	return &e.stats
+}
+
+// hash returns the canonical hash of a state, states with equal hashes are considered equal.
+func hash(s *compose.State) [sha256.Size]byte {
+	return sha256.Sum256([]byte(s.Key()))
+}
+
+// Nodes explores the system and yields every reachable node once, after its successors are computed. The exploration
+// stops when the loop body breaks, a limit is reached, or computing the successors of a state fails, in which case
+// the error is yielded.
+func (e *Explorer) Nodes() iter.Seq2[*Node, error] {
+	return func(yield func(*Node, error) bool) {
+		start := time.Now()
+		e.stats = Stats{}
+		defer func() {
+			e.stats.Duration = time.Since(start)
+		}()
+
+		init := &Node{State: e.sys.Initial()}
+		visited := map[[sha256.Size]byte]bool{hash(init.State): true}
+		pending := []*Node{init}
+		e.stats.States = 1
+
+		for len(pending) != 0 {
+			if e.limits.Timeout != 0 && e.limits.Timeout <= time.Since(start) {
+				e.stats.Stopped = fmt.Sprintf("timeout of %s", e.limits.Timeout)
+				return
+			}
+
+			var n *Node
+			if e.strategy == DFS {
+				n, pending = pending[len(pending)-1], pending[:len(pending)-1]
+			} else {
+				n, pending = pending[0], pending[1:]
+			}
+
+			e.stats.MaxDepth = max(e.stats.MaxDepth, n.Depth)
+			steps, err := e.sys.Successors(n.State)
+			if err != nil {
+				yield(nil, fmt.Errorf("%s: %w", e.sys.Describe(n.State), err))
+				return
+			}
+			n.Steps = steps
+			e.stats.Transitions += len(steps)
+
+			for _, step := range steps {
+				h := hash(step.Target)
+				if visited[h] {
+					continue
+				}
+
+				if e.limits.MaxDepth != 0 && e.limits.MaxDepth <= n.Depth {
+					e.stats.Stopped = fmt.Sprintf("maximum depth of %d", e.limits.MaxDepth)
+					continue
+				}
+				if e.limits.MaxStates != 0 && e.limits.MaxStates <= e.stats.States {
+					e.stats.Stopped = fmt.Sprintf("maximum of %d states", e.limits.MaxStates)
+					continue
+				}
+
+				visited[h] = true
+				pending = append(pending, &Node{
+					ID:     e.stats.States,
+					State:  step.Target,
+					Depth:  n.Depth + 1,
+					Parent: n,
+					Step:   step,
+				})
+				e.stats.States++
+			}
+
+			if !yield(n, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Run explores the whole system, up to the limits.
+func (e *Explorer) Run() (*Stats, error) {
+	for _, err := range e.Nodes() {
+		if err != nil {
+			return e.Stats(), err
+		}
+	}
+	return e.Stats(), nil
+}
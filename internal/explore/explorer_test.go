@@ -0,0 +1,138 @@
+package explore
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+const pingPong = `
+	(defprocess Client
+	  (loop
+	    (!send :message ping :to Server)
+	    (?receive :message pong)))
+	(defprocess Server
+	  (loop
+	    (?receive :message ping :from client)
+	    (!send :message pong :to client)))`
+
+const counter = `
+	(defprocess Counter
+	  (let ((n 0))
+	    (loop
+	      (if (< n 3)
+	          (set! n (+ n 1))
+	          (set! n 0)))))`
+
+func newSystem(t *testing.T, str string) *compose.System {
+	m, err := lisp.LoadString(str)
+	assert.Nil(t, err)
+
+	sys, err := compose.New(m, compose.Options{})
+	assert.Nil(t, err)
+	return sys
+}
+
+func TestRun(t *testing.T) {
+	var tests = []struct {
+		name       string
+		str        string
+		strategy   Strategy
+		limits     Limits
+		expStates  int
+		expStopped string
+	}{
+		{
+			name:      "breadth first",
+			str:       pingPong,
+			strategy:  BFS,
+			expStates: 11,
+		},
+		{
+			name:      "depth first",
+			str:       pingPong,
+			strategy:  DFS,
+			expStates: 11,
+		},
+		{
+			name:       "maximum number of states",
+			str:        pingPong,
+			limits:     Limits{MaxStates: 5},
+			expStates:  5,
+			expStopped: "maximum of 5 states",
+		},
+		{
+			name:       "maximum depth",
+			str:        pingPong,
+			limits:     Limits{MaxDepth: 2},
+			expStates:  3,
+			expStopped: "maximum depth of 2",
+		},
+		{
+			name:      "maximum depth that is not reached",
+			str:       pingPong,
+			limits:    Limits{MaxDepth: 100},
+			expStates: 11,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Run - %s", test.name), func(t *testing.T) {
+			e := New(newSystem(t, test.str), test.strategy, test.limits)
+			stats, err := e.Run()
+			assert.Nil(t, err)
+
+			assert.Equal(t, test.expStates, stats.States)
+			assert.Equal(t, test.expStopped, stats.Stopped)
+			assert.Equal(t, test.expStopped == "", stats.Complete())
+		})
+	}
+}
+
+func TestNodes(t *testing.T) {
+	sys := newSystem(t, counter)
+	e := New(sys, BFS, Limits{})
+
+	ids := []int{}
+	values := []string{}
+	for n, err := range e.Nodes() {
+		assert.Nil(t, err)
+		ids = append(ids, n.ID)
+		assert.Equal(t, n.Depth, len(n.Trace()))
+		if n.Parent != nil {
+			assert.Equal(t, n.Step.Target, n.State)
+		}
+		values = append(values, fmt.Sprint(n.State.Vars[0]))
+	}
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, ids)
+	assert.Equal(t, 17, e.Stats().States)
+	assert.Equal(t, 17, e.Stats().Transitions)
+	assert.Contains(t, values, "[3]")
+}
+
+func TestNodesBreak(t *testing.T) {
+	e := New(newSystem(t, pingPong), DFS, Limits{})
+
+	count := 0
+	for range e.Nodes() {
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, count)
+	assert.False(t, e.Stats().States == 0)
+}
+
+func TestParseStrategy(t *testing.T) {
+	s, err := ParseStrategy("dfs")
+	assert.Nil(t, err)
+	assert.Equal(t, DFS, s)
+
+	_, err = ParseStrategy("random")
+	assert.Error(t, err)
+}
@@ -0,0 +1,2 @@
+// package explore enumerates the reachable states of a composed system
+package explore
@@ -0,0 +1,198 @@
+package ioco
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Message is a message that is exchanged with the implementation under test.
+type Message struct {
+	Name   string         `json:"message"`
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// Adapter connects the tester to an implementation under test.
+type Adapter interface {
+	// Input gives a message to the implementation.
+	Input(msg Message) error
+	// Output waits for the next message of the implementation. It returns false if there is none within the timeout,
+	// which the tester observes as quiescence.
+	Output(timeout time.Duration) (Message, bool, error)
+	// Close disconnects from the implementation.
+	Close() error
+}
+
+// frame is a line of the JSON lines protocol. The tester writes {"type": "input", "message": ..., "fields": ...}, the
+// implementation answers with lines of type output whenever it sends a message.
+type frame struct {
+	Type string `json:"type"`
+	Message
+}
+
+// stream is an adapter that speaks the JSON lines protocol over a pair of streams.
+type stream struct {
+	w       io.Writer
+	outputs chan Message
+	errs    chan error
+	close   func() error
+	// done is closed by Close, finished is closed once the reader returns
+	done, finished chan struct{}
+}
+
+// NewStream returns an adapter that writes inputs to w and reads outputs from r, as JSON lines.
+func NewStream(r io.Reader, w io.Writer, close func() error) Adapter {
+	s := newStream(r, w)
+	s.close = close
+	return s
+}
+
+func newStream(r io.Reader, w io.Writer) *stream {
+	s := &stream{
+		w:        w,
+		outputs:  make(chan Message, 64),
+		errs:     make(chan error, 1),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	go s.read(r)
+	return s
+}
+
+// read passes the outputs on to Output. Once the adapter is closed, or the stream fails, it drops them, but keeps
+// reading until the implementation closes the stream.
+func (s *stream) read(r io.Reader) {
+	defer close(s.finished)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		f := frame{}
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			s.fail(fmt.Errorf("could not decode %q: %w", scanner.Text(), err))
+			drain(r)
+			return
+		}
+		if f.Type != "output" {
+			s.fail(fmt.Errorf("expected a frame of type output, got %q", f.Type))
+			drain(r)
+			return
+		}
+
+		select {
+		case s.outputs <- f.Message:
+		case <-s.done:
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	s.fail(fmt.Errorf("the implementation closed the connection: %w", err))
+	drain(r)
+}
+
+// drain discards the rest of the stream, so that an implementation doesn't block on writing outputs that aren't read.
+func drain(r io.Reader) {
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// fail reports the error that ends the stream. The reader fails only once, so the error always fits in errs.
+func (s *stream) fail(err error) {
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+func (s *stream) Input(msg Message) error {
+	line, err := json.Marshal(frame{Type: "input", Message: msg})
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+func (s *stream) Output(timeout time.Duration) (Message, bool, error) {
+	// An output that has already arrived takes precedence over an expired timeout.
+	select {
+	case msg := <-s.outputs:
+		return msg, true, nil
+	default:
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case msg := <-s.outputs:
+		return msg, true, nil
+	case err := <-s.errs:
+		return Message{}, false, err
+	case <-timer.C:
+		return Message{}, false, nil
+	}
+}
+
+func (s *stream) Close() error {
+	close(s.done)
+	return s.close()
+}
+
+// closeTimeout is how long Close waits for an implementation to exit after closing its stdin, before killing it.
+var closeTimeout = 5 * time.Second
+
+// Start starts the implementation as a child process that speaks the JSON lines protocol over its stdin and stdout.
+// Its stderr is passed through.
+func Start(name string, args ...string) (Adapter, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	s := newStream(stdout, stdin)
+	s.close = func() error {
+		stdin.Close()
+		// Wait closes stdout, so it has to wait for the reader to see the implementation close it.
+		select {
+		case <-s.finished:
+		case <-time.After(closeTimeout):
+			cmd.Process.Kill()
+			if err := cmd.Wait(); err != nil {
+				return fmt.Errorf("the implementation didn't exit within %s after its stdin was closed: %w", closeTimeout, err)
+			}
+			return nil
+		}
+		return cmd.Wait()
+	}
+	return s, nil
+}
+
+// Dial connects to an implementation that speaks the JSON lines protocol on a local TCP socket.
+func Dial(addr string) (Adapter, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(conn, conn, conn.Close), nil
+}
@@ -0,0 +1,272 @@
+package ioco
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/eval"
+	"dberk.nl/graphchecker/internal/lts"
+)
+
+// Event types
+const (
+	// EventReceive is the receipt of a message by the instance, an input
+	EventReceive = "receive"
+	// EventSend is the sending of a message by the instance, an output
+	EventSend = "send"
+	// EventQuiescent records that the instance was quiescent, e.g. because a request timed out
+	EventQuiescent = "quiescent"
+)
+
+// Event is a message that an instance sent or received, as recorded in a log.
+type Event struct {
+	Time time.Time `json:"time"`
+	// Instance names the instance that sent or received the message. Events of other instances than the one that is
+	// checked are skipped, events without an instance always apply.
+	Instance string `json:"instance,omitempty"`
+	Type     string `json:"type"`
+	Message
+	// Source describes where the event was read from, e.g. the line of the log
+	Source string `json:"-"`
+}
+
+func (e Event) String() string {
+	s := e.Type
+	if e.Name != "" {
+		s += " " + e.Name
+	}
+	if len(e.Fields) != 0 {
+		fields, _ := json.Marshal(e.Fields)
+		s += " " + string(fields)
+	}
+	if !e.Time.IsZero() {
+		s += " at " + e.Time.Format(time.RFC3339Nano)
+	}
+	if e.Source != "" {
+		s += " (" + e.Source + ")"
+	}
+	return s
+}
+
+// Label returns the action of the instance that the event records.
+func (e Event) Label() (lts.Label, error) {
+	switch e.Type {
+	case EventReceive:
+		return lts.Label{Kind: lts.Input, Name: e.Name}, nil
+	case EventSend:
+		return lts.Label{Kind: lts.Output, Name: e.Name}, nil
+	case EventQuiescent:
+		return lts.DeltaLabel, nil
+	default:
+		return lts.Label{}, fmt.Errorf("unknown event type %q", e.Type)
+	}
+}
+
+// ReadEvents reads a log of events, one JSON object per line. The events must be logged in the order in which they
+// happened, it fails if the time of an event is before that of an earlier event.
+func ReadEvents(r io.Reader, name string) ([]Event, error) {
+	events := []Event{}
+	var last time.Time
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		e := Event{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		if _, err := e.Label(); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		if !e.Time.IsZero() {
+			if e.Time.Before(last) {
+				return nil, fmt.Errorf("%s:%d: the event at %s happened before the previous event at %s", name, line, e.Time.Format(time.RFC3339Nano), last.Format(time.RFC3339Nano))
+			}
+			last = e.Time
+		}
+
+		e.Source = fmt.Sprintf("%s:%d", name, line)
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// Divergence is the first event of a trace that the specification doesn't accept.
+type Divergence struct {
+	Event Event
+	// Label is the action that the specification doesn't accept, δ if the instance was quiescent for too long before
+	// the event
+	Label lts.Label
+	// Trace contains the accepted actions before the divergence
+	Trace []lts.Label
+	// Outputs and Inputs are the actions that the specification allows instead
+	Outputs, Inputs []lts.Label
+	// Fields contains the values of the fields that the specification allows for the output, if the output is allowed
+	// but its fields don't match
+	Fields []map[string]eval.Value
+}
+
+func (d *Divergence) String() string {
+	var b strings.Builder
+	switch {
+	case d.Label.Kind == lts.Delta && d.Event.Type != EventQuiescent:
+		fmt.Fprintf(&b, "the trace diverges from the specification before %s: the instance was quiescent\n", d.Event)
+	case d.Label.Kind == lts.Input:
+		fmt.Fprintf(&b, "the trace diverges from the specification at %s: the input is not specified\n", d.Event)
+	case len(d.Fields) != 0:
+		fmt.Fprintf(&b, "the trace diverges from the specification at %s: the fields don't match\n", d.Event)
+	default:
+		fmt.Fprintf(&b, "the trace diverges from the specification at %s\n", d.Event)
+	}
+
+	fmt.Fprintf(&b, "after %d accepted action(s)", len(d.Trace))
+	if len(d.Trace) != 0 {
+		fmt.Fprintf(&b, ": %s", labels(d.Trace))
+	}
+	fmt.Fprintf(&b, "\nallowed outputs: %s\n", labels(d.Outputs))
+	fmt.Fprintf(&b, "allowed inputs: %s\n", labels(d.Inputs))
+	if len(d.Fields) != 0 {
+		msgs := []string{}
+		for _, fields := range d.Fields {
+			msgs = append(msgs, compose.Message{Name: d.Label.Name, Fields: fields}.String())
+		}
+		fmt.Fprintf(&b, "allowed fields: %s\n", strings.Join(msgs, ", "))
+	}
+	return b.String()
+}
+
+// Conform checks whether the specification accepts the events of its instance as a suspension trace. It returns the
+// accepted actions, and the first event at which the trace diverges. If quiescence is positive, then a gap of at least
+// that long between two events is observed as δ, so the events must be in the order in which they happened.
+//
+// The fields of an output must have the values that the specification sends, except for the fields whose value
+// depends on the environment. Fields that the specification doesn't set are not checked.
+func Conform(spec *Spec, events []Event, quiescence time.Duration) ([]lts.Label, *Divergence) {
+	trace := []lts.Label{}
+	s := spec.Initial
+	var last time.Time
+	for _, e := range events {
+		if e.Instance != "" && e.Instance != spec.Name {
+			continue
+		}
+
+		labels := []lts.Label{}
+		if quiescence > 0 && !last.IsZero() && !e.Time.IsZero() && e.Time.Sub(last) >= quiescence && e.Type != EventQuiescent {
+			labels = append(labels, lts.DeltaLabel)
+		}
+		label, _ := e.Label()
+		labels = append(labels, label)
+		if !e.Time.IsZero() {
+			last = e.Time
+		}
+
+		for _, label := range labels {
+			next, ok := spec.Step(s, label)
+			if !ok {
+				return trace, &Divergence{Event: e, Label: label, Trace: trace, Outputs: spec.Out(s), Inputs: spec.In(s)}
+			}
+			if allowed := spec.Fields(s, label); label.Kind == lts.Output && len(allowed) != 0 && !anyFields(allowed, e.Fields) {
+				return trace, &Divergence{Event: e, Label: label, Trace: trace, Outputs: spec.Out(s), Inputs: spec.In(s), Fields: allowed}
+			}
+			trace = append(trace, label)
+			s = next
+		}
+	}
+	return trace, nil
+}
+
+// anyFields returns whether the logged fields match one of the valuations.
+func anyFields(valuations []map[string]eval.Value, fields map[string]any) bool {
+	for _, valuation := range valuations {
+		matches := true
+		for name, val := range valuation {
+			logged, ok := fields[name]
+			if val != eval.Unknown && (!ok || !matchValue(val, logged)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// matchValue returns whether a value of the specification equals a value that was decoded from JSON. Identities and
+// keywords are logged as strings, e.g. "(Worker 1)" and ":done" or "done".
+func matchValue(val eval.Value, logged any) bool {
+	switch logged := logged.(type) {
+	case nil:
+		return val == eval.Nil
+	case bool:
+		b, ok := val.(eval.Bool)
+		return ok && bool(b) == logged
+	case float64, int, int64, json.Number:
+		i, ok := val.(eval.Int)
+		return ok && sameNumber(i, logged)
+	case string:
+		switch val := val.(type) {
+		case eval.Ident:
+			return string(val) == logged
+		case eval.Keyword:
+			return string(val) == logged || strings.TrimPrefix(string(val), ":") == logged
+		}
+		return false
+	case []any:
+		l, ok := val.(eval.List)
+		if !ok || len(l) != len(logged) {
+			return false
+		}
+		for idx := range l {
+			if l[idx] != eval.Unknown && !matchValue(l[idx], logged[idx]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		m, ok := val.(*eval.Map)
+		if !ok || m.Len() != len(logged) {
+			return false
+		}
+		for key, v := range logged {
+			// Maps compare keys by their rendering, so a key that was logged as a string finds e.g. an integer key.
+			mv, ok := m.Get(eval.Ident(key))
+			if !ok || (mv != eval.Unknown && !matchValue(mv, v)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// sameNumber returns whether a logged number equals an integer. JSON logs decode numbers as float64, the telemetry
+// importers keep integer attributes as int64.
+func sameNumber(i eval.Int, logged any) bool {
+	switch logged := logged.(type) {
+	case float64:
+		return float64(i) == logged
+	case int:
+		return int64(i) == int64(logged)
+	case int64:
+		return int64(i) == logged
+	case json.Number:
+		if n, err := logged.Int64(); err == nil {
+			return int64(i) == n
+		}
+		f, err := logged.Float64()
+		return err == nil && float64(i) == f
+	default:
+		return false
+	}
+}
@@ -0,0 +1,172 @@
+package ioco
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConform(t *testing.T) {
+	var tests = []struct {
+		name          string
+		log           string
+		quiescence    time.Duration
+		expDivergence string
+	}{
+		{
+			name: "accepted",
+			log: `{"type":"receive","message":"get","fields":{"key":1}}
+			      {"type":"send","message":"missing"}
+			      {"type":"quiescent"}`,
+		},
+		{
+			name: "other instances are skipped",
+			log: `{"type":"receive","message":"get"}
+			      {"instance":"Client","type":"receive","message":"missing"}
+			      {"instance":"Server","type":"send","message":"value"}`,
+		},
+		{
+			name: "unexpected output",
+			log: `{"type":"receive","message":"get"}
+			      {"type":"send","message":"value"}
+			      {"type":"send","message":"value","fields":{"value":7}}`,
+			expDivergence: `the trace diverges from the specification at send value {"value":7} (log:3)
+after 2 accepted action(s): ?get, !value
+allowed outputs: δ
+allowed inputs: ?get
+`,
+		},
+		{
+			name: "unspecified input",
+			log:  `{"type":"receive","message":"put"}`,
+			expDivergence: `the trace diverges from the specification at receive put (log:1): the input is not specified
+after 0 accepted action(s)
+allowed outputs: δ
+allowed inputs: ?get
+`,
+		},
+		{
+			name: "quiescence",
+			log: `{"time":"2026-10-19T10:00:00Z","type":"receive","message":"get"}
+			      {"time":"2026-10-19T10:00:03Z","type":"send","message":"value"}`,
+			quiescence: 2 * time.Second,
+			expDivergence: `the trace diverges from the specification before send value at 2026-10-19T10:00:03Z (log:2): the instance was quiescent
+after 1 accepted action(s): ?get
+allowed outputs: !missing, !value
+allowed inputs: nothing
+`,
+		},
+		{
+			name:       "quick response",
+			quiescence: 2 * time.Second,
+			log: `{"time":"2026-10-19T10:00:00Z","type":"receive","message":"get"}
+			      {"time":"2026-10-19T10:00:01Z","type":"send","message":"value"}`,
+		},
+	}
+
+	m, err := lisp.LoadString(server)
+	assert.Nil(t, err)
+
+	spec, err := NewSpec(m, "Server", 100)
+	assert.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Conform - %s", test.name), func(t *testing.T) {
+			events, err := ReadEvents(strings.NewReader(test.log), "log")
+			assert.Nil(t, err)
+
+			_, d := Conform(spec, events, test.quiescence)
+			if test.expDivergence == "" {
+				assert.Nil(t, d)
+				return
+			}
+
+			assert.NotNil(t, d)
+			assert.Equal(t, test.expDivergence, d.String())
+		})
+	}
+}
+
+func TestConformFields(t *testing.T) {
+	var tests = []struct {
+		name          string
+		log           string
+		expDivergence string
+	}{
+		{
+			name: "accepted",
+			log: `{"type":"receive","message":"get","fields":{"key":7}}
+			      {"type":"send","message":"value","fields":{"key":7,"count":0,"status":"ok","extra":true}}
+			      {"type":"receive","message":"get","fields":{"key":8}}
+			      {"type":"send","message":"value","fields":{"key":3,"count":1,"status":":ok"}}`,
+		},
+		{
+			name: "wrong value",
+			log: `{"type":"receive","message":"get","fields":{"key":7}}
+			      {"type":"send","message":"value","fields":{"key":7,"count":5,"status":"ok"}}`,
+			expDivergence: `the trace diverges from the specification at send value {"count":5,"key":7,"status":"ok"} (log:2): the fields don't match
+after 1 accepted action(s): ?get
+allowed outputs: !value
+allowed inputs: nothing
+allowed fields: (value :count 0 :key ? :status :ok)
+`,
+		},
+		{
+			name: "missing field",
+			log: `{"type":"receive","message":"get","fields":{"key":7}}
+			      {"type":"send","message":"value","fields":{"key":7,"status":"ok"}}`,
+			expDivergence: `the trace diverges from the specification at send value {"key":7,"status":"ok"} (log:2): the fields don't match
+after 1 accepted action(s): ?get
+allowed outputs: !value
+allowed inputs: nothing
+allowed fields: (value :count 0 :key ? :status :ok)
+`,
+		},
+	}
+
+	m, err := lisp.LoadString(`
+		(defprocess Server
+		  (let ((n 0))
+		    (loop
+		      (let (({key} (?receive :message get)))
+		        (!send :message value :key key :count n :status :ok)
+		        (set! n (- 1 n))))))`)
+	assert.Nil(t, err)
+
+	spec, err := NewSpec(m, "Server", 100)
+	assert.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Conform fields - %s", test.name), func(t *testing.T) {
+			events, err := ReadEvents(strings.NewReader(test.log), "log")
+			assert.Nil(t, err)
+
+			_, d := Conform(spec, events, 0)
+			if test.expDivergence == "" {
+				assert.Nil(t, d)
+				return
+			}
+
+			assert.NotNil(t, d)
+			assert.Equal(t, test.expDivergence, d.String())
+		})
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	_, err := ReadEvents(strings.NewReader(`{"type":"receive","message":"get"}`+"\n"+`{"type":"call"}`), "log")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `log:2: unknown event type "call"`)
+
+	_, err = ReadEvents(strings.NewReader(`{"type":`), "log")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "log:1:")
+
+	_, err = ReadEvents(strings.NewReader(`{"time":"2026-10-19T10:00:03Z","type":"receive","message":"get"}`+"\n"+`{"time":"2026-10-19T10:00:01Z","type":"send","message":"value"}`), "log")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "log:2: the event at 2026-10-19T10:00:01Z happened before the previous event at 2026-10-19T10:00:03Z")
+}
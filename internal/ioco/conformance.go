@@ -0,0 +1,105 @@
+package ioco
+
+import (
+	"fmt"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/lts"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Suspend isolates an instance of the model and returns its suspension automaton. It fails if the instance or the
+// automaton has more than max states.
+func Suspend(m *model.Model, name string, max int) (*lts.Suspension, error) {
+	spec, err := NewSpec(m, name, max)
+	if err != nil {
+		return nil, err
+	}
+	return spec.Suspension, nil
+}
+
+// Failure is a suspension trace of the specification after which the implementation may produce an output, or be
+// quiescent, while the specification doesn't allow it.
+type Failure struct {
+	Trace   []lts.Label
+	Output  lts.Label
+	Allowed []lts.Label
+}
+
+func (f *Failure) String() string {
+	var b strings.Builder
+	b.WriteString("after the suspension trace\n")
+	if len(f.Trace) == 0 {
+		b.WriteString("  (empty)\n")
+	}
+	for idx, label := range f.Trace {
+		fmt.Fprintf(&b, "  %d. %s\n", idx+1, label)
+	}
+	fmt.Fprintf(&b, "the implementation may produce %s, the specification only allows %s\n", f.Output, labels(f.Allowed))
+	return b.String()
+}
+
+func labels(ls []lts.Label) string {
+	if len(ls) == 0 {
+		return "nothing"
+	}
+
+	strs := []string{}
+	for _, l := range ls {
+		strs = append(strs, l.String())
+	}
+	return strings.Join(strs, ", ")
+}
+
+// Check decides whether impl ioco spec: after every suspension trace of the specification, the outputs of the
+// implementation, including quiescence, are allowed by the specification. It returns a shortest trace that shows
+// otherwise, or nil. Inputs that the implementation does not accept are skipped, ioco assumes that an implementation
+// accepts every input.
+func Check(impl, spec *lts.Suspension) *Failure {
+	start := &visit{pair: pair{spec.Initial, impl.Initial}}
+	seen := map[pair]bool{start.pair: true}
+	queue := []*visit{start}
+	for len(queue) != 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		allowed := spec.Out(v.spec)
+		for _, out := range impl.Out(v.impl) {
+			if _, ok := spec.Step(v.spec, out); !ok {
+				return &Failure{Trace: trace(v), Output: out, Allowed: allowed}
+			}
+		}
+
+		for _, t := range spec.Transitions[v.spec] {
+			to, ok := impl.Step(v.impl, t.Label)
+			if !ok {
+				continue
+			}
+
+			next := &visit{prev: v, label: t.Label, pair: pair{t.To, to}}
+			if !seen[next.pair] {
+				seen[next.pair] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// pair is a state of the specification and a state of the implementation that are reached by the same trace.
+type pair struct{ spec, impl int }
+
+// visit is a pair that is reached by the label from the previous visit.
+type visit struct {
+	prev  *visit
+	label lts.Label
+	pair
+}
+
+func trace(v *visit) []lts.Label {
+	labels := []lts.Label{}
+	for ; v.prev != nil; v = v.prev {
+		labels = append([]lts.Label{v.label}, labels...)
+	}
+	return labels
+}
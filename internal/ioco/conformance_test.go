@@ -0,0 +1,94 @@
+package ioco
+
+import (
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+const server = `
+	(defprocess Server
+	  (loop
+	    (?receive :message get :from client)
+	    (select
+	      (!send :message value :to client)
+	      (!send :message missing :to client))))
+`
+
+func TestCheck(t *testing.T) {
+	var tests = []struct {
+		name      string
+		impl      string
+		expFailed string
+	}{
+		{
+			name: "identical",
+			impl: server,
+		},
+		{
+			name: "fewer outputs",
+			impl: `(defprocess Server
+			         (loop
+			           (?receive :message get :from client)
+			           (!send :message value :to client)))`,
+		},
+		{
+			name: "additional input",
+			impl: `(defprocess Server
+			         (loop
+			           (?receive :message get :from client)
+			           (!send :message value :to client)
+			           (?receive :message put)))`,
+		},
+		{
+			name: "unexpected output",
+			impl: `(defprocess Server
+			         (loop
+			           (?receive :message get :from client)
+			           (!send :message value :to client)
+			           (!send :message value :to client)))`,
+			expFailed: `after the suspension trace
+  1. ?get
+  2. !value
+the implementation may produce !value, the specification only allows δ
+`,
+		},
+		{
+			name: "unexpected quiescence",
+			impl: `(defprocess Server
+			         (?receive :message get :from client)
+			         (?receive :message get :from client)
+			         (!send :message value :to client))`,
+			expFailed: `after the suspension trace
+  1. ?get
+the implementation may produce δ, the specification only allows !missing, !value
+`,
+		},
+	}
+
+	m, err := lisp.LoadString(server)
+	assert.Nil(t, err)
+
+	spec, err := Suspend(m, "Server", 100)
+	assert.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := lisp.LoadString(test.impl)
+			assert.Nil(t, err)
+
+			impl, err := Suspend(m, "Server", 100)
+			assert.Nil(t, err)
+
+			f := Check(impl, spec)
+			if test.expFailed == "" {
+				assert.Nil(t, f)
+				return
+			}
+
+			assert.NotNil(t, f)
+			assert.Equal(t, test.expFailed, f.String())
+		})
+	}
+}
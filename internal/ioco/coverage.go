@@ -0,0 +1,277 @@
+package ioco
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/lts"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Coverage counts how often test runs took the states and transitions of a process. It is accumulated across runs and
+// persisted as JSON. A step of a nondeterministic specification counts every transition that it may take.
+type Coverage struct {
+	Process string `json:"process"`
+	Runs    int    `json:"runs"`
+	// Transitions describes the transitions of the process, so that coverage of an older version of the specification is
+	// detected. The descriptions leave out the locations of the transitions, which change when the specification moves.
+	Transitions []string `json:"transitions"`
+	// StateCounts and TransitionCounts count the steps into every state and over every transition, by their index in the
+	// process
+	StateCounts      []int `json:"state_counts"`
+	TransitionCounts []int `json:"transition_counts"`
+}
+
+// NewCoverage returns the coverage of the process of the specification without any runs.
+func NewCoverage(p *model.Process) *Coverage {
+	c := &Coverage{
+		Process:          p.Name,
+		Transitions:      []string{},
+		StateCounts:      make([]int, len(p.States)),
+		TransitionCounts: make([]int, len(p.Transitions)),
+	}
+	c.Transitions = append(c.Transitions, fingerprints(p)...)
+	return c
+}
+
+// fingerprints describes the transitions of the process by their actions, constraints, guards and the indices of
+// their states.
+func fingerprints(p *model.Process) []string {
+	states := map[*model.State]int{}
+	for idx, s := range p.States {
+		states[s] = idx
+	}
+
+	fps := []string{}
+	for _, t := range p.Transitions {
+		fp := fmt.Sprintf("%d -> %d tau", states[t.From], states[t.To])
+		switch t.Action {
+		case model.ActionInput:
+			fp = fmt.Sprintf("%d -> %d ?%s", states[t.From], states[t.To], t.Receive)
+		case model.ActionOutput:
+			fp = fmt.Sprintf("%d -> %d !%s", states[t.From], states[t.To], t.Send)
+		}
+		if t.Constraint != nil {
+			fp += fmt.Sprintf(" [%s]", t.Constraint)
+		}
+		if t.Guard != 0 {
+			fp += fmt.Sprintf(" guard %d", t.Guard)
+		}
+		if t.Else {
+			fp += " else"
+		}
+		fps = append(fps, fp)
+	}
+	return fps
+}
+
+// LoadCoverage reads the coverage of the process from a file, or returns coverage without runs if the file doesn't
+// exist. It fails if the file was written for another process, or for another version of it.
+func LoadCoverage(path string, p *model.Process) (*Coverage, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewCoverage(p), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Coverage{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("could not read the coverage in %s: %w", path, err)
+	}
+	if err := c.check(p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// check returns an error if the coverage wasn't recorded for the process.
+func (c *Coverage) check(p *model.Process) error {
+	if c.Process != p.Name {
+		return fmt.Errorf("the coverage was recorded for %s, not %s", c.Process, p.Name)
+	}
+	if len(c.StateCounts) != len(p.States) || len(c.TransitionCounts) != len(p.Transitions) || len(c.Transitions) != len(p.Transitions) {
+		return fmt.Errorf("the coverage was recorded for another version of %s", p.Name)
+	}
+	for idx, fp := range fingerprints(p) {
+		if c.Transitions[idx] != fp {
+			return fmt.Errorf("the coverage was recorded for another version of %s: transition %s changed to %s", p.Name, c.Transitions[idx], fp)
+		}
+	}
+	return nil
+}
+
+// Save writes the coverage to a file.
+func (c *Coverage) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Add records a run that followed the suspension trace through the specification. The trace stops at the first label
+// that the specification doesn't allow, e.g. the output that failed the run.
+func (c *Coverage) Add(spec *Spec, trace []lts.Label) error {
+	if err := c.check(spec.Process); err != nil {
+		return err
+	}
+
+	states := map[*model.State]int{}
+	for idx, s := range spec.Process.States {
+		states[s] = idx
+	}
+	transitions := map[*model.Transition]int{}
+	for idx, t := range spec.Process.Transitions {
+		transitions[t] = idx
+	}
+	take := func(ts []*model.Transition) {
+		for _, t := range ts {
+			c.TransitionCounts[transitions[t]]++
+			c.StateCounts[states[t.To]]++
+		}
+	}
+
+	c.Runs++
+	c.StateCounts[0]++
+	take(spec.initial)
+
+	s := spec.Initial
+	for _, label := range trace {
+		next, ok := spec.Step(s, label)
+		if !ok {
+			break
+		}
+		take(spec.Covers(s, label))
+		s = next
+	}
+	return nil
+}
+
+// Report is the coverage of a process per state, transition and outcome of a guard.
+type Report struct {
+	Process     string               `json:"process"`
+	Runs        int                  `json:"runs"`
+	States      []StateCoverage      `json:"states"`
+	Transitions []TransitionCoverage `json:"transitions"`
+	Guards      []GuardCoverage      `json:"guards"`
+}
+
+// StateCoverage counts the steps into a state, unnamed states are named by their ID, e.g. #3.
+type StateCoverage struct {
+	State string `json:"state"`
+	Count int    `json:"count"`
+}
+
+// TransitionCoverage counts the steps over a transition.
+type TransitionCoverage struct {
+	Transition string `json:"transition"`
+	// Location is the path:line:column of the statement that the transition was created for, if known
+	Location string `json:"location,omitempty"`
+	Count    int    `json:"count"`
+}
+
+// GuardCoverage counts the outcomes of the guard of an if. An if without an else blocks while its guard is false, so it
+// has no false outcome to cover.
+type GuardCoverage struct {
+	Guard    string `json:"guard"`
+	Location string `json:"location,omitempty"`
+	True     int    `json:"true"`
+	// False is nil if the if has no else
+	False *int `json:"false,omitempty"`
+}
+
+// Report returns the coverage of every state, transition and guard outcome of the process.
+func (c *Coverage) Report(p *model.Process) (*Report, error) {
+	if err := c.check(p); err != nil {
+		return nil, err
+	}
+
+	r := &Report{Process: p.Name, Runs: c.Runs, States: []StateCoverage{}, Transitions: []TransitionCoverage{}, Guards: []GuardCoverage{}}
+	for idx, s := range p.States {
+		name := s.Name
+		if !s.Named() {
+			name = fmt.Sprintf("#%d", s.ID)
+		}
+		r.States = append(r.States, StateCoverage{State: name, Count: c.StateCounts[idx]})
+	}
+
+	// The branches of an if share its number, the then branch holds the guard itself.
+	guards := map[int]int{}
+	for idx, t := range p.Transitions {
+		r.Transitions = append(r.Transitions, TransitionCoverage{Transition: t.String(), Location: t.Location, Count: c.TransitionCounts[idx]})
+		if t.Guard != 0 && !t.Else {
+			guards[t.Guard] = len(r.Guards)
+			r.Guards = append(r.Guards, GuardCoverage{Guard: t.Constraint.String(), Location: t.Location, True: c.TransitionCounts[idx]})
+		}
+	}
+	for idx, t := range p.Transitions {
+		if g, ok := guards[t.Guard]; ok && t.Else {
+			count := c.TransitionCounts[idx]
+			r.Guards[g].False = &count
+		}
+	}
+	return r, nil
+}
+
+// Text renders the report for a terminal, listing what the runs didn't cover.
+func (r *Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "coverage of %s after %d run(s)\n", r.Process, r.Runs)
+
+	uncovered := []string{}
+	for _, s := range r.States {
+		if s.Count == 0 {
+			uncovered = append(uncovered, s.State)
+		}
+	}
+	writeSection(&b, "states", len(r.States), uncovered)
+
+	uncovered = []string{}
+	for _, t := range r.Transitions {
+		if t.Count == 0 {
+			uncovered = append(uncovered, t.Transition)
+		}
+	}
+	writeSection(&b, "transitions", len(r.Transitions), uncovered)
+
+	outcomes := 0
+	uncovered = []string{}
+	for _, g := range r.Guards {
+		at := ""
+		if g.Location != "" {
+			at = fmt.Sprintf(" (%s)", g.Location)
+		}
+
+		outcomes++
+		if g.True == 0 {
+			uncovered = append(uncovered, fmt.Sprintf("%s is true%s", g.Guard, at))
+		}
+		if g.False != nil {
+			outcomes++
+			if *g.False == 0 {
+				uncovered = append(uncovered, fmt.Sprintf("%s is false%s", g.Guard, at))
+			}
+		}
+	}
+	writeSection(&b, "guard outcomes", outcomes, uncovered)
+	return b.String()
+}
+
+func writeSection(b *strings.Builder, name string, total int, uncovered []string) {
+	covered := total - len(uncovered)
+	percentage := 100.0
+	if total != 0 {
+		percentage = float64(100*covered) / float64(total)
+	}
+
+	fmt.Fprintf(b, "%s: %d of %d (%.0f%%)\n", name, covered, total, percentage)
+	for _, desc := range uncovered {
+		fmt.Fprintf(b, "  not covered: %s\n", desc)
+	}
+}
@@ -0,0 +1,228 @@
+package ioco
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/lts"
+	"github.com/stretchr/testify/assert"
+)
+
+const counter = `
+	(defprocess Counter
+	  (let ((n 0))
+	    (loop
+	      (?receive :message inc)
+	      (if (< n 1)
+	        (!send :message ok)
+	        (!send :message full))
+	      (set! n 1))))
+`
+
+func TestCoverage(t *testing.T) {
+	inc := lts.Label{Kind: lts.Input, Name: "inc"}
+	ok := lts.Label{Kind: lts.Output, Name: "ok"}
+	full := lts.Label{Kind: lts.Output, Name: "full"}
+
+	var tests = []struct {
+		name    string
+		traces  [][]lts.Label
+		expText string
+	}{
+		{
+			name:   "no runs",
+			traces: [][]lts.Label{},
+			expText: `coverage of Counter after 0 run(s)
+states: 0 of 9 (0%)
+  not covered: :start
+  not covered: #2
+  not covered: #3
+  not covered: #4
+  not covered: #5
+  not covered: #6
+  not covered: #7
+  not covered: #8
+  not covered: #9
+transitions: 0 of 10 (0%)
+  not covered: tau (<string>:3:4)
+  not covered: ?inc (<string>:5:8)
+  not covered: tau [(< n 1)] (<string>:6:8)
+  not covered: !ok (<string>:7:10)
+  not covered: tau [(not (< n 1))] (<string>:6:8)
+  not covered: !full (<string>:8:10)
+  not covered: tau (<string>:6:8)
+  not covered: tau (<string>:6:8)
+  not covered: tau (<string>:9:8)
+  not covered: tau (<string>:4:6)
+guard outcomes: 0 of 2 (0%)
+  not covered: (< n 1) is true (<string>:6:8)
+  not covered: (< n 1) is false (<string>:6:8)
+`,
+		},
+		{
+			name:   "then branch",
+			traces: [][]lts.Label{{inc, ok, lts.DeltaLabel}},
+			expText: `coverage of Counter after 1 run(s)
+states: 7 of 9 (78%)
+  not covered: #6
+  not covered: #7
+transitions: 7 of 10 (70%)
+  not covered: tau [(not (< n 1))] (<string>:6:8)
+  not covered: !full (<string>:8:10)
+  not covered: tau (<string>:6:8)
+guard outcomes: 1 of 2 (50%)
+  not covered: (< n 1) is false (<string>:6:8)
+`,
+		},
+		{
+			name:   "accumulated runs",
+			traces: [][]lts.Label{{inc, ok}, {inc, ok, inc, full}},
+			expText: `coverage of Counter after 2 run(s)
+states: 9 of 9 (100%)
+transitions: 10 of 10 (100%)
+guard outcomes: 2 of 2 (100%)
+`,
+		},
+		{
+			// The guard is evaluated after the input, before the output that fails.
+			name:   "trace stops at a failure",
+			traces: [][]lts.Label{{inc, full, inc, full}},
+			expText: `coverage of Counter after 1 run(s)
+states: 4 of 9 (44%)
+  not covered: #5
+  not covered: #6
+  not covered: #7
+  not covered: #8
+  not covered: #9
+transitions: 3 of 10 (30%)
+  not covered: !ok (<string>:7:10)
+  not covered: tau [(not (< n 1))] (<string>:6:8)
+  not covered: !full (<string>:8:10)
+  not covered: tau (<string>:6:8)
+  not covered: tau (<string>:6:8)
+  not covered: tau (<string>:9:8)
+  not covered: tau (<string>:4:6)
+guard outcomes: 1 of 2 (50%)
+  not covered: (< n 1) is false (<string>:6:8)
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Coverage - %s", test.name), func(t *testing.T) {
+			m, err := lisp.LoadString(counter)
+			assert.Nil(t, err)
+
+			spec, err := NewSpec(m, "Counter", 100)
+			assert.Nil(t, err)
+
+			c := NewCoverage(spec.Process)
+			for _, trace := range test.traces {
+				assert.Nil(t, c.Add(spec, trace))
+			}
+
+			r, err := c.Report(spec.Process)
+			assert.Nil(t, err)
+			assert.Equal(t, test.expText, r.Text())
+		})
+	}
+}
+
+func TestReportGuards(t *testing.T) {
+	// The ifs start in the same state, neither is the else branch of the other.
+	m, err := lisp.LoadString(`
+		(defprocess Router
+		  (let ((n 0))
+		    (loop
+		      (?receive :message req)
+		      (select
+		        (if (< n 1) (!send :message low))
+		        (if (< 0 n) (!send :message high) (!send :message none)))
+		      (set! n 1))))`)
+	assert.Nil(t, err)
+
+	p := m.Processes[0]
+	r, err := NewCoverage(p).Report(p)
+	assert.Nil(t, err)
+
+	zero := 0
+	assert.Equal(t, []GuardCoverage{
+		{Guard: "(< n 1)", Location: "<string>:7:11"},
+		{Guard: "(< 0 n)", Location: "<string>:8:11", False: &zero},
+	}, r.Guards)
+}
+
+func TestLoadCoverage(t *testing.T) {
+	m, err := lisp.LoadString(counter)
+	assert.Nil(t, err)
+
+	spec, err := NewSpec(m, "Counter", 100)
+	assert.Nil(t, err)
+
+	path := filepath.Join(t.TempDir(), "coverage.json")
+	c, err := LoadCoverage(path, spec.Process)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, c.Runs)
+
+	assert.Nil(t, c.Add(spec, []lts.Label{{Kind: lts.Input, Name: "inc"}}))
+	assert.Nil(t, c.Save(path))
+
+	loaded, err := LoadCoverage(path, spec.Process)
+	assert.Nil(t, err)
+	assert.Equal(t, c, loaded)
+
+	// Moving the statements doesn't make it another version.
+	m, err = lisp.LoadString("; the counter\n" + counter)
+	assert.Nil(t, err)
+	_, err = LoadCoverage(path, m.Processes[0])
+	assert.Nil(t, err)
+
+	m, err = lisp.LoadString(server)
+	assert.Nil(t, err)
+	_, err = LoadCoverage(path, m.Processes[0])
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the coverage was recorded for Counter, not Server")
+
+	m, err = lisp.LoadString(`
+		(defprocess Counter
+		  (loop
+		    (?receive :message inc)
+		    (!send :message ok)))`)
+	assert.Nil(t, err)
+	_, err = LoadCoverage(path, m.Processes[0])
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the coverage was recorded for another version of Counter")
+}
+
+func TestWriteHTML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "counter.lisp")
+	assert.Nil(t, os.WriteFile(path, []byte(counter), 0o644))
+
+	m, err := lisp.LoadFile(path)
+	assert.Nil(t, err)
+
+	spec, err := NewSpec(m, "Counter", 100)
+	assert.Nil(t, err)
+
+	c := NewCoverage(spec.Process)
+	assert.Nil(t, c.Add(spec, []lts.Label{{Kind: lts.Input, Name: "inc"}, {Kind: lts.Output, Name: "ok"}}))
+
+	r, err := c.Report(spec.Process)
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, WriteHTML(&b, r, os.ReadFile))
+
+	html := b.String()
+	assert.Contains(t, html, "<h2>"+path+"</h2>")
+	assert.Contains(t, html, `<span class="covered" title="?inc: 1">(?receive :message inc)</span>`)
+	assert.Contains(t, html, `<span class="covered" title="!ok: 1">(!send :message ok)</span>`)
+	assert.Contains(t, html, `<span class="uncovered" title="!full: 0">(!send :message full)</span>`)
+	assert.Contains(t, html, `<span class="partial" title="tau [(&lt; n 1)]: 1`)
+	assert.Contains(t, html, "not covered: !full")
+}
@@ -0,0 +1,190 @@
+package ioco
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/lts"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// GoOptions configure the Go tests that are generated from a test suite.
+type GoOptions struct {
+	// Package is the package of the test file
+	Package string
+	// Prefix names the tests, e.g. TestStore generates TestStore1, TestStore2, ...
+	Prefix string
+	// Adapter is the function that the tests call to connect to the implementation. It has the signature
+	// func(t *testing.T) *iocotest.Adapter, and is written by hand next to the generated file.
+	Adapter string
+	// Source names the specification in the header of the file
+	Source string
+	// Dir is the directory that the citations locate the transitions relative to, so that the generated file doesn't
+	// depend on where the specification was checked out. Locations are cited as they are if it is empty.
+	Dir string
+}
+
+// WriteGo writes the tests as a Go test file that only depends on the iocotest package. Every test cites the
+// transitions of the specification that it covers.
+func WriteGo(w io.Writer, tests []*Test, opts GoOptions) error {
+	var b bytes.Buffer
+	for idx, t := range tests {
+		fmt.Fprintf(&b, "\n// %s%d covers:\n", opts.Prefix, idx+1)
+		for _, c := range citations(t) {
+			fmt.Fprintf(&b, "//   - %s\n", cite(c, opts.Dir))
+		}
+		fmt.Fprintf(&b, "func %s%d(t *testing.T) {\n", opts.Prefix, idx+1)
+		fmt.Fprintf(&b, "a := %s(t)\n", opts.Adapter)
+		writeGoTest(&b, t, opts.Dir)
+		b.WriteString("}\n")
+	}
+
+	// The tests only refer to the iocotest package if they observe quiescence.
+	imports := "import \"testing\"\n"
+	if strings.Contains(b.String(), "iocotest.") {
+		imports = "import (\n\t\"testing\"\n\n\t\"dberk.nl/graphchecker/iocotest\"\n)\n"
+	}
+	header := fmt.Sprintf("// Code generated by graphchecker testgen from %s; DO NOT EDIT.\n\npackage %s\n\n%s", opts.Source, opts.Package, imports)
+
+	src, err := format.Source(append([]byte(header), b.Bytes()...))
+	if err != nil {
+		return fmt.Errorf("could not format the generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// citations returns the transitions that the test covers, in the order in which it first reaches them.
+func citations(t *Test) []*model.Transition {
+	seen := map[*model.Transition]bool{}
+	ts := []*model.Transition{}
+
+	var walk func(t *Test)
+	walk = func(t *Test) {
+		for _, c := range t.Covers {
+			if !seen[c] {
+				seen[c] = true
+				ts = append(ts, c)
+			}
+		}
+		if t.Next != nil {
+			walk(t.Next)
+		}
+		for _, label := range t.Observations() {
+			walk(t.Observe[label])
+		}
+	}
+	walk(t)
+	return ts
+}
+
+// cite describes the transition, with its location relative to dir.
+func cite(t *model.Transition, dir string) string {
+	if dir == "" {
+		return t.String()
+	}
+
+	// A location is path:line:column
+	parts := strings.Split(t.Location, ":")
+	if len(parts) < 3 {
+		return t.String()
+	}
+	path := strings.Join(parts[:len(parts)-2], ":")
+	if !filepath.IsAbs(path) {
+		return t.String()
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return t.String()
+	}
+
+	c := *t
+	c.Location = strings.Join(append([]string{filepath.ToSlash(rel)}, parts[len(parts)-2:]...), ":")
+	return c.String()
+}
+
+// goName returns the string that the adapter uses for an observation.
+func goName(label lts.Label) string {
+	if label.Kind == lts.Delta {
+		return "iocotest.Quiescence"
+	}
+	return strconv.Quote(label.Name)
+}
+
+func writeGoTest(b *bytes.Buffer, t *Test, dir string) {
+	if t.Verdict != "" {
+		return
+	}
+
+	labels := t.Observations()
+	names := []string{}
+	for _, label := range labels {
+		names = append(names, goName(label))
+	}
+
+	call := fmt.Sprintf("a.Observe(%s)", strings.Join(names, ", "))
+	if t.Input != nil {
+		call = fmt.Sprintf("a.Give(%s)", strings.Join(append([]string{strconv.Quote(t.Input.Name)}, names...), ", "))
+	}
+
+	// A single continuation needs no switch.
+	var next *Test
+	switch {
+	case t.Input == nil && len(labels) == 1:
+		next = t.Observe[labels[0]]
+	case t.Input != nil && len(labels) == 0:
+		next = t.Next
+	}
+	if next != nil {
+		writeCovers(b, next, dir)
+		fmt.Fprintf(b, "%s\n", call)
+		writeGoTest(b, next, dir)
+		return
+	}
+
+	fmt.Fprintf(b, "switch %s {\n", call)
+	if t.Input != nil {
+		writeCase(b, "\"\"", t.Next, dir)
+	}
+	for idx, label := range labels {
+		writeCase(b, names[idx], t.Observe[label], dir)
+	}
+	b.WriteString("}\n")
+}
+
+func writeCase(b *bytes.Buffer, name string, t *Test, dir string) {
+	// A case that passes has no statements, so the citations go on the case itself.
+	if t.Verdict != "" {
+		descs := []string{}
+		for _, c := range t.Covers {
+			if c.Action != model.ActionTau {
+				descs = append(descs, cite(c, dir))
+			}
+		}
+		fmt.Fprintf(b, "case %s:", name)
+		if len(descs) != 0 {
+			fmt.Fprintf(b, " // %s", strings.Join(descs, ", "))
+		}
+		b.WriteString("\n")
+		return
+	}
+
+	fmt.Fprintf(b, "case %s:\n", name)
+	writeCovers(b, t, dir)
+	writeGoTest(b, t, dir)
+}
+
+// writeCovers cites the inputs and outputs that the step into the test covers, the internal steps are only listed in
+// the doc comment of the test.
+func writeCovers(b *bytes.Buffer, t *Test, dir string) {
+	for _, c := range t.Covers {
+		if c.Action != model.ActionTau {
+			fmt.Fprintf(b, "// %s\n", cite(c, dir))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package ioco
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteGo(t *testing.T) {
+	m, err := lisp.LoadString(`
+		(defprocess Queue
+		  (?receive :message put)
+		  (select
+		    (!send :message ok)
+		    (!send :message full))
+		  (?receive :message get))`)
+	assert.Nil(t, err)
+
+	spec, err := NewSpec(m, "Queue", 100)
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	err = WriteGo(&b, Generate(spec, GenOptions{Depth: 10}), GoOptions{Package: "queue", Prefix: "TestQueue", Adapter: "newAdapter", Source: "queue.lisp"})
+	assert.Nil(t, err)
+	assert.Equal(t, `// Code generated by graphchecker testgen from queue.lisp; DO NOT EDIT.
+
+package queue
+
+import (
+	"testing"
+
+	"dberk.nl/graphchecker/iocotest"
+)
+
+// TestQueue1 covers:
+//   - ?put (<string>:3:5)
+//   - !full (<string>:6:7)
+//   - tau (<string>:4:5)
+//   - ?get (<string>:7:5)
+//   - !ok (<string>:5:7)
+//   - tau (<string>:4:5)
+func TestQueue1(t *testing.T) {
+	a := newAdapter(t)
+	// ?put (<string>:3:5)
+	a.Give("put")
+	switch a.Observe("full", "ok") {
+	case "full":
+		// !full (<string>:6:7)
+		// ?get (<string>:7:5)
+		a.Give("get")
+		a.Observe(iocotest.Quiescence)
+	case "ok": // !ok (<string>:5:7)
+	}
+}
+`, b.String())
+}
+
+func TestWriteGoLocations(t *testing.T) {
+	// The citations don't depend on where the specification is.
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "echo.lisp"), []byte("(defprocess Echo\n  (?receive :message ping)\n  (!send :message pong))\n"), 0o644))
+
+	m, err := lisp.LoadFile(filepath.Join(dir, "echo.lisp"))
+	assert.Nil(t, err)
+
+	spec, err := NewSpec(m, "Echo", 100)
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	err = WriteGo(&b, Generate(spec, GenOptions{Depth: 10}), GoOptions{Package: "echo", Prefix: "TestEcho", Adapter: "newAdapter", Source: "echo.lisp", Dir: dir})
+	assert.Nil(t, err)
+	assert.NotContains(t, b.String(), dir)
+	assert.Contains(t, b.String(), "//   - ?ping (echo.lisp:2:3)\n")
+	assert.Contains(t, b.String(), "// !pong (echo.lisp:3:3)\n")
+}
@@ -0,0 +1,222 @@
+package ioco
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// region is the extent of the statement at a source location, together with the transitions that were created for it.
+type region struct {
+	start, end  int
+	transitions []TransitionCoverage
+}
+
+// class returns whether the runs covered all, some or none of the transitions of the region.
+func (r *region) class() string {
+	covered := 0
+	for _, t := range r.transitions {
+		if t.Count != 0 {
+			covered++
+		}
+	}
+
+	switch covered {
+	case 0:
+		return "uncovered"
+	case len(r.transitions):
+		return "covered"
+	default:
+		return "partial"
+	}
+}
+
+func (r *region) title() string {
+	descs := []string{}
+	for _, t := range r.transitions {
+		descs = append(descs, fmt.Sprintf("%s: %d", strings.TrimSuffix(t.Transition, fmt.Sprintf(" (%s)", t.Location)), t.Count))
+	}
+	return strings.Join(descs, "\n")
+}
+
+type htmlFile struct {
+	Path   string
+	Source template.HTML
+}
+
+var htmlReport = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>coverage of {{.Report.Process}}</title>
+<style>
+body { font-family: sans-serif; }
+pre { font-family: monospace; line-height: 1.4; }
+.covered { background: #d4f4d4; }
+.partial { background: #f9eeb8; }
+.uncovered { background: #f8c8c8; }
+</style>
+</head>
+<body>
+<h1>coverage of {{.Report.Process}} after {{.Report.Runs}} run(s)</h1>
+<pre>{{.Summary}}</pre>
+{{range .Files}}<h2>{{.Path}}</h2>
+<pre>{{.Source}}</pre>
+{{end}}</body>
+</html>
+`))
+
+// WriteHTML renders the report as a page that shows the sources of the specification, in which the statements whose
+// transitions the runs didn't cover are highlighted. Hovering over a statement shows how often its transitions were
+// taken. Read returns the contents of a source file.
+func WriteHTML(w io.Writer, r *Report, read func(path string) ([]byte, error)) error {
+	regions := map[string]map[string]*region{}
+	paths := []string{}
+	for _, t := range r.Transitions {
+		path, pos, ok := splitLocation(t.Location)
+		if !ok {
+			continue
+		}
+		if regions[path] == nil {
+			regions[path] = map[string]*region{}
+			paths = append(paths, path)
+		}
+		if regions[path][pos] == nil {
+			regions[path][pos] = &region{}
+		}
+		regions[path][pos].transitions = append(regions[path][pos].transitions, t)
+	}
+
+	files := []htmlFile{}
+	for _, path := range paths {
+		src, err := read(path)
+		if err != nil {
+			return err
+		}
+
+		rs := []*region{}
+		for pos, rg := range regions[path] {
+			var ok bool
+			if rg.start, rg.end, ok = extent(string(src), pos); ok {
+				rs = append(rs, rg)
+			}
+		}
+		files = append(files, htmlFile{Path: path, Source: highlight(string(src), rs)})
+	}
+
+	return htmlReport.Execute(w, map[string]any{"Report": r, "Summary": r.Text(), "Files": files})
+}
+
+// splitLocation splits path:line:column into the path and line:column.
+func splitLocation(loc string) (string, string, bool) {
+	col := strings.LastIndex(loc, ":")
+	if col < 0 {
+		return "", "", false
+	}
+	line := strings.LastIndex(loc[:col], ":")
+	if line < 0 {
+		return "", "", false
+	}
+	return loc[:line], loc[line+1:], true
+}
+
+// extent returns the offsets of the expression that starts at line:column in the source: a list up to its closing
+// parenthesis, or a single atom such as a label.
+func extent(src, pos string) (int, int, bool) {
+	parts := strings.Split(pos, ":")
+	line, err1 := strconv.Atoi(parts[0])
+	col, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || line < 1 || col < 1 {
+		return 0, 0, false
+	}
+
+	start := 0
+	for l := 1; l < line; l++ {
+		nl := strings.IndexByte(src[start:], '\n')
+		if nl < 0 {
+			return 0, 0, false
+		}
+		start += nl + 1
+	}
+	start += col - 1
+	if len(src) <= start {
+		return 0, 0, false
+	}
+
+	if src[start] != '(' {
+		end := start
+		for end < len(src) && !strings.ContainsRune(" \t\r\n()", rune(src[end])) {
+			end++
+		}
+		return start, end, true
+	}
+
+	depth := 0
+	for end := start; end < len(src); end++ {
+		switch src[end] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return start, end + 1, true
+			}
+		case ';':
+			for end < len(src) && src[end] != '\n' {
+				end++
+			}
+		case '"':
+			for end++; end < len(src) && src[end] != '"'; end++ {
+				if src[end] == '\\' {
+					end++
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// highlight escapes the source and wraps every region in a span. Regions of nested statements are nested spans.
+func highlight(src string, rs []*region) template.HTML {
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].start != rs[j].start {
+			return rs[i].start < rs[j].start
+		}
+		return rs[i].end > rs[j].end
+	})
+
+	var b strings.Builder
+	open := []*region{}
+	pos := 0
+	for {
+		// The next boundary is the end of the innermost open region, or the start of the next region.
+		next := len(src)
+		if len(open) != 0 {
+			next = open[len(open)-1].end
+		}
+		if len(rs) != 0 && rs[0].start < next {
+			next = rs[0].start
+		}
+		b.WriteString(template.HTMLEscapeString(src[pos:next]))
+		pos = next
+
+		switch {
+		case len(open) != 0 && open[len(open)-1].end == pos:
+			b.WriteString("</span>")
+			open = open[:len(open)-1]
+		case len(rs) != 0 && rs[0].start == pos:
+			// Statements nest, but a region never extends beyond the region that contains it.
+			if len(open) != 0 && open[len(open)-1].end < rs[0].end {
+				rs[0].end = open[len(open)-1].end
+			}
+			fmt.Fprintf(&b, `<span class="%s" title="%s">`, rs[0].class(), template.HTMLEscapeString(rs[0].title()))
+			open = append(open, rs[0])
+			rs = rs[1:]
+		default:
+			return template.HTML(b.String())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package ioco
+
+import (
+	"math/rand"
+	"time"
+
+	"dberk.nl/graphchecker/internal/lts"
+)
+
+// OnlineOptions configure an online test.
+type OnlineOptions struct {
+	// Steps is the number of inputs and observations of the test
+	Steps int
+	// Seed determines the choices of the tester, the same seed replays the same test against a deterministic
+	// implementation
+	Seed int64
+	// Timeout is how long the tester waits for an output before it observes quiescence
+	Timeout time.Duration
+}
+
+// Run is the outcome of an online test.
+type Run struct {
+	Seed int64
+	// Trace contains the inputs and observations of the test, including the one that failed
+	Trace []lts.Label
+	// Failure is nil if the implementation passed the test
+	Failure *Failure
+}
+
+// Online tests the implementation behind the adapter while it walks the specification. At every step it either gives
+// an input that the specification accepts, or observes an output or quiescence and checks that the specification
+// allows it. Outputs that the implementation produces before an input is given are observed first.
+//
+// The test only looks at the names of messages: inputs are given without fields, and the fields of outputs are ignored.
+func Online(spec *Spec, a Adapter, opts OnlineOptions) (*Run, error) {
+	rnd := rand.New(rand.NewSource(opts.Seed))
+	run := &Run{Seed: opts.Seed, Trace: []lts.Label{}}
+
+	s := spec.Initial
+	for step := 0; step < opts.Steps; step++ {
+		ins := spec.In(s)
+		if len(ins) != 0 && rnd.Intn(2) == 0 {
+			// An output that has already arrived is observed before the input is given.
+			msg, ok, err := a.Output(0)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				in := ins[rnd.Intn(len(ins))]
+				if err := a.Input(Message{Name: in.Name}); err != nil {
+					return nil, err
+				}
+
+				run.Trace = append(run.Trace, in)
+				s, _ = spec.Step(s, in)
+				continue
+			}
+
+			if s, ok = run.observe(spec, s, lts.Label{Kind: lts.Output, Name: msg.Name}); !ok {
+				return run, nil
+			}
+			continue
+		}
+
+		msg, ok, err := a.Output(opts.Timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		label := lts.DeltaLabel
+		if ok {
+			label = lts.Label{Kind: lts.Output, Name: msg.Name}
+		}
+		if s, ok = run.observe(spec, s, label); !ok {
+			return run, nil
+		}
+	}
+	return run, nil
+}
+
+// observe checks an observation against the specification, and returns the state that it leads to. It records a
+// failure and returns false if the specification doesn't allow the observation.
+func (run *Run) observe(spec *Spec, s int, label lts.Label) (int, bool) {
+	trace := append([]lts.Label{}, run.Trace...)
+	run.Trace = append(run.Trace, label)
+
+	next, ok := spec.Step(s, label)
+	if !ok {
+		run.Failure = &Failure{Trace: trace, Output: label, Allowed: spec.Out(s)}
+	}
+	return next, ok
+}
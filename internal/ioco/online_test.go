@@ -0,0 +1,165 @@
+package ioco
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+// fake is an implementation under test that answers every input with the outputs of a function.
+type fake struct {
+	respond func(in string) []string
+	pending []string
+}
+
+func (f *fake) Input(msg Message) error {
+	f.pending = append(f.pending, f.respond(msg.Name)...)
+	return nil
+}
+
+func (f *fake) Output(timeout time.Duration) (Message, bool, error) {
+	if len(f.pending) == 0 {
+		return Message{}, false, nil
+	}
+
+	msg := Message{Name: f.pending[0]}
+	f.pending = f.pending[1:]
+	return msg, true, nil
+}
+
+func (f *fake) Close() error {
+	return nil
+}
+
+func TestOnline(t *testing.T) {
+	var tests = []struct {
+		name       string
+		respond    func(in string) []string
+		expFailure string
+	}{
+		{
+			name:    "conforming",
+			respond: func(in string) []string { return []string{"value"} },
+		},
+		{
+			name:       "unexpected output",
+			respond:    func(in string) []string { return []string{"value", "value"} },
+			expFailure: "the implementation may produce !value, the specification only allows δ",
+		},
+		{
+			name:       "unexpected quiescence",
+			respond:    func(in string) []string { return nil },
+			expFailure: "the implementation may produce δ, the specification only allows !missing, !value",
+		},
+	}
+
+	m, err := lisp.LoadString(server)
+	assert.Nil(t, err)
+
+	spec, err := NewSpec(m, "Server", 100)
+	assert.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Online - %s", test.name), func(t *testing.T) {
+			run, err := Online(spec, &fake{respond: test.respond}, OnlineOptions{Steps: 50, Seed: 1})
+			assert.Nil(t, err)
+
+			if test.expFailure == "" {
+				assert.Nil(t, run.Failure)
+				assert.Equal(t, 50, len(run.Trace))
+				return
+			}
+
+			assert.NotNil(t, run.Failure)
+			assert.Contains(t, run.Failure.String(), test.expFailure)
+			assert.Equal(t, run.Failure.Output, run.Trace[len(run.Trace)-1])
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	a := NewStream(outR, inW, func() error { return inW.Close() })
+
+	// The implementation answers every input with its name in capitals.
+	go func() {
+		scanner := bufio.NewScanner(inR)
+		for scanner.Scan() {
+			name := strings.Split(scanner.Text(), `"message":"`)[1]
+			name = strings.ToUpper(strings.Split(name, `"`)[0])
+			fmt.Fprintf(outW, `{"type":"output","message":%q,"fields":{"n":1}}`+"\n", name)
+		}
+		fmt.Fprintln(outW, `{"type":"input","message":"x"}`)
+	}()
+
+	assert.Nil(t, a.Input(Message{Name: "ping"}))
+	msg, ok, err := a.Output(time.Second)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Message{Name: "PING", Fields: map[string]any{"n": float64(1)}}, msg)
+
+	_, ok, err = a.Output(10 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, a.Close())
+	_, _, err = a.Output(time.Second)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `expected a frame of type output, got "input"`)
+}
+
+func TestStartClose(t *testing.T) {
+	defer func(timeout time.Duration) { closeTimeout = timeout }(closeTimeout)
+	closeTimeout = time.Second
+
+	var tests = []struct {
+		name   string
+		script string
+		expErr string
+	}{
+		{
+			// The implementation sends more outputs than the adapter buffers.
+			name:   "unread outputs",
+			script: `for i in $(seq 100); do echo '{"type":"output","message":"x"}'; done; cat >/dev/null`,
+		},
+		{
+			// The implementation keeps writing after a line that the adapter can't decode.
+			name:   "outputs after a decode error",
+			script: `echo x; yes '{"type":"output","message":"x"}' | head -n 100000; cat >/dev/null`,
+		},
+		{
+			name:   "implementation ignores the end of its input",
+			script: `exec sleep 60`,
+			expErr: "the implementation didn't exit within 1s after its stdin was closed: signal: killed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Close - %s", test.name), func(t *testing.T) {
+			a, err := Start("sh", "-c", test.script)
+			assert.Nil(t, err)
+
+			closed := make(chan error)
+			go func() { closed <- a.Close() }()
+
+			select {
+			case err := <-closed:
+				if test.expErr == "" {
+					assert.Nil(t, err)
+					return
+				}
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			case <-time.After(5 * time.Second):
+				t.Fatal("Close did not return")
+			}
+		})
+	}
+}
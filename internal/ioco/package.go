@@ -0,0 +1,2 @@
+// package ioco relates implementations to specifications with the input-output conformance relation ioco
+package ioco
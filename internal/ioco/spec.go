@@ -0,0 +1,106 @@
+package ioco
+
+import (
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/eval"
+	"dberk.nl/graphchecker/internal/lts"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Spec is the suspension automaton of an isolated instance, together with the transitions of its process that every
+// step of the automaton may take.
+type Spec struct {
+	*lts.Suspension
+	Name    string
+	Process *model.Process
+	// covers contains, per state and label, the transitions of the process that the step may take, including the
+	// internal transitions that may follow it
+	covers []map[lts.Label][]*model.Transition
+	// initial contains the internal transitions that may be taken before the first step
+	initial []*model.Transition
+	// fields contains, per state and output, the values of the fields of the message that the specification allows.
+	// Fields whose value depends on the environment are unknown.
+	fields []map[lts.Label][]map[string]eval.Value
+}
+
+// NewSpec isolates an instance of the model and returns its suspension automaton. It fails if the instance or the
+// automaton has more than max states.
+func NewSpec(m *model.Model, name string, max int) (*Spec, error) {
+	sys, err := compose.Isolate(m, name)
+	if err != nil {
+		return nil, err
+	}
+
+	l, steps, err := sys.UnfoldSteps(max)
+	if err != nil {
+		return nil, err
+	}
+
+	sa, err := lts.Suspend(l, max)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &Spec{
+		Suspension: sa,
+		Name:       name,
+		Process:    sys.Instances[0].Process,
+		covers:     []map[lts.Label][]*model.Transition{},
+		fields:     []map[lts.Label][]map[string]eval.Value{},
+	}
+	internal := func(set []int) []*model.Transition {
+		ts := []*model.Transition{}
+		for _, s := range set {
+			for idx, t := range l.Transitions[s] {
+				if t.Label.Kind == lts.Tau {
+					ts = append(ts, moved(steps[s][idx])...)
+				}
+			}
+		}
+		return ts
+	}
+
+	spec.initial = internal(sa.Sets[sa.Initial])
+	for from, ts := range sa.Transitions {
+		covers := map[lts.Label][]*model.Transition{}
+		fields := map[lts.Label][]map[string]eval.Value{}
+		for _, t := range ts {
+			for _, s := range sa.Sets[from] {
+				for idx, lt := range l.Transitions[s] {
+					if lt.Label != t.Label {
+						continue
+					}
+					step := steps[s][idx]
+					covers[t.Label] = append(covers[t.Label], moved(step)...)
+					if t.Label.Kind == lts.Output && step.Message != nil {
+						fields[t.Label] = append(fields[t.Label], step.Message.Fields)
+					}
+				}
+			}
+			covers[t.Label] = append(covers[t.Label], internal(sa.Sets[t.To])...)
+		}
+		spec.covers = append(spec.covers, covers)
+		spec.fields = append(spec.fields, fields)
+	}
+	return spec, nil
+}
+
+// Covers returns the transitions of the process that the step from the state with the label may take.
+func (spec *Spec) Covers(s int, label lts.Label) []*model.Transition {
+	return spec.covers[s][label]
+}
+
+// Fields returns the values of the fields of the output from the state that the specification allows, one valuation
+// per way in which the specification may send it.
+func (spec *Spec) Fields(s int, label lts.Label) []map[string]eval.Value {
+	return spec.fields[s][label]
+}
+
+// moved returns the transitions of the processes that take part in the step.
+func moved(step *compose.Step) []*model.Transition {
+	ts := []*model.Transition{}
+	for _, m := range step.Moves {
+		ts = append(ts, m.Transition)
+	}
+	return ts
+}
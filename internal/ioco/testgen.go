@@ -0,0 +1,268 @@
+package ioco
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/lts"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Verdict is the outcome of a test.
+type Verdict string
+
+const (
+	Pass Verdict = "pass"
+	Fail Verdict = "fail"
+)
+
+// Test is a test case in the style of Tretmans: a tree that, at every node, either ends with a verdict, gives an input
+// to the implementation, or observes its output. Quiescence is observed as δ, e.g. by a timeout.
+type Test struct {
+	// Verdict ends the test, it is empty if the test goes on
+	Verdict Verdict
+	// Input is given to the implementation, after which the test continues with Next. It is nil if the test observes.
+	Input *lts.Label
+	Next  *Test
+	// Observe contains the test that continues after every allowed observation, outputs that aren't allowed fail. A test
+	// that gives an input may observe an output instead, if the implementation produces one first.
+	Observe map[lts.Label]*Test
+	// Covers contains the transitions of the process that the step into this node may take
+	Covers []*model.Transition
+}
+
+// Observations returns the allowed observations in a canonical order.
+func (t *Test) Observations() []lts.Label {
+	labels := []lts.Label{}
+	for label := range t.Observe {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].String() < labels[j].String()
+	})
+	return labels
+}
+
+func (t *Test) String() string {
+	var b strings.Builder
+	t.write(&b, "")
+	return b.String()
+}
+
+func (t *Test) write(b *strings.Builder, indent string) {
+	switch {
+	case t.Verdict != "":
+		fmt.Fprintf(b, "%s\n", t.Verdict)
+	case t.Input != nil:
+		fmt.Fprintf(b, "give %s\n", t.Input)
+		for _, label := range t.Observations() {
+			fmt.Fprintf(b, "%s  if %s comes first: ", indent, label)
+			t.Observe[label].write(b, indent+"  ")
+		}
+		fmt.Fprintf(b, "%s  then ", indent)
+		t.Next.write(b, indent+"  ")
+	default:
+		b.WriteString("observe\n")
+		for _, label := range t.Observations() {
+			fmt.Fprintf(b, "%s  on %s: ", indent, label)
+			t.Observe[label].write(b, indent+"  ")
+		}
+		fmt.Fprintf(b, "%s  on anything else: %s\n", indent, Fail)
+	}
+}
+
+// GenOptions configure the generation of tests.
+type GenOptions struct {
+	// Depth bounds the number of inputs and observations along every path of a test
+	Depth int
+	// Seed breaks the ties between equally useful choices
+	Seed int64
+	// Max bounds the number of tests, 0 generates tests until every reachable transition is covered
+	Max int
+}
+
+// Generate generates tests from the specification. Every test steers towards the transitions of the process that the
+// previous tests didn't cover, generation stops when a test covers nothing new.
+func Generate(spec *Spec, opts GenOptions) []*Test {
+	g := &generator{spec: spec, rnd: rand.New(rand.NewSource(opts.Seed)), covered: map[*model.Transition]bool{}}
+	g.cover(spec.initial)
+
+	tests := []*Test{}
+	for opts.Max == 0 || len(tests) < opts.Max {
+		before := len(g.covered)
+		t := g.test(spec.Initial, opts.Depth, false)
+		if len(tests) != 0 && len(g.covered) == before {
+			break
+		}
+		tests = append(tests, t)
+	}
+	return tests
+}
+
+// Covered returns the transitions of the process that the tests cover.
+func (spec *Spec) Covered(tests []*Test) map[*model.Transition]bool {
+	covered := map[*model.Transition]bool{}
+	for _, t := range spec.initial {
+		covered[t] = true
+	}
+
+	var walk func(t *Test)
+	walk = func(t *Test) {
+		if t == nil {
+			return
+		}
+		for _, c := range t.Covers {
+			covered[c] = true
+		}
+		walk(t.Next)
+		for _, next := range t.Observe {
+			walk(next)
+		}
+	}
+	for _, t := range tests {
+		walk(t)
+	}
+	return covered
+}
+
+type generator struct {
+	spec    *Spec
+	rnd     *rand.Rand
+	covered map[*model.Transition]bool
+}
+
+func (g *generator) cover(ts []*model.Transition) {
+	for _, t := range ts {
+		g.covered[t] = true
+	}
+}
+
+// gain returns the number of transitions that the step covers for the first time.
+func (g *generator) gain(s int, label lts.Label) int {
+	n := 0
+	for _, t := range g.spec.Covers(s, label) {
+		if !g.covered[t] {
+			n++
+		}
+	}
+	return n
+}
+
+// test generates a test from the state of the automaton. A test that has just given an input observes the response,
+// even if there is nothing left to cover.
+func (g *generator) test(s int, depth int, given bool) *Test {
+	if depth == 0 {
+		return &Test{Verdict: Pass}
+	}
+
+	input, ok := g.choose(s)
+	if !ok && !given {
+		return &Test{Verdict: Pass}
+	}
+	if !ok {
+		depth, input = 1, nil
+	}
+
+	labels := []lts.Label{}
+	if input != nil {
+		labels = append(labels, *input)
+	}
+	for _, out := range g.spec.Out(s) {
+		if input == nil || out.Kind != lts.Delta {
+			labels = append(labels, out)
+		}
+	}
+
+	// The steps of siblings are covered before the subtests are generated, so that the subtests look further.
+	for _, label := range labels {
+		g.cover(g.spec.Covers(s, label))
+	}
+
+	t := &Test{Observe: map[lts.Label]*Test{}}
+	for _, label := range labels {
+		to, _ := g.spec.Step(s, label)
+		next := g.test(to, depth-1, input != nil && label == *input)
+		next.Covers = g.spec.Covers(s, label)
+
+		if input != nil && label == *input {
+			t.Input, t.Next = input, next
+		} else {
+			t.Observe[label] = next
+		}
+	}
+	return t
+}
+
+// choose decides whether the test gives an input or observes. It prefers the step that covers the most new
+// transitions, and otherwise heads for the nearest step that covers any. It returns false if the test should stop
+// because there is nothing left to cover.
+func (g *generator) choose(s int) (*lts.Label, bool) {
+	type option struct {
+		input *lts.Label
+		gain  int
+	}
+
+	observe := 0
+	for _, out := range g.spec.Out(s) {
+		observe += g.gain(s, out)
+	}
+
+	options := []option{{nil, observe}}
+	for _, in := range g.spec.In(s) {
+		in := in
+		options = append(options, option{&in, g.gain(s, in)})
+	}
+	g.rnd.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+
+	best := options[0]
+	for _, o := range options[1:] {
+		if o.gain > best.gain {
+			best = o
+		}
+	}
+	if best.gain > 0 {
+		return best.input, true
+	}
+
+	label, ok := g.towards(s)
+	if !ok {
+		return nil, false
+	}
+	if label.Kind == lts.Input {
+		return &label, true
+	}
+	return nil, true
+}
+
+// towards returns the first label of a shortest path to a step that covers new transitions.
+func (g *generator) towards(from int) (lts.Label, bool) {
+	first := map[int]lts.Label{}
+	seen := map[int]bool{from: true}
+	queue := []int{from}
+	for len(queue) != 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		for _, t := range g.spec.Transitions[s] {
+			label, ok := first[s]
+			if s == from {
+				label, ok = t.Label, true
+			}
+			if !ok {
+				continue
+			}
+
+			if g.gain(s, t.Label) > 0 {
+				return label, true
+			}
+			if !seen[t.To] {
+				seen[t.To] = true
+				first[t.To] = label
+				queue = append(queue, t.To)
+			}
+		}
+	}
+	return lts.Label{}, false
+}
@@ -0,0 +1,112 @@
+package ioco
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	var tests = []struct {
+		name       string
+		str        string
+		opts       GenOptions
+		expTests   []string
+		expCovered int
+	}{
+		{
+			name: "choice between outputs",
+			str:  server,
+			opts: GenOptions{Depth: 10},
+			expTests: []string{`give ?get
+  then observe
+    on !missing: pass
+    on !value: pass
+    on anything else: fail
+`},
+			expCovered: 6,
+		},
+		{
+			name: "choice between inputs",
+			str: `(defprocess Door
+			        (loop
+			          (select
+			            (?receive :message open)
+			            (?receive :message lock))
+			          (!send :message done)))`,
+			opts: GenOptions{Depth: 10},
+			expTests: []string{`give ?lock
+  then observe
+    on !done: give ?open
+      then observe
+        on !done: pass
+        on anything else: fail
+    on anything else: fail
+`},
+			expCovered: 6,
+		},
+		{
+			name: "depth bound",
+			str: `(defprocess Chain
+			        (?receive :message a)
+			        (!send :message b)
+			        (?receive :message c))`,
+			opts: GenOptions{Depth: 1},
+			expTests: []string{`give ?a
+  then pass
+`},
+			expCovered: 1,
+		},
+		{
+			name: "seed",
+			str: `(defprocess Door
+			        (loop
+			          (select
+			            (?receive :message open)
+			            (?receive :message lock))
+			          (!send :message done)))`,
+			opts: GenOptions{Depth: 10, Seed: 6},
+			expTests: []string{`give ?open
+  then observe
+    on !done: give ?lock
+      then observe
+        on !done: pass
+        on anything else: fail
+    on anything else: fail
+`},
+			expCovered: 6,
+		},
+		{
+			name: "quiescence",
+			str: `(defprocess Chain
+			        (?receive :message a))`,
+			opts: GenOptions{Depth: 3},
+			expTests: []string{`give ?a
+  then observe
+    on δ: pass
+    on anything else: fail
+`},
+			expCovered: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Generate - %s", test.name), func(t *testing.T) {
+			m, err := lisp.LoadString(test.str)
+			assert.Nil(t, err)
+
+			spec, err := NewSpec(m, m.Processes[0].Name, 100)
+			assert.Nil(t, err)
+
+			tests := Generate(spec, test.opts)
+			strs := []string{}
+			for _, t := range tests {
+				strs = append(strs, t.String())
+			}
+			assert.Equal(t, test.expTests, strs)
+			assert.Equal(t, test.expCovered, len(spec.Covered(tests)))
+		})
+	}
+}
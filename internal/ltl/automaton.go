@@ -0,0 +1,260 @@
+package ltl
+
+import (
+	"sort"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Automaton is a generalized Büchi automaton. A run is accepted if it visits every acceptance set infinitely often.
+//
+// The states are labelled: a state can only be at a position of a run at which its literals hold.
+type Automaton struct {
+	// Atoms contains the propositions of the formula, literals refer to them by index
+	Atoms   []*model.Expression
+	States  []*State
+	Initial []int
+	// Sets is the number of acceptance sets
+	Sets int
+}
+
+// State is a state of an automaton.
+type State struct {
+	ID int
+	// Pos and Neg contain the atoms that must hold and must not hold at the position of the state
+	Pos, Neg []int
+	Succ     []int
+	// Accepting contains, per acceptance set, whether the state is part of it
+	Accepting []bool
+}
+
+// Enabled returns whether the literals of the state hold, given the value of every atom.
+func (s *State) Enabled(val []bool) bool {
+	for _, a := range s.Pos {
+		if !val[a] {
+			return false
+		}
+	}
+	for _, a := range s.Neg {
+		if val[a] {
+			return false
+		}
+	}
+	return true
+}
+
+// node is a node of the tableau of Gerth, Peled, Vardi and Wolper.
+type node struct {
+	id       int
+	incoming map[int]bool
+	new      map[string]*Formula
+	old      map[string]*Formula
+	next     map[string]*Formula
+}
+
+const initID = -1
+
+// Translate constructs an automaton that accepts exactly the runs that satisfy the formula.
+func Translate(f *Formula) *Automaton {
+	f = NNF(f)
+
+	t := &tableau{}
+	t.expand(&node{
+		incoming: map[int]bool{initID: true},
+		new:      map[string]*Formula{f.String(): f},
+		old:      map[string]*Formula{},
+		next:     map[string]*Formula{},
+	})
+
+	a := &Automaton{Atoms: []*model.Expression{}, States: []*State{}, Initial: []int{}}
+	atoms := map[string]int{}
+	atom := func(expr *model.Expression) int {
+		key := expr.String()
+		if idx, ok := atoms[key]; ok {
+			return idx
+		}
+		atoms[key] = len(a.Atoms)
+		a.Atoms = append(a.Atoms, expr)
+		return atoms[key]
+	}
+
+	untils := []*Formula{}
+	collectUntils(f, map[string]bool{}, &untils)
+	a.Sets = len(untils)
+
+	for _, n := range t.nodes {
+		s := &State{ID: n.id, Pos: []int{}, Neg: []int{}, Succ: []int{}, Accepting: []bool{}}
+		for _, key := range sortedKeys(n.old) {
+			g := n.old[key]
+			switch {
+			case g.Op == Atom:
+				s.Pos = append(s.Pos, atom(g.Expr))
+			case g.Op == Not:
+				s.Neg = append(s.Neg, atom(g.Left.Expr))
+			}
+		}
+
+		for _, u := range untils {
+			_, pending := n.old[u.String()]
+			_, fulfilled := n.old[u.Right.String()]
+			s.Accepting = append(s.Accepting, !pending || fulfilled)
+		}
+
+		if n.incoming[initID] {
+			a.Initial = append(a.Initial, n.id)
+		}
+		a.States = append(a.States, s)
+	}
+
+	for _, n := range t.nodes {
+		for from := range n.incoming {
+			if from != initID {
+				a.States[from].Succ = append(a.States[from].Succ, n.id)
+			}
+		}
+	}
+	for _, s := range a.States {
+		sort.Ints(s.Succ)
+	}
+
+	return a
+}
+
+type tableau struct {
+	nodes []*node
+}
+
+func (t *tableau) expand(n *node) {
+	if len(n.new) == 0 {
+		for _, other := range t.nodes {
+			if sameKeys(other.old, n.old) && sameKeys(other.next, n.next) {
+				for id := range n.incoming {
+					other.incoming[id] = true
+				}
+				return
+			}
+		}
+
+		n.id = len(t.nodes)
+		t.nodes = append(t.nodes, n)
+		t.expand(&node{
+			incoming: map[int]bool{n.id: true},
+			new:      copyFormulas(n.next),
+			old:      map[string]*Formula{},
+			next:     map[string]*Formula{},
+		})
+		return
+	}
+
+	// Take the formulas in a fixed order, so that the automaton does not depend on the iteration order of maps.
+	key := sortedKeys(n.new)[0]
+	f := n.new[key]
+	delete(n.new, key)
+
+	switch f.Op {
+	case False:
+		return
+
+	case True, Atom, Not:
+		if f.Op != True {
+			if _, ok := n.old[negate(f).String()]; ok {
+				return
+			}
+		}
+		n.old[key] = f
+		t.expand(n)
+
+	case And:
+		n.old[key] = f
+		n.add(f.Left)
+		n.add(f.Right)
+		t.expand(n)
+
+	case Next:
+		n.old[key] = f
+		n.next[f.Left.String()] = f.Left
+		t.expand(n)
+
+	default:
+		// Or, Until and Release split the node into two alternatives.
+		n1, n2 := n.copy(), n.copy()
+		n1.old[key] = f
+		n2.old[key] = f
+
+		switch f.Op {
+		case Or:
+			n1.add(f.Left)
+			n2.add(f.Right)
+		case Until:
+			n1.add(f.Left)
+			n1.next[key] = f
+			n2.add(f.Right)
+		case Release:
+			n1.add(f.Right)
+			n1.next[key] = f
+			n2.add(f.Left)
+			n2.add(f.Right)
+		}
+
+		t.expand(n1)
+		t.expand(n2)
+	}
+}
+
+// add schedules a formula for processing, unless it was processed already.
+func (n *node) add(f *Formula) {
+	key := f.String()
+	if _, ok := n.old[key]; !ok {
+		n.new[key] = f
+	}
+}
+
+func (n *node) copy() *node {
+	incoming := map[int]bool{}
+	for id := range n.incoming {
+		incoming[id] = true
+	}
+
+	return &node{
+		incoming: incoming,
+		new:      copyFormulas(n.new),
+		old:      copyFormulas(n.old),
+		next:     copyFormulas(n.next),
+	}
+}
+
+func copyFormulas(fs map[string]*Formula) map[string]*Formula {
+	c := map[string]*Formula{}
+	for k, f := range fs {
+		c[k] = f
+	}
+	return c
+}
+
+func sortedKeys(fs map[string]*Formula) []string {
+	keys := []string{}
+	for k := range fs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sameKeys(a, b map[string]*Formula) bool {
+	return strings.Join(sortedKeys(a), "\x00") == strings.Join(sortedKeys(b), "\x00")
+}
+
+// collectUntils collects the until subformulas of the formula, each of which gives rise to an acceptance set.
+func collectUntils(f *Formula, seen map[string]bool, untils *[]*Formula) {
+	if f == nil {
+		return
+	}
+
+	if f.Op == Until && !seen[f.String()] {
+		seen[f.String()] = true
+		*untils = append(*untils, f)
+	}
+	collectUntils(f.Left, seen, untils)
+	collectUntils(f.Right, seen, untils)
+}
@@ -0,0 +1,156 @@
+package ltl
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	var tests = []struct {
+		formula string
+		// prefix and cycle contain the atoms p and q that hold at every position of an ultimately periodic run
+		prefix, cycle []string
+		expAccepted   bool
+	}{
+		{formula: "p", cycle: []string{"p"}, expAccepted: true},
+		{formula: "p", cycle: []string{""}, expAccepted: false},
+		{formula: "(X p)", prefix: []string{""}, cycle: []string{"p"}, expAccepted: true},
+		{formula: "(F p)", prefix: []string{"", "", "p"}, cycle: []string{""}, expAccepted: true},
+		{formula: "(F p)", cycle: []string{""}, expAccepted: false},
+		{formula: "(G p)", prefix: []string{"p"}, cycle: []string{"p", ""}, expAccepted: false},
+		{formula: "(G (F p))", cycle: []string{"", "p"}, expAccepted: true},
+		{formula: "(G (F p))", prefix: []string{"p"}, cycle: []string{""}, expAccepted: false},
+		{formula: "(F (G p))", prefix: []string{"", "p", ""}, cycle: []string{"p"}, expAccepted: true},
+		{formula: "(U p q)", prefix: []string{"p", "p"}, cycle: []string{"q"}, expAccepted: true},
+		{formula: "(U p q)", prefix: []string{"p", ""}, cycle: []string{"q"}, expAccepted: false},
+		{formula: "(G (-> p (F q)))", prefix: []string{"p", "", "q"}, cycle: []string{"p", "q"}, expAccepted: true},
+		{formula: "(G (-> p (F q)))", prefix: []string{"q"}, cycle: []string{"p", ""}, expAccepted: false},
+		{formula: "(not (G (-> p (F q))))", prefix: []string{"q"}, cycle: []string{"p", ""}, expAccepted: true},
+		{formula: "(R p q)", cycle: []string{"q"}, expAccepted: true},
+		{formula: "(R p q)", prefix: []string{"q", "pq"}, cycle: []string{""}, expAccepted: true},
+		{formula: "(R p q)", prefix: []string{"q", "p"}, cycle: []string{""}, expAccepted: false},
+		{formula: "(and p (not p))", cycle: []string{"p"}, expAccepted: false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Translate(%s) on %v%v", test.formula, test.prefix, test.cycle), func(t *testing.T) {
+			expr, err := lisp.ParseExpression(test.formula)
+			assert.Nil(t, err)
+
+			f, err := Parse(expr)
+			assert.Nil(t, err)
+
+			a := Translate(f)
+			assert.Equal(t, test.expAccepted, accepts(a, test.prefix, test.cycle))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		formula string
+		exp     string
+		expErr  string
+	}{
+		{formula: "(always (-> (received req) (eventually (sent resp))))", exp: "(R false (or (not (received req)) (U true (sent resp))))"},
+		{formula: "(and p q r)", exp: "(and (and p q) r)"},
+		{formula: "(= (var Worker :n) 1)", exp: "(= (var Worker :n) 1)"},
+		{formula: "(G p q)", expErr: "G: expected 1 argument(s), got 2"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Parse(%s)", test.formula), func(t *testing.T) {
+			expr, err := lisp.ParseExpression(test.formula)
+			assert.Nil(t, err)
+
+			f, err := Parse(expr)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.exp, f.String())
+		})
+	}
+}
+
+// accepts returns whether the automaton accepts the run prefix cycle cycle cycle ...
+func accepts(a *Automaton, prefix, cycle []string) bool {
+	word := append(append([]string{}, prefix...), cycle...)
+	valuation := func(pos int) []bool {
+		val := []bool{}
+		for _, atom := range a.Atoms {
+			val = append(val, contains(word[pos], atom.String()))
+		}
+		return val
+	}
+	next := func(pos int) int {
+		if pos+1 == len(word) {
+			return len(prefix)
+		}
+		return pos + 1
+	}
+
+	// Nodes of the product are pairs of a position and a state, encoded as pos*len(states) + state.
+	n := len(word) * len(a.States)
+	reach := make([][]bool, n)
+	for v := range reach {
+		reach[v] = make([]bool, n)
+		pos, q := v/len(a.States), v%len(a.States)
+		if !a.States[q].Enabled(valuation(pos)) {
+			continue
+		}
+		for _, r := range a.States[q].Succ {
+			if a.States[r].Enabled(valuation(next(pos))) {
+				reach[v][next(pos)*len(a.States)+r] = true
+			}
+		}
+	}
+
+	for k := range n {
+		for i := range n {
+			for j := range n {
+				reach[i][j] = reach[i][j] || (reach[i][k] && reach[k][j])
+			}
+		}
+	}
+
+	for _, q := range a.Initial {
+		if !a.States[q].Enabled(valuation(0)) {
+			continue
+		}
+
+		for v := range n {
+			if v != q && !reach[q][v] || !reach[v][v] {
+				continue
+			}
+
+			accepted := true
+			for set := range a.Sets {
+				found := false
+				for u := range n {
+					found = found || (reach[v][u] && reach[u][v] && a.States[u%len(a.States)].Accepting[set])
+				}
+				accepted = accepted && found
+			}
+			if accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(s, atom string) bool {
+	for _, r := range s {
+		if string(r) == atom {
+			return true
+		}
+	}
+	return false
+}
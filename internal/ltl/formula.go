@@ -0,0 +1,176 @@
+package ltl
+
+import (
+	"fmt"
+
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Op is the operator of a formula.
+type Op int
+
+const (
+	True Op = iota
+	False
+	// Atom is a proposition that holds or doesn't hold at a position of a run
+	Atom
+	Not
+	And
+	Or
+	// Next holds if its operand holds at the next position
+	Next
+	// Until holds if its right operand eventually holds, and its left operand holds until then
+	Until
+	// Release holds if its right operand holds up to and including the position where its left operand holds, or
+	// forever if the left operand never holds
+	Release
+)
+
+// Formula is a formula of linear temporal logic.
+type Formula struct {
+	Op Op
+	// Expr is the proposition of an atom
+	Expr        *model.Expression
+	Left, Right *Formula
+}
+
+func (f *Formula) String() string {
+	switch f.Op {
+	case True:
+		return "true"
+	case False:
+		return "false"
+	case Atom:
+		return f.Expr.String()
+	case Not:
+		return fmt.Sprintf("(not %s)", f.Left)
+	case And:
+		return fmt.Sprintf("(and %s %s)", f.Left, f.Right)
+	case Or:
+		return fmt.Sprintf("(or %s %s)", f.Left, f.Right)
+	case Next:
+		return fmt.Sprintf("(X %s)", f.Left)
+	case Until:
+		return fmt.Sprintf("(U %s %s)", f.Left, f.Right)
+	case Release:
+		return fmt.Sprintf("(R %s %s)", f.Left, f.Right)
+	default:
+		return fmt.Sprintf("<%d>", f.Op)
+	}
+}
+
+func unary(op Op, f *Formula) *Formula {
+	return &Formula{Op: op, Left: f}
+}
+
+func binary(op Op, l, r *Formula) *Formula {
+	return &Formula{Op: op, Left: l, Right: r}
+}
+
+// Parse converts an expression into a formula. The temporal operators are written as:
+// - (X f) or (next f)
+// - (F f) or (eventually f)
+// - (G f) or (always f)
+// - (U f g) or (until f g)
+// - (R f g) or (release f g)
+//
+// Next to those, there are not, and, or and (-> f g). Every other expression is an atom.
+func Parse(expr *model.Expression) (*Formula, error) {
+	switch {
+	case expr.Type == "ref" && expr.Ref == "true":
+		return &Formula{Op: True}, nil
+	case expr.Type == "ref" && expr.Ref == "false":
+		return &Formula{Op: False}, nil
+	case expr.Type != "lst" || len(expr.Sub) == 0 || expr.Sub[0].Type != "ref":
+		return &Formula{Op: Atom, Expr: expr}, nil
+	}
+
+	op, args := expr.Sub[0].Ref, expr.Sub[1:]
+	arity := map[string]int{
+		"not": 1, "X": 1, "next": 1, "F": 1, "eventually": 1, "G": 1, "always": 1,
+		"->": 2, "U": 2, "until": 2, "R": 2, "release": 2,
+	}
+
+	if op != "and" && op != "or" {
+		n, ok := arity[op]
+		if !ok {
+			return &Formula{Op: Atom, Expr: expr}, nil
+		}
+		if len(args) != n {
+			return nil, fmt.Errorf("%s: expected %d argument(s), got %d", op, n, len(args))
+		}
+	}
+
+	fs := []*Formula{}
+	for _, arg := range args {
+		f, err := Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+
+	switch op {
+	case "and", "or":
+		if len(fs) == 0 {
+			return &Formula{Op: map[string]Op{"and": True, "or": False}[op]}, nil
+		}
+		f := fs[0]
+		for _, g := range fs[1:] {
+			f = binary(map[string]Op{"and": And, "or": Or}[op], f, g)
+		}
+		return f, nil
+	case "not":
+		return unary(Not, fs[0]), nil
+	case "X", "next":
+		return unary(Next, fs[0]), nil
+	case "F", "eventually":
+		return binary(Until, &Formula{Op: True}, fs[0]), nil
+	case "G", "always":
+		return binary(Release, &Formula{Op: False}, fs[0]), nil
+	case "->":
+		return binary(Or, unary(Not, fs[0]), fs[1]), nil
+	case "U", "until":
+		return binary(Until, fs[0], fs[1]), nil
+	default:
+		return binary(Release, fs[0], fs[1]), nil
+	}
+}
+
+// NNF returns an equivalent formula in which negations only apply to atoms.
+func NNF(f *Formula) *Formula {
+	switch f.Op {
+	case Not:
+		return negate(f.Left)
+	case True, False, Atom:
+		return f
+	case Next:
+		return unary(Next, NNF(f.Left))
+	default:
+		return binary(f.Op, NNF(f.Left), NNF(f.Right))
+	}
+}
+
+// negate returns the negation of the formula in negation normal form.
+func negate(f *Formula) *Formula {
+	switch f.Op {
+	case True:
+		return &Formula{Op: False}
+	case False:
+		return &Formula{Op: True}
+	case Atom:
+		return unary(Not, f)
+	case Not:
+		return NNF(f.Left)
+	case And:
+		return binary(Or, negate(f.Left), negate(f.Right))
+	case Or:
+		return binary(And, negate(f.Left), negate(f.Right))
+	case Next:
+		return unary(Next, negate(f.Left))
+	case Until:
+		return binary(Release, negate(f.Left), negate(f.Right))
+	default:
+		return binary(Until, negate(f.Left), negate(f.Right))
+	}
+}
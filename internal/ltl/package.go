@@ -0,0 +1,2 @@
+// package ltl contains linear temporal logic formulas, and their translation into Büchi automata
+package ltl
@@ -0,0 +1,2 @@
+// package lts contains explicit labelled transition systems, and the algorithms that operate on them
+package lts
@@ -0,0 +1,182 @@
+package lts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Quiescent returns whether the state is quiescent: it has neither outputs nor internal transitions, so the system
+// waits for an input. A state on a cycle of internal transitions is divergent, it is not quiescent.
+func (l *LTS) Quiescent(s int) bool {
+	for _, t := range l.Transitions[s] {
+		if t.Label.Kind == Output || t.Label.Kind == Tau {
+			return false
+		}
+	}
+	return true
+}
+
+// WithQuiescence returns a copy of the LTS with a δ transition from every quiescent state to itself.
+func (l *LTS) WithQuiescence() *LTS {
+	q := &LTS{
+		States:      append([]string{}, l.States...),
+		Initial:     l.Initial,
+		Transitions: [][]Transition{},
+	}
+
+	for s, ts := range l.Transitions {
+		q.Transitions = append(q.Transitions, append([]Transition{}, ts...))
+		if l.Quiescent(s) {
+			q.AddTransition(s, DeltaLabel, s)
+		}
+	}
+	return q
+}
+
+// Suspension is the suspension automaton of an LTS: the deterministic LTS without internal transitions whose traces
+// are the suspension traces of the LTS, i.e. its traces in which quiescence is observed as δ.
+type Suspension struct {
+	*LTS
+	// Sets contains, for every state, the states of the original LTS that it stands for, in increasing order
+	Sets [][]int
+}
+
+// Suspend adds quiescence to the LTS and determinises it into its suspension automaton. It fails if the automaton has
+// more than max states.
+func Suspend(l *LTS, max int) (*Suspension, error) {
+	q := l.WithQuiescence()
+
+	init := q.closure([]int{q.Initial})
+	sa := &Suspension{LTS: New(q.describe(init)), Sets: [][]int{init}}
+	indices := map[string]int{setKey(init): sa.Initial}
+
+	queue := []int{sa.Initial}
+	for len(queue) != 0 {
+		from := queue[0]
+		queue = queue[1:]
+
+		targets := map[Label][]int{}
+		for _, s := range sa.Sets[from] {
+			for _, t := range q.Transitions[s] {
+				if t.Label.Observable() {
+					targets[t.Label] = append(targets[t.Label], t.To)
+				}
+			}
+		}
+
+		for _, label := range sortedLabels(targets) {
+			set := q.closure(targets[label])
+			key := setKey(set)
+			to, ok := indices[key]
+			if !ok {
+				if max <= len(sa.States) {
+					return nil, fmt.Errorf("the suspension automaton has more than %d states", max)
+				}
+
+				to = sa.AddState(q.describe(set))
+				sa.Sets = append(sa.Sets, set)
+				indices[key] = to
+				queue = append(queue, to)
+			}
+			sa.AddTransition(from, label, to)
+		}
+	}
+
+	return sa, nil
+}
+
+// Step returns the state that the automaton moves to with the label, if any.
+func (sa *Suspension) Step(from int, label Label) (int, bool) {
+	for _, t := range sa.Transitions[from] {
+		if t.Label == label {
+			return t.To, true
+		}
+	}
+	return -1, false
+}
+
+// After returns the state that the automaton reaches with the suspension trace, if any.
+func (sa *Suspension) After(trace []Label) (int, bool) {
+	s := sa.Initial
+	for _, label := range trace {
+		var ok bool
+		if s, ok = sa.Step(s, label); !ok {
+			return -1, false
+		}
+	}
+	return s, true
+}
+
+// Out returns the outputs that the system may produce in a state, including δ if it may be quiescent.
+func (sa *Suspension) Out(s int) []Label {
+	return sa.labels(s, Output, Delta)
+}
+
+// In returns the inputs that the system accepts in a state.
+func (sa *Suspension) In(s int) []Label {
+	return sa.labels(s, Input)
+}
+
+func (sa *Suspension) labels(s int, kinds ...Kind) []Label {
+	labels := []Label{}
+	for _, t := range sa.Transitions[s] {
+		for _, kind := range kinds {
+			if t.Label.Kind == kind {
+				labels = append(labels, t.Label)
+			}
+		}
+	}
+	return labels
+}
+
+// closure returns the states that are reachable from the states by internal transitions, in increasing order.
+func (l *LTS) closure(states []int) []int {
+	seen := map[int]bool{}
+	stack := append([]int{}, states...)
+	for len(stack) != 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[s] {
+			continue
+		}
+
+		seen[s] = true
+		for _, t := range l.Transitions[s] {
+			if t.Label.Kind == Tau {
+				stack = append(stack, t.To)
+			}
+		}
+	}
+
+	set := []int{}
+	for s := range seen {
+		set = append(set, s)
+	}
+	sort.Ints(set)
+	return set
+}
+
+func (l *LTS) describe(set []int) string {
+	descs := []string{}
+	for _, s := range set {
+		descs = append(descs, l.States[s])
+	}
+	return "{" + strings.Join(descs, " | ") + "}"
+}
+
+func setKey(set []int) string {
+	return fmt.Sprint(set)
+}
+
+// sortedLabels returns the labels in a canonical order: the outputs and inputs by name, then δ.
+func sortedLabels[T any](m map[Label]T) []Label {
+	labels := []Label{}
+	for label := range m {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].String() < labels[j].String()
+	})
+	return labels
+}
@@ -0,0 +1,88 @@
+package lts
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// coffee is a machine that, after a coin, either serves coffee or internally decides to wait for a button.
+func coffee() *LTS {
+	l := New("idle")
+	paid := l.AddState("paid")
+	waiting := l.AddState("waiting")
+	l.AddTransition(l.Initial, Label{Input, "coin"}, paid)
+	l.AddTransition(paid, Label{Output, "coffee"}, l.Initial)
+	l.AddTransition(paid, TauLabel, waiting)
+	l.AddTransition(waiting, Label{Input, "button"}, paid)
+	return l
+}
+
+func TestQuiescent(t *testing.T) {
+	l := coffee()
+	assert.Equal(t, []bool{true, false, true}, []bool{l.Quiescent(0), l.Quiescent(1), l.Quiescent(2)})
+
+	q := l.WithQuiescence()
+	assert.Equal(t, 6, q.NumTransitions())
+	assert.Equal(t, 4, l.NumTransitions())
+	assert.Contains(t, q.Transitions[2], Transition{DeltaLabel, 2})
+}
+
+func TestSuspend(t *testing.T) {
+	sa, err := Suspend(coffee(), 100)
+	assert.Nil(t, err)
+
+	descs := []string{}
+	for s, ts := range sa.Transitions {
+		for _, tr := range ts {
+			descs = append(descs, fmt.Sprintf("%s %s %s", sa.States[s], tr.Label, sa.States[tr.To]))
+		}
+	}
+	assert.Equal(t, []string{
+		"{idle} ?coin {paid | waiting}",
+		"{idle} δ {idle}",
+		"{paid | waiting} !coffee {idle}",
+		"{paid | waiting} ?button {paid | waiting}",
+		"{paid | waiting} δ {waiting}",
+		"{waiting} ?button {paid | waiting}",
+		"{waiting} δ {waiting}",
+	}, descs)
+
+	var tests = []struct {
+		trace  []Label
+		expOut []string
+		expIn  []string
+	}{
+		{trace: []Label{}, expOut: []string{"δ"}, expIn: []string{"?coin"}},
+		{trace: []Label{{Input, "coin"}}, expOut: []string{"!coffee", "δ"}, expIn: []string{"?button"}},
+		{trace: []Label{{Input, "coin"}, DeltaLabel}, expOut: []string{"δ"}, expIn: []string{"?button"}},
+		{trace: []Label{{Input, "coin"}, DeltaLabel, {Input, "button"}}, expOut: []string{"!coffee", "δ"}, expIn: []string{"?button"}},
+		{trace: []Label{{Input, "coin"}, DeltaLabel, {Output, "coffee"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("After(%v)", test.trace), func(t *testing.T) {
+			s, ok := sa.After(test.trace)
+			if test.expOut == nil {
+				assert.False(t, ok)
+				return
+			}
+
+			assert.True(t, ok)
+			assert.Equal(t, test.expOut, labelStrings(sa.Out(s)))
+			assert.Equal(t, test.expIn, labelStrings(sa.In(s)))
+		})
+	}
+
+	_, err = Suspend(coffee(), 2)
+	assert.Error(t, err)
+}
+
+func labelStrings(labels []Label) []string {
+	strs := []string{}
+	for _, label := range labels {
+		strs = append(strs, label.String())
+	}
+	return strs
+}
@@ -0,0 +1,93 @@
+package lts
+
+import "fmt"
+
+// Kind is the kind of an action.
+type Kind string
+
+const (
+	// Input is an action that is controlled by the environment
+	Input Kind = "input"
+	// Output is an action that is controlled by the system, and observed by the environment
+	Output Kind = "output"
+	// Tau is an internal action, it can't be observed by the environment
+	Tau Kind = "tau"
+	// Delta is the observation of quiescence: the system produces no output until it receives an input
+	Delta Kind = "delta"
+)
+
+// Label is the label of a transition.
+type Label struct {
+	Kind Kind
+	Name string
+}
+
+// TauLabel is the label of internal transitions.
+var TauLabel = Label{Kind: Tau}
+
+// DeltaLabel is the label of transitions that observe quiescence.
+var DeltaLabel = Label{Kind: Delta}
+
+func (l Label) String() string {
+	switch l.Kind {
+	case Input:
+		return fmt.Sprintf("?%s", l.Name)
+	case Output:
+		return fmt.Sprintf("!%s", l.Name)
+	case Delta:
+		return "δ"
+	default:
+		return "tau"
+	}
+}
+
+// Observable returns whether the environment can observe the action.
+func (l Label) Observable() bool {
+	return l.Kind != Tau
+}
+
+// LTS is a labelled transition system. States are identified by their index.
+type LTS struct {
+	// States contains a description of every state
+	States []string
+	// Initial is the initial state
+	Initial int
+	// Transitions contains the outgoing transitions of every state
+	Transitions [][]Transition
+}
+
+// Transition is a labelled transition to another state.
+type Transition struct {
+	Label Label
+	To    int
+}
+
+// New returns an LTS that consists of only its initial state.
+func New(initial string) *LTS {
+	return &LTS{
+		States:      []string{initial},
+		Initial:     0,
+		Transitions: [][]Transition{{}},
+	}
+}
+
+// AddState adds a state and returns its index.
+func (l *LTS) AddState(desc string) int {
+	l.States = append(l.States, desc)
+	l.Transitions = append(l.Transitions, []Transition{})
+	return len(l.States) - 1
+}
+
+// AddTransition adds a transition between two states.
+func (l *LTS) AddTransition(from int, label Label, to int) {
+	l.Transitions[from] = append(l.Transitions[from], Transition{Label: label, To: to})
+}
+
+// NumTransitions returns the number of transitions.
+func (l *LTS) NumTransitions() int {
+	n := 0
+	for _, ts := range l.Transitions {
+		n += len(ts)
+	}
+	return n
+}
@@ -8,6 +8,17 @@ import (
 type Model struct {
 	Messages  []*Message
 	Processes []*Process
+	// System contains the process instances that make up the system, as declared by defsystem. It is nil if the spec
+	// does not declare a system.
+	System []*Instance
+	// Channels configures the channels between the instances, as declared by defchannel.
+	Channels []*Channel
+	// Invariants are the safety properties of the system, as declared by definvariant.
+	Invariants []*Invariant
+	// Properties are the temporal properties of the system, as declared by defproperty.
+	Properties []*Property
+	// Fairness contains the fairness assumptions under which the properties are checked, as declared by fair.
+	Fairness []*Fairness
 }
 
 type Message struct {
@@ -28,28 +39,123 @@ func (m *Message) String() string {
 	return fmt.Sprintf("(defmessage %s) %s", m.Name, strings.Join(fields, " "))
 }
 
+// Process is the graph of a single process. The first state is the initial state, named :start.
 type Process struct {
 	Name        string
+	Params      []string
 	Vars        []string
-	States      []string
+	States      []*State
 	Transitions []*Transition
 }
 
+// Instance is a copy of a process with its own identity. The arguments are bound to the parameters of the process.
+type Instance struct {
+	Name    string
+	Process *Process
+	Args    []*Expression
+}
+
+// Channel semantics
+const (
+	// ChannelSync channels have no buffer, a message is sent and received in a single step
+	ChannelSync = "sync"
+	// ChannelFIFO channels deliver messages reliably, in the order in which they were sent
+	ChannelFIFO = "fifo"
+	// ChannelBag channels deliver messages reliably, in any order
+	ChannelBag = "bag"
+	// ChannelLossy channels are FIFO channels that may lose messages
+	ChannelLossy = "lossy"
+	// ChannelDuplicating channels are FIFO channels that may deliver a message more than once
+	ChannelDuplicating = "duplicating"
+	// ChannelReordering channels are FIFO channels in which a message may overtake the message in front of it
+	ChannelReordering = "reordering"
+)
+
+// DefaultChannelCapacity is the capacity of a channel for which no capacity was declared.
+const DefaultChannelCapacity = 2
+
+// Channel configures the channel from one instance to another. A channel without From and To is the default for all
+// channels of the system.
+type Channel struct {
+	From, To  string
+	Semantics string
+	Capacity  int
+}
+
+// Default returns whether the configuration applies to every channel that isn't configured explicitly.
+func (c *Channel) Default() bool {
+	return c.From == "" && c.To == ""
+}
+
 type State struct {
 	ID   int
 	Name string
+	// Final marks a state in which the process may intentionally stop
+	Final bool
 }
 
 func (s *State) Named() bool {
 	return s.Name != ""
 }
 
+// Action is the kind of a transition.
+type Action string
+
+const (
+	// ActionInput receives a message
+	ActionInput Action = "input"
+	// ActionOutput sends a message
+	ActionOutput Action = "output"
+	// ActionTau is an internal step of the process, e.g. a goto, a branch of an if or an assignment
+	ActionTau Action = "tau"
+)
+
 type Transition struct {
 	From, To *State
+	// Action is the kind of the transition, Receive or Send name the message of an input or an output.
+	Action Action
 	Receive string
 	Send string
+	// Peer is the other end of the channel over which the message travels. For a send it is the recipient, for a receive
+	// it refers to the variable that is bound to the sender. It is nil if the message is not addressed.
+	Peer *Expression
 	Valuation map[string]*Expression
 	Constraint *Expression
+	// Guard numbers the if whose branch the transition takes, from 1, and Else marks its else branch. Guard is zero
+	// for transitions that are not the branch of an if.
+	Guard int
+	Else bool
+	// Bindings maps variables onto the fields of the received message whose values are assigned to them.
+	Bindings map[string]string
+	// Assignments are executed in order when the transition is taken.
+	Assignments []*Assignment
+	// Location is the path:line:column of the statement that the transition was created for, if known.
+	Location string
+}
+
+// String renders the action of the transition, its guard and its location, e.g. ?job [(< n 2)] (spec.lisp:3:5).
+func (t *Transition) String() string {
+	desc := "tau"
+	switch t.Action {
+	case ActionInput:
+		desc = "?" + t.Receive
+	case ActionOutput:
+		desc = "!" + t.Send
+	}
+
+	if t.Constraint != nil {
+		desc += fmt.Sprintf(" [%s]", t.Constraint)
+	}
+	if t.Location != "" {
+		desc += fmt.Sprintf(" (%s)", t.Location)
+	}
+	return desc
+}
+
+// Assignment assigns the value of an expression to a variable.
+type Assignment struct {
+	Var   string
+	Value *Expression
 }
 
 type Variable struct {
@@ -57,9 +163,87 @@ type Variable struct {
 	Name string
 }
 
+// Invariant is a condition that must hold in every reachable state of the system.
+type Invariant struct {
+	Name string
+	Expr *Expression
+	// Location is the path:line:column of the definition, if known.
+	Location string
+}
+
+// Property is a formula of linear temporal logic that every run of the system must satisfy.
+type Property struct {
+	Name    string
+	Formula *Expression
+	// Location is the path:line:column of the definition, if known.
+	Location string
+}
+
+// Fairness is an assumption about the runs of the system. A weakly fair action that is enabled continuously is
+// eventually taken, a strongly fair action that is enabled infinitely often is taken infinitely often.
+type Fairness struct {
+	Strong bool
+	// Process selects the steps of every instance of a process, Instance those of a single instance.
+	Process, Instance string
+	// Receive or Send restrict the steps to those that receive or send the message.
+	Receive, Send string
+	// From and To select the steps that deliver messages over the channel between two instances, instead.
+	From, To string
+	// Location is the path:line:column of the declaration, if known.
+	Location string
+}
+
+// String renders the assumption in the syntax of the DSL.
+func (f *Fairness) String() string {
+	kind := ":weak"
+	if f.Strong {
+		kind = ":strong"
+	}
+
+	target := f.Process
+	if f.Instance != "" {
+		target = f.Instance
+	}
+
+	switch {
+	case f.From != "":
+		target = fmt.Sprintf("(channel %s %s)", f.From, f.To)
+	case f.Receive != "":
+		target = fmt.Sprintf("(%s ?receive %s)", target, f.Receive)
+	case f.Send != "":
+		target = fmt.Sprintf("(%s !send %s)", target, f.Send)
+	}
+
+	return fmt.Sprintf("(fair %s %s)", kind, target)
+}
+
 type Expression struct {
 	Type string
 	Ref string
 	Sub []*Expression
 	Int int64
 }
+
+// String renders the expression in the syntax of the DSL.
+func (e *Expression) String() string {
+	switch e.Type {
+	case "lst":
+		subs := []string{}
+		for _, sub := range e.Sub {
+			subs = append(subs, sub.String())
+		}
+		return fmt.Sprintf("(%s)", strings.Join(subs, " "))
+	case "ref", "kw":
+		return e.Ref
+	case "int":
+		return fmt.Sprintf("%d", e.Int)
+	case "map":
+		subs := []string{}
+		for _, sub := range e.Sub {
+			subs = append(subs, sub.String())
+		}
+		return fmt.Sprintf("{%s}", strings.Join(subs, " "))
+	default:
+		return fmt.Sprintf("<%s>", e.Type)
+	}
+}
@@ -0,0 +1,3 @@
+// package simulate steps through a composed system one step at a time, interactively or at random, and saves the
+// steps as traces that can be replayed
+package simulate
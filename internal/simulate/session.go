@@ -0,0 +1,242 @@
+package simulate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/check"
+	"dberk.nl/graphchecker/internal/compose"
+)
+
+const help = `commands:
+  N          take enabled step N
+  r [N]      take N random steps, 1 by default
+  u, undo    undo the last step
+  redo       redo the last step that was undone
+  trace      show the steps so far
+  save FILE  save the steps so far as a trace that simulate --replay replays
+  q, quit    stop
+`
+
+// errQuit is returned by the command that ends the session.
+var errQuit = errors.New("quit")
+
+// Interact runs an interactive session: it shows the current state and the enabled steps, and reads commands from in
+// until it is closed or the user quits.
+func (sim *Simulator) Interact(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	if err := sim.show(out); err != nil {
+		return err
+	}
+
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		changed, err := sim.command(fields, out)
+		if err == errQuit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(out, "%v\n", err)
+			continue
+		}
+		if changed {
+			if err := sim.show(out); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// command executes a command, and returns whether it changed the current state.
+func (sim *Simulator) command(fields []string, out io.Writer) (bool, error) {
+	switch cmd := fields[0]; cmd {
+	case "q", "quit":
+		return false, errQuit
+	case "h", "help", "?":
+		fmt.Fprint(out, help)
+		return false, nil
+	case "u", "undo":
+		if !sim.Undo() {
+			return false, fmt.Errorf("nothing to undo")
+		}
+		return true, nil
+	case "redo":
+		if !sim.Redo() {
+			return false, fmt.Errorf("nothing to redo")
+		}
+		return true, nil
+	case "trace":
+		var b strings.Builder
+		fmt.Fprintf(&b, "  0. %s\n", sim.sys.Describe(sim.states[0]))
+		check.WriteSteps(&b, sim.Steps(), 1)
+		fmt.Fprint(out, b.String())
+		return false, nil
+	case "save":
+		if len(fields) != 2 {
+			return false, fmt.Errorf("expected the file to save the trace to")
+		}
+		if err := sim.Trace().Save(fields[1]); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "saved %d step(s) to %s\n", sim.pos, fields[1])
+		return false, nil
+	case "r", "random":
+		n := 1
+		if len(fields) == 2 {
+			var err error
+			if n, err = strconv.Atoi(fields[1]); err != nil || n < 1 {
+				return false, fmt.Errorf("expected a number of steps, got %s", fields[1])
+			}
+		}
+
+		taken := 0
+		for ; taken < n; taken++ {
+			step, err := sim.Random()
+			if err != nil {
+				return taken != 0, err
+			}
+			if step == nil && taken == 0 {
+				return false, fmt.Errorf("no step is enabled")
+			}
+			if step == nil {
+				break
+			}
+			fmt.Fprintf(out, "took %s\n", step)
+		}
+		return true, nil
+	default:
+		n, err := strconv.Atoi(cmd)
+		if err != nil {
+			return false, fmt.Errorf("unknown command %s, type help for the commands", cmd)
+		}
+
+		step, err := sim.Take(n - 1)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "took %s\n", step)
+		return true, nil
+	}
+}
+
+// show writes the current state, with the valuations of the variables and the contents of the channels, and the steps
+// that are enabled in it.
+func (sim *Simulator) show(out io.Writer) error {
+	sys, s := sim.sys, sim.State()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "state after %d step(s):\n", sim.pos)
+	for i, inst := range sys.Instances {
+		fmt.Fprintf(&b, "  %s at %s", inst.Name, location(s, i))
+		vars := []string{}
+		for idx, name := range inst.Vars {
+			vars = append(vars, fmt.Sprintf("%s=%s", name, s.Vars[i][idx]))
+		}
+		if len(vars) != 0 {
+			fmt.Fprintf(&b, " {%s}", strings.Join(vars, " "))
+		}
+		b.WriteString("\n")
+	}
+	for from, src := range sys.Instances {
+		for to, dst := range sys.Instances {
+			msgs := []string{}
+			for _, msg := range sys.Channel(s, from, to) {
+				msgs = append(msgs, msg.String())
+			}
+			if len(msgs) != 0 {
+				fmt.Fprintf(&b, "  channel %s -> %s: %s\n", src.Name, dst.Name, strings.Join(msgs, " "))
+			}
+		}
+	}
+
+	steps, err := sim.Enabled()
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		b.WriteString("no step is enabled\n")
+	} else {
+		b.WriteString("enabled steps:\n")
+	}
+	for idx, step := range steps {
+		desc, err := sim.describe(s, step)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "  %d. %s\n", idx+1, desc)
+	}
+
+	blocked, err := sys.Blocked(s)
+	if err != nil {
+		return err
+	}
+	if len(blocked) != 0 {
+		b.WriteString("disabled by their guard:\n")
+	}
+	for _, m := range blocked {
+		fmt.Fprintf(&b, "  - %s", m)
+		if m.Transition.Location != "" {
+			fmt.Fprintf(&b, "  (%s)", m.Transition.Location)
+		}
+		b.WriteString("\n")
+	}
+
+	v, err := sim.Check()
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		fmt.Fprintf(&b, "the state violates %s:\n", v.Property)
+		for _, reason := range v.Reasons {
+			fmt.Fprintf(&b, "  - %s\n", reason)
+		}
+	}
+
+	_, err = io.WriteString(out, b.String())
+	return err
+}
+
+// describe describes an enabled step, with the values of its guards and the locations of its statements.
+func (sim *Simulator) describe(s *compose.State, step *compose.Step) (string, error) {
+	desc := step.String()
+
+	vals := []string{}
+	for _, m := range step.Moves {
+		val, err := sim.sys.Guard(s, m)
+		if err != nil {
+			return "", err
+		}
+		if val != nil {
+			vals = append(vals, val.String())
+		}
+	}
+	if len(vals) != 0 {
+		desc += ": " + strings.Join(vals, ", ")
+	}
+
+	if locs := check.Locations(step); len(locs) != 0 {
+		desc += fmt.Sprintf("  (%s)", strings.Join(locs, ", "))
+	}
+	return desc, nil
+}
+
+func location(s *compose.State, i int) string {
+	if s.Locs[i].Named() {
+		return s.Locs[i].Name
+	}
+	return fmt.Sprintf("#%d", s.Locs[i].ID)
+}
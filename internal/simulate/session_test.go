@@ -0,0 +1,60 @@
+package simulate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInteract(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.json")
+	sim := newSimulator(t, pingPong, Options{Spec: "pingpong.lisp"})
+
+	var out strings.Builder
+	err := sim.Interact(strings.NewReader(strings.Join([]string{"2", "1", "9", "r 2", "undo", "save " + path, "frobnicate", "q", "1"}, "\n")), &out)
+	assert.Nil(t, err)
+	assert.Equal(t, `state after 0 step(s):
+  Client at :start
+  Server at :start {client=nil n=nil}
+enabled steps:
+  1. Client !ping, to Server  (<string>:4:6)
+  2. Server n := 0  (<string>:7:4)
+> took Server n := 0
+state after 1 step(s):
+  Client at :start
+  Server at #2 {client=nil n=0}
+enabled steps:
+  1. Client !ping, to Server  (<string>:4:6)
+> took Client !ping, to Server
+state after 2 step(s):
+  Client at #2
+  Server at #2 {client=nil n=0}
+  channel Client -> Server: ping
+enabled steps:
+  1. Server ?ping, from Client  (<string>:9:8)
+> there is no step 9, 1 step(s) are enabled
+> took Server ?ping, from Client
+took Server #3 -> #4 [(< n 1)]
+state after 4 step(s):
+  Client at #2
+  Server at #4 {client=Client n=0}
+enabled steps:
+  1. Server !pong, to Client  (<string>:11:10)
+> state after 3 step(s):
+  Client at #2
+  Server at #3 {client=Client n=0}
+enabled steps:
+  1. Server #3 -> #4 [(< n 1)]: true  (<string>:10:8)
+disabled by their guard:
+  - Server #3 -> #6 [(not (< n 1))]  (<string>:10:8)
+> saved 3 step(s) to `+path+`
+> unknown command frobnicate, type help for the commands
+> `, out.String())
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"step": "Server ?ping, from Client"`)
+}
@@ -0,0 +1,296 @@
+package simulate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"dberk.nl/graphchecker/internal/check"
+	"dberk.nl/graphchecker/internal/compose"
+)
+
+// Shrink minimizes a trace that ends in the violation of an invariant or of deadlock freedom. It removes steps with
+// delta debugging, and replaces steps by steps that take the same transitions with smaller data, e.g. a message to
+// (Worker 1) instead of (Worker 3), as long as the trace still violates the same property. Removing any single step
+// of the result no longer reproduces the violation.
+//
+// Candidate traces are replayed by the identities of their steps, skipping the steps that are not enabled.
+func Shrink(sys *compose.System, opts Options, t *Trace) (*Trace, *check.Violation, error) {
+	opts.Seed = t.Seed
+	sim := New(sys, opts)
+	if err := sim.Replay(t); err != nil {
+		return nil, nil, err
+	}
+	sh := &shrinker{sys: sys, opts: opts, nodes: map[string]*node{}, visited: map[*compose.State]*node{}, initial: sys.Initial()}
+
+	// The original trace may violate the property before its last step.
+	steps, _, v, err := sh.run(identities(sim.Steps()))
+	if err != nil {
+		return nil, nil, err
+	}
+	if v == nil {
+		return nil, nil, fmt.Errorf("the trace doesn't end in a violation")
+	}
+	sh.property = v.Property
+	descs := identities(steps)
+
+	// Simplifying first lets a step use data that an earlier step provides, e.g. receive the message of the first
+	// client, so that delta debugging can remove the steps that provided the other data.
+	for {
+		changed := false
+		if descs, changed, err = sh.simplify(descs); err != nil {
+			return nil, nil, err
+		}
+
+		shorter := len(descs)
+		if descs, err = sh.ddmin(descs); err != nil {
+			return nil, nil, err
+		}
+		if !changed && len(descs) == shorter {
+			break
+		}
+	}
+
+	steps, _, v, err = sh.run(descs)
+	if err != nil {
+		return nil, nil, err
+	}
+	shrunk, err := Record(sys, opts, steps)
+	if err != nil {
+		return nil, nil, err
+	}
+	shrunk.Violation = describeViolation(v)
+
+	shrunkV := *v
+	shrunkV.Trace = steps
+	return shrunk, &shrunkV, nil
+}
+
+type shrinker struct {
+	sys  *compose.System
+	opts Options
+	// property is the property that the shrunk traces must violate, any violation will do if it is empty
+	property string
+	// nodes caches the states that candidate traces visit by their key, most candidates share most of their states
+	nodes map[string]*node
+	// visited caches the nodes by the states themselves, as computing the key is expensive and the cached steps lead to
+	// the same states again
+	visited map[*compose.State]*node
+	initial *compose.State
+}
+
+// node is a state with its enabled steps, their identities and the property that it violates, if any.
+type node struct {
+	steps     []*compose.Step
+	descs     []string
+	violation *check.Violation
+}
+
+func (sh *shrinker) node(s *compose.State) (*node, error) {
+	if n, ok := sh.visited[s]; ok {
+		return n, nil
+	}
+	key := s.Key()
+	if n, ok := sh.nodes[key]; ok {
+		sh.visited[s] = n
+		return n, nil
+	}
+
+	steps, err := sh.sys.Successors(s)
+	if err != nil {
+		return nil, err
+	}
+	n := &node{steps: steps, descs: identities(steps)}
+
+	inv, err := check.Violated(sh.sys, sh.opts.Invariants, s)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case inv != nil:
+		n.violation = check.InvariantViolation(inv, nil, s)
+	case len(steps) == 0:
+		n.violation = check.DeadlockViolation(sh.sys, nil, s)
+	}
+
+	sh.nodes[key] = n
+	sh.visited[s] = n
+	return n, nil
+}
+
+// run takes the steps with the identities until a state violates the property, and returns the steps that it took,
+// the state it stopped in and the violation. Steps that are not enabled are skipped, so that a candidate that removes
+// e.g. a send may still succeed without the matching receive. The violation is nil if no state violates the property.
+func (sh *shrinker) run(descs []string) ([]*compose.Step, *compose.State, *check.Violation, error) {
+	s := sh.initial
+	taken := []*compose.Step{}
+	for idx := 0; ; idx++ {
+		n, err := sh.node(s)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if v := n.violation; v != nil && (sh.property == "" || v.Property == sh.property) {
+			return taken, s, v, nil
+		}
+		if idx == len(descs) {
+			return taken, s, nil, nil
+		}
+
+		var next *compose.Step
+		for i, desc := range n.descs {
+			if desc == descs[idx] {
+				next = n.steps[i]
+				break
+			}
+		}
+		if next != nil {
+			taken = append(taken, next)
+			s = next.Target
+		}
+	}
+}
+
+// identities returns the identities of the steps.
+func identities(steps []*compose.Step) []string {
+	ids := []string{}
+	for _, step := range steps {
+		ids = append(ids, identity(step))
+	}
+	return ids
+}
+
+// identity tells the step apart from the other steps of its state. The description of a step leaves out the contents
+// of its message, so steps that deliver different messages from a bag may share it. A step that a channel takes on its
+// own, e.g. a reordering, has no message and is told apart by the position of the message that it moves.
+func identity(step *compose.Step) string {
+	id := step.String()
+	if step.Message != nil {
+		id += " " + step.Message.String()
+	}
+	if len(step.Moves) == 0 {
+		id += fmt.Sprintf(" at %d", step.Position)
+	}
+	return id
+}
+
+// ddmin removes chunks of steps, starting with halves, as long as the rest still violates the property.
+func (sh *shrinker) ddmin(descs []string) ([]string, error) {
+	if len(descs) == 0 {
+		return descs, nil
+	}
+	if _, _, v, err := sh.run(nil); v != nil || err != nil {
+		return []string{}, err
+	}
+
+	n := 2
+	for 2 <= len(descs) {
+		size := (len(descs) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(descs); start += size {
+			end := min(start+size, len(descs))
+			candidate := append(append([]string{}, descs[:start]...), descs[end:]...)
+
+			steps, _, v, err := sh.run(candidate)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				descs = identities(steps)
+				n = max(n-1, 2)
+				reduced = true
+				break
+			}
+		}
+
+		if !reduced {
+			if len(descs) <= n {
+				break
+			}
+			n = min(2*n, len(descs))
+		}
+	}
+	return descs, nil
+}
+
+// simplify replaces steps by steps that take the same transitions with smaller data. It returns whether it replaced
+// any step.
+func (sh *shrinker) simplify(descs []string) ([]string, bool, error) {
+	changed := false
+	for idx := 0; idx < len(descs); idx++ {
+		_, s, _, err := sh.run(descs[:idx])
+		if err != nil {
+			return nil, false, err
+		}
+		n, err := sh.node(s)
+		if err != nil {
+			return nil, false, err
+		}
+
+		var current *compose.Step
+		for i, desc := range n.descs {
+			if desc == descs[idx] {
+				current = n.steps[i]
+			}
+		}
+		if current == nil || len(current.Moves) == 0 {
+			continue
+		}
+
+		alternatives := []string{}
+		for i, step := range n.steps {
+			if sameMoves(step, current) && size(n.descs[i]) < size(descs[idx]) {
+				alternatives = append(alternatives, n.descs[i])
+			}
+		}
+		sort.SliceStable(alternatives, func(i, j int) bool {
+			return size(alternatives[i]) < size(alternatives[j])
+		})
+
+		for _, alt := range alternatives {
+			candidate := append(append(append([]string{}, descs[:idx]...), alt), descs[idx+1:]...)
+			steps, _, v, err := sh.run(candidate)
+			if err != nil {
+				return nil, false, err
+			}
+			if v != nil {
+				descs = identities(steps)
+				changed = true
+				break
+			}
+		}
+	}
+	return descs, changed, nil
+}
+
+// sameMoves returns whether the steps take the same transitions of the same instances.
+func sameMoves(a, b *compose.Step) bool {
+	if len(a.Moves) != len(b.Moves) {
+		return false
+	}
+	for idx := range a.Moves {
+		if a.Moves[idx] != b.Moves[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+var integer = regexp.MustCompile(`-?\d+`)
+
+// size measures the data in the identity of a step: the sum of the magnitudes of the integers in it, e.g. the
+// fields of a message or the arguments of an instance.
+func size(desc string) int {
+	sum := 0
+	for _, s := range integer.FindAllString(desc, -1) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			continue
+		}
+		if n < 0 {
+			n = -n
+		}
+		sum += n
+	}
+	return sum
+}
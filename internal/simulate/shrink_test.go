@@ -0,0 +1,179 @@
+package simulate
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/check"
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/explore"
+	"github.com/stretchr/testify/assert"
+)
+
+// noisy counts the pings of the client, while another instance keeps taking steps that don't matter.
+const noisy = `(defprocess Client
+                 (loop
+                   (!send :message ping :to Server)
+                   (?receive :message pong)))
+               (defprocess Server
+                 (let ((n 0))
+                   (loop
+                     (?receive :message ping :from client)
+                     (!send :message pong :to client)
+                     (set! n (+ n 1)))))
+               (defprocess Noise
+                 (let ((x 0))
+                   (loop (set! x (+ x 1)))))
+               (definvariant few (!= (var Server :n) 2))`
+
+// clients lets the server receive a ping from either client.
+const clients = `(defprocess Client (id)
+                   (loop (!send :message ping :to Server)))
+                 (defprocess Server
+                   (let ((n 0))
+                     (loop
+                       (?receive :message ping)
+                       (set! n (+ n 1)))))
+                 (defsystem (Client 1) (Client 2) Server)
+                 (defchannel :semantics fifo :capacity 3)
+                 (definvariant none (!= (var Server :n) 1))`
+
+func TestShrink(t *testing.T) {
+	var tests = []struct {
+		name     string
+		str      string
+		bias     Bias
+		expSteps int
+		expStep  string
+	}{
+		{name: "invariant", str: noisy, bias: Bias{"Noise": 10}, expSteps: 12},
+		{name: "deadlock", str: pingPong, expSteps: 17},
+		{name: "data", str: clients, bias: Bias{"Server": 0.1}, expSteps: 4, expStep: "Server ?ping, from (Client 1)"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Shrink - %s", test.name), func(t *testing.T) {
+			m, err := lisp.LoadString(test.str)
+			assert.Nil(t, err)
+
+			sys, err := compose.New(m, compose.Options{})
+			assert.Nil(t, err)
+
+			opts := Options{Invariants: m.Invariants}
+			walks, err := Walk(sys, WalkOptions{Options: Options{Seed: 1, Bias: test.bias, Invariants: m.Invariants}, Walks: 1, Length: 1000})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(walks.Failures))
+			original := walks.Failures[0]
+
+			shrunk, v, err := Shrink(sys, opts, original.Trace)
+			assert.Nil(t, err)
+			assert.Equal(t, original.Violation.Property, v.Property)
+			assert.Equal(t, test.expSteps, len(shrunk.Steps))
+			assert.LessOrEqual(t, len(shrunk.Steps), len(original.Trace.Steps))
+			if test.expStep != "" {
+				descs := []string{}
+				for _, ts := range shrunk.Steps {
+					descs = append(descs, ts.Step)
+				}
+				assert.Contains(t, descs, test.expStep)
+			}
+
+			// The shrunk trace reproduces the violation
+			sim := New(sys, opts)
+			assert.Nil(t, sim.Replay(shrunk))
+			again, err := sim.Check()
+			assert.Nil(t, err)
+			assert.Equal(t, v.Property, again.Property)
+			assert.Equal(t, describeViolation(v), shrunk.Violation)
+		})
+	}
+}
+
+func TestShrinkExplorerTrace(t *testing.T) {
+	m, err := lisp.LoadString(noisy)
+	assert.Nil(t, err)
+
+	sys, err := compose.New(m, compose.Options{})
+	assert.Nil(t, err)
+
+	v, _, err := check.Invariants(sys, m.Invariants, explore.Limits{})
+	assert.Nil(t, err)
+
+	opts := Options{Invariants: m.Invariants}
+	trace, err := Record(sys, opts, v.Trace)
+	assert.Nil(t, err)
+
+	shrunk, sv, err := Shrink(sys, opts, trace)
+	assert.Nil(t, err)
+	assert.Equal(t, v.Property, sv.Property)
+	assert.Equal(t, len(v.Trace), len(shrunk.Steps))
+}
+
+func TestShrinkWithoutViolation(t *testing.T) {
+	m, err := lisp.LoadString(noisy)
+	assert.Nil(t, err)
+
+	sys, err := compose.New(m, compose.Options{})
+	assert.Nil(t, err)
+
+	_, _, err = Shrink(sys, Options{Invariants: m.Invariants}, &Trace{Steps: []TraceStep{}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the trace doesn't end in a violation")
+}
+
+func TestShrinkMessageContents(t *testing.T) {
+	// The server may receive either ping from the bag, the receipts only differ in the field of the message.
+	m, err := lisp.LoadString(`
+		(defprocess Client
+		  (!send :message ping :to Server :n 1)
+		  (!send :message ping :to Server :n 2))
+		(defprocess Server
+		  (let ((got 0))
+		    (loop
+		      (let (({n} (?receive :message ping)))
+		        (set! got n)))))
+		(defchannel :semantics bag :capacity 2)
+		(definvariant first (!= (var Server :got) 2))`)
+	assert.Nil(t, err)
+
+	sys, err := compose.New(m, compose.Options{})
+	assert.Nil(t, err)
+
+	// Send both pings before receiving the second one.
+	opts := Options{Invariants: m.Invariants}
+	sim := New(sys, opts)
+	for {
+		v, err := sim.Check()
+		assert.Nil(t, err)
+		if v != nil {
+			break
+		}
+
+		steps, err := sim.Enabled()
+		assert.Nil(t, err)
+		idx := 0
+		for i, step := range steps {
+			if step.Message != nil && step.Message.String() == "(ping :n 2)" && step.Recipient != nil && step.Recipient.Name == "Server" && len(step.Target.Chans[1]) == 1 {
+				idx = i
+			}
+		}
+		_, err = sim.Take(idx)
+		assert.Nil(t, err)
+	}
+
+	shrunk, v, err := Shrink(sys, opts, sim.Trace())
+	assert.Nil(t, err)
+	assert.Equal(t, "invariant first", v.Property)
+
+	last := v.Trace[len(v.Trace)-2]
+	assert.Equal(t, "Server ?ping, from Client", last.String())
+	assert.Equal(t, "(ping :n 2)", last.Message.String())
+
+	// The shrunk trace reproduces the violation
+	sim = New(sys, opts)
+	assert.Nil(t, sim.Replay(shrunk))
+	again, err := sim.Check()
+	assert.Nil(t, err)
+	assert.NotNil(t, again)
+}
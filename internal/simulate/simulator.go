@@ -0,0 +1,276 @@
+package simulate
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/check"
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/lts"
+	"dberk.nl/graphchecker/internal/model"
+)
+
+// Options configure a simulation.
+type Options struct {
+	// Seed determines the random choices, and Bias how likely every step is to be chosen
+	Seed int64
+	Bias Bias
+	// Invariants are checked in every state
+	Invariants []*model.Invariant
+	// Spec is the path of the specification, and Closed whether the system was composed without the environment. Both
+	// are recorded in traces.
+	Spec   string
+	Closed bool
+}
+
+// Simulator walks through the states of a system. It remembers the steps it took, so that they can be undone and
+// redone.
+type Simulator struct {
+	sys  *compose.System
+	opts Options
+	rnd  *rand.Rand
+	// states contains the states that were visited, steps[i] is the step from states[i] to states[i+1] and choices[i]
+	// its index among the enabled steps. The states after pos were undone and can be redone.
+	states  []*compose.State
+	steps   []*compose.Step
+	choices []int
+	pos     int
+}
+
+// New returns a simulator in the initial state of the system.
+func New(sys *compose.System, opts Options) *Simulator {
+	return &Simulator{
+		sys:     sys,
+		opts:    opts,
+		rnd:     rand.New(rand.NewSource(opts.Seed)),
+		states:  []*compose.State{sys.Initial()},
+		steps:   []*compose.Step{},
+		choices: []int{},
+	}
+}
+
+// System returns the system that is simulated.
+func (sim *Simulator) System() *compose.System {
+	return sim.sys
+}
+
+// State returns the current state.
+func (sim *Simulator) State() *compose.State {
+	return sim.states[sim.pos]
+}
+
+// Steps returns the steps from the initial state to the current state.
+func (sim *Simulator) Steps() []*compose.Step {
+	return sim.steps[:sim.pos]
+}
+
+// Enabled returns the steps that the system can take from the current state.
+func (sim *Simulator) Enabled() ([]*compose.Step, error) {
+	return sim.sys.Successors(sim.State())
+}
+
+// Take takes the enabled step with the index. Steps that were undone are forgotten.
+func (sim *Simulator) Take(idx int) (*compose.Step, error) {
+	steps, err := sim.Enabled()
+	if err != nil {
+		return nil, err
+	}
+	if idx < 0 || len(steps) <= idx {
+		return nil, fmt.Errorf("there is no step %d, %d step(s) are enabled", idx+1, len(steps))
+	}
+
+	step := steps[idx]
+	sim.states = append(sim.states[:sim.pos+1], step.Target)
+	sim.steps = append(sim.steps[:sim.pos], step)
+	sim.choices = append(sim.choices[:sim.pos], idx)
+	sim.pos++
+	return step, nil
+}
+
+// Random takes an enabled step at random, according to the bias. It returns nil if no step is enabled.
+func (sim *Simulator) Random() (*compose.Step, error) {
+	steps, err := sim.Enabled()
+	if err != nil || len(steps) == 0 {
+		return nil, err
+	}
+
+	weights := []float64{}
+	total := 0.0
+	for _, step := range steps {
+		w := sim.opts.Bias.weight(step)
+		weights = append(weights, w)
+		total += w
+	}
+	if total == 0 {
+		// Steps that the bias excludes are only taken if there is nothing else to do.
+		return sim.Take(sim.rnd.Intn(len(steps)))
+	}
+
+	r := sim.rnd.Float64() * total
+	for idx, w := range weights {
+		if r < w {
+			return sim.Take(idx)
+		}
+		r -= w
+	}
+	return sim.Take(len(steps) - 1)
+}
+
+// Check returns the violation of an invariant by the current state, or of deadlock freedom if no step is enabled and
+// the state isn't an intended terminal state. It returns nil if the state violates neither.
+func (sim *Simulator) Check() (*check.Violation, error) {
+	s := sim.State()
+	inv, err := check.Violated(sim.sys, sim.opts.Invariants, s)
+	if err != nil {
+		return nil, err
+	}
+	if inv != nil {
+		return check.InvariantViolation(inv, sim.Steps(), s), nil
+	}
+
+	steps, err := sim.Enabled()
+	if err != nil || len(steps) != 0 {
+		return nil, err
+	}
+	return check.DeadlockViolation(sim.sys, sim.Steps(), s), nil
+}
+
+// Undo returns to the state before the last step. It returns false if the simulator is in the initial state.
+func (sim *Simulator) Undo() bool {
+	if sim.pos == 0 {
+		return false
+	}
+	sim.pos--
+	return true
+}
+
+// Redo takes the last step that was undone again. It returns false if there is none.
+func (sim *Simulator) Redo() bool {
+	if sim.pos == len(sim.steps) {
+		return false
+	}
+	sim.pos++
+	return true
+}
+
+// Trace returns the steps from the initial state to the current state.
+func (sim *Simulator) Trace() *Trace {
+	t := &Trace{Spec: sim.opts.Spec, Closed: sim.opts.Closed, Seed: sim.opts.Seed, Steps: []TraceStep{}}
+	for idx, step := range sim.Steps() {
+		t.Steps = append(t.Steps, TraceStep{Index: sim.choices[idx], Step: step.String()})
+	}
+	return t
+}
+
+// Record replays steps that were found in another way, e.g. by the explorer, and returns them as a trace.
+func Record(sys *compose.System, opts Options, steps []*compose.Step) (*Trace, error) {
+	sim := New(sys, opts)
+	for n, step := range steps {
+		enabled, err := sim.Enabled()
+		if err != nil {
+			return nil, err
+		}
+
+		idx := -1
+		for i := 0; idx < 0 && i < len(enabled); i++ {
+			if enabled[i].String() == step.String() && enabled[i].Target.Key() == step.Target.Key() {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("step %d, %s, is not enabled", n+1, step)
+		}
+
+		if _, err := sim.Take(idx); err != nil {
+			return nil, err
+		}
+	}
+	return sim.Trace(), nil
+}
+
+// Replay takes the steps of the trace from the current state. A step whose index doesn't match its description is
+// looked up by its description, it fails if no enabled step matches.
+func (sim *Simulator) Replay(t *Trace) error {
+	for n, ts := range t.Steps {
+		steps, err := sim.Enabled()
+		if err != nil {
+			return err
+		}
+
+		idx := -1
+		if 0 <= ts.Index && ts.Index < len(steps) && steps[ts.Index].String() == ts.Step {
+			idx = ts.Index
+		}
+		for i := 0; idx < 0 && i < len(steps); i++ {
+			if steps[i].String() == ts.Step {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return fmt.Errorf("step %d of the trace, %s, is not enabled", n+1, ts.Step)
+		}
+
+		if _, err := sim.Take(idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Keys of a bias that don't name an instance.
+const (
+	// BiasEnvironment weighs the inputs from the environment
+	BiasEnvironment = "environment"
+	// BiasChannels weighs the steps that channels take on their own, e.g. losing a message
+	BiasChannels = "channels"
+)
+
+// Bias weighs the steps of a random walk, by the name of the instance that takes them, or by BiasEnvironment or
+// BiasChannels. The weight of a step is the product of the weights that apply to it, 1 by default, and a step is
+// chosen with a probability proportional to its weight.
+type Bias map[string]float64
+
+// ParseBias parses a bias like Server=2,environment=0.5.
+func ParseBias(s string) (Bias, error) {
+	b := Bias{}
+	if s == "" {
+		return b, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		idx := strings.LastIndex(part, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("expected name=weight, got %s", part)
+		}
+
+		w, err := strconv.ParseFloat(part[idx+1:], 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("expected a weight of at least 0 for %s, got %s", part[:idx], part[idx+1:])
+		}
+		b[strings.TrimSpace(part[:idx])] = w
+	}
+	return b, nil
+}
+
+func (b Bias) weight(step *compose.Step) float64 {
+	keys := []string{}
+	for _, m := range step.Moves {
+		keys = append(keys, m.Instance.Name)
+	}
+	if step.Label.Kind == lts.Input {
+		keys = append(keys, BiasEnvironment)
+	}
+	if len(step.Moves) == 0 {
+		keys = append(keys, BiasChannels)
+	}
+
+	w := 1.0
+	for _, key := range keys {
+		if bw, ok := b[key]; ok {
+			w *= bw
+		}
+	}
+	return w
+}
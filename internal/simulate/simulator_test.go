@@ -0,0 +1,155 @@
+package simulate
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+const pingPong = `
+	(defprocess Client
+	  (loop
+	    (!send :message ping :to Server)
+	    (?receive :message pong)))
+	(defprocess Server
+	  (let ((n 0))
+	    (loop
+	      (?receive :message ping :from client)
+	      (if (< n 1)
+	        (!send :message pong :to client)
+	        (!send :message busy :to client))
+	      (set! n (+ n 1)))))
+`
+
+func newSimulator(t *testing.T, str string, opts Options) *Simulator {
+	m, err := lisp.LoadString(str)
+	assert.Nil(t, err)
+
+	sys, err := compose.New(m, compose.Options{Closed: opts.Closed})
+	assert.Nil(t, err)
+	return New(sys, opts)
+}
+
+func describe(sim *Simulator) string {
+	return sim.System().Describe(sim.State())
+}
+
+func TestSimulator(t *testing.T) {
+	sim := newSimulator(t, pingPong, Options{})
+
+	steps, err := sim.Enabled()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(steps))
+
+	step, err := sim.Take(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "Client !ping, to Server", step.String())
+	_, err = sim.Take(1)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "there is no step 2, 1 step(s) are enabled")
+
+	_, err = sim.Take(0)
+	assert.Nil(t, err)
+	after := describe(sim)
+	assert.Equal(t, "Client@#2, Server@#2 {client=nil n=0}, Client->Server [ping]", after)
+
+	assert.True(t, sim.Undo())
+	assert.True(t, sim.Undo())
+	assert.False(t, sim.Undo())
+	assert.Equal(t, "Client@:start, Server@:start {client=nil n=nil}", describe(sim))
+
+	assert.True(t, sim.Redo())
+	assert.True(t, sim.Redo())
+	assert.False(t, sim.Redo())
+	assert.Equal(t, after, describe(sim))
+
+	// Taking a step forgets the steps that were undone
+	assert.True(t, sim.Undo())
+	_, err = sim.Take(0)
+	assert.Nil(t, err)
+	assert.False(t, sim.Redo())
+	assert.Equal(t, 2, len(sim.Steps()))
+}
+
+func TestRandom(t *testing.T) {
+	walk := func(seed int64) []string {
+		sim := newSimulator(t, pingPong, Options{Seed: seed})
+		for i := 0; i < 20; i++ {
+			_, err := sim.Random()
+			assert.Nil(t, err)
+		}
+		strs := []string{}
+		for _, ts := range sim.Trace().Steps {
+			strs = append(strs, ts.Step)
+		}
+		return strs
+	}
+
+	assert.Equal(t, walk(1), walk(1))
+	assert.NotEmpty(t, walk(1))
+
+	sim := newSimulator(t, `(defprocess Done (!send :message done :to Done))`, Options{Closed: true})
+	_, err := sim.Random()
+	assert.Nil(t, err)
+	step, err := sim.Random()
+	assert.Nil(t, err)
+	assert.Nil(t, step)
+}
+
+func TestReplay(t *testing.T) {
+	sim := newSimulator(t, pingPong, Options{Seed: 7, Spec: "pingpong.lisp"})
+	for i := 0; i < 12; i++ {
+		_, err := sim.Random()
+		assert.Nil(t, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	assert.Nil(t, sim.Trace().Save(path))
+
+	trace, err := LoadTrace(path)
+	assert.Nil(t, err)
+	assert.Equal(t, sim.Trace(), trace)
+	assert.Equal(t, "pingpong.lisp", trace.Spec)
+
+	var tests = []struct {
+		name   string
+		edit   func(t *Trace)
+		expErr string
+	}{
+		{
+			name: "unchanged",
+			edit: func(t *Trace) {},
+		},
+		{
+			name: "index that doesn't match",
+			edit: func(t *Trace) { t.Steps[0].Index = 5 },
+		},
+		{
+			name:   "step that is not enabled",
+			edit:   func(t *Trace) { t.Steps[1].Step = "Server !pong, to Client" },
+			expErr: "step 2 of the trace, Server !pong, to Client, is not enabled",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Replay - %s", test.name), func(t *testing.T) {
+			trace, err := LoadTrace(path)
+			assert.Nil(t, err)
+			test.edit(trace)
+
+			replay := newSimulator(t, pingPong, Options{})
+			err = replay.Replay(trace)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, describe(sim), describe(replay))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package simulate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Trace is a saved run of a system. Every step is identified by its index among the steps that are enabled in the
+// state before it, its description guards against replaying the trace on another version of the specification.
+type Trace struct {
+	// Spec is the path of the specification that the trace was recorded on
+	Spec string `json:"spec"`
+	// Closed is set if the system did not receive messages from the environment
+	Closed bool `json:"closed,omitempty"`
+	// Seed is the seed of the random choices, if any
+	Seed  int64       `json:"seed,omitempty"`
+	Steps []TraceStep `json:"steps"`
+	// Violation describes the property that the last state violates, if any
+	Violation string `json:"violation,omitempty"`
+}
+
+// TraceStep is a step of a trace.
+type TraceStep struct {
+	Index int    `json:"index"`
+	Step  string `json:"step"`
+}
+
+// LoadTrace reads a trace from a file.
+func LoadTrace(path string) (*Trace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Trace{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("could not read the trace in %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Save writes the trace to a file.
+func (t *Trace) Save(path string) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(t); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
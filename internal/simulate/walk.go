@@ -0,0 +1,80 @@
+package simulate
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"dberk.nl/graphchecker/internal/check"
+	"dberk.nl/graphchecker/internal/compose"
+)
+
+// WalkOptions configure a batch of random walks. The seed of the options determines the seeds of the walks.
+type WalkOptions struct {
+	Options
+	// Walks is the number of walks, and Length the maximum number of steps of a walk
+	Walks  int
+	Length int
+}
+
+// Failure is a random walk that ended in a state that violates a property.
+type Failure struct {
+	// Walk numbers the walks from 1, Seed is the seed of the walk
+	Walk      int
+	Seed      int64
+	Violation *check.Violation
+	// Trace replays the walk
+	Trace *Trace
+}
+
+// Walks summarizes a batch of random walks.
+type Walks struct {
+	Walks, Steps int
+	Failures     []*Failure
+}
+
+// Walk takes random walks through the system, and checks the invariants and deadlock freedom in every state that
+// they visit. A walk ends when it violates a property, reaches a terminal state or has taken the maximum number of
+// steps.
+func Walk(sys *compose.System, opts WalkOptions) (*Walks, error) {
+	rnd := rand.New(rand.NewSource(opts.Seed))
+	walks := &Walks{Failures: []*Failure{}}
+	for n := 1; n <= opts.Walks; n++ {
+		wopts := opts.Options
+		wopts.Seed = rnd.Int63()
+		sim := New(sys, wopts)
+
+		v, err := sim.walk(opts.Length)
+		if err != nil {
+			return nil, fmt.Errorf("walk %d (seed %d): %w", n, wopts.Seed, err)
+		}
+
+		walks.Walks++
+		walks.Steps += sim.pos
+		if v != nil {
+			trace := sim.Trace()
+			trace.Violation = describeViolation(v)
+			walks.Failures = append(walks.Failures, &Failure{Walk: n, Seed: wopts.Seed, Violation: v, Trace: trace})
+		}
+	}
+	return walks, nil
+}
+
+// walk takes up to length random steps, and returns the first violation.
+func (sim *Simulator) walk(length int) (*check.Violation, error) {
+	for {
+		v, err := sim.Check()
+		if v != nil || err != nil || sim.pos == length {
+			return v, err
+		}
+
+		step, err := sim.Random()
+		if step == nil || err != nil {
+			return nil, err
+		}
+	}
+}
+
+func describeViolation(v *check.Violation) string {
+	return fmt.Sprintf("%s: %s", v.Property, strings.Join(v.Reasons, ", "))
+}
@@ -0,0 +1,134 @@
+package simulate
+
+import (
+	"fmt"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/compose"
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalk(t *testing.T) {
+	var tests = []struct {
+		name         string
+		str          string
+		opts         WalkOptions
+		expFailures  int
+		expViolation string
+	}{
+		{
+			name: "invariant",
+			str: `(defprocess Client
+			        (loop
+			          (!send :message ping :to Server)
+			          (?receive :message pong)))
+			      (defprocess Server
+			        (let ((n 0))
+			          (loop
+			            (?receive :message ping :from client)
+			            (!send :message pong :to client)
+			            (set! n (+ n 1)))))
+			      (definvariant few (!= (var Server :n) 3))`,
+			opts:         WalkOptions{Walks: 5, Length: 100},
+			expFailures:  5,
+			expViolation: "invariant few: (!= (var Server :n) 3) is false (<string>:11:10)",
+		},
+		{
+			name:         "deadlock",
+			str:          pingPong,
+			opts:         WalkOptions{Walks: 5, Length: 100},
+			expFailures:  5,
+			expViolation: "deadlock freedom: Client is not in a final state, Server is not in a final state, 1 message(s) are still in flight",
+		},
+		{
+			name:        "length",
+			str:         pingPong,
+			opts:        WalkOptions{Walks: 5, Length: 3},
+			expFailures: 0,
+		},
+		{
+			name: "terminal state",
+			str: `(defprocess Client
+			        (!send :message ping :to Server)
+			        :done :final)
+			      (defprocess Server
+			        (?receive :message ping)
+			        :done :final)`,
+			opts:        WalkOptions{Walks: 5, Length: 100},
+			expFailures: 0,
+		},
+		{
+			name: "bias",
+			str: `(defprocess Client
+			        (loop (!send :message ping :to Server)))
+			      (defprocess Server
+			        (loop (?receive :message ping)))
+			      (defchannel :semantics fifo :capacity 10)
+			      (definvariant short (< (len chan) 3))`,
+			opts:         WalkOptions{Walks: 5, Length: 100, Options: Options{Bias: Bias{"Server": 0}}},
+			expFailures:  5,
+			expViolation: "invariant short: (< (len chan) 3) is false (<string>:6:10)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Walk - %s", test.name), func(t *testing.T) {
+			m, err := lisp.LoadString(test.str)
+			assert.Nil(t, err)
+
+			sys, err := compose.New(m, compose.Options{})
+			assert.Nil(t, err)
+
+			test.opts.Seed = 1
+			test.opts.Invariants = m.Invariants
+			walks, err := Walk(sys, test.opts)
+			assert.Nil(t, err)
+			assert.Equal(t, test.opts.Walks, walks.Walks)
+			assert.Equal(t, test.expFailures, len(walks.Failures))
+
+			for _, f := range walks.Failures {
+				assert.Equal(t, test.expViolation, f.Trace.Violation)
+
+				// The trace reproduces the violation
+				sim := New(sys, Options{Invariants: m.Invariants})
+				assert.Nil(t, sim.Replay(f.Trace))
+				v, err := sim.Check()
+				assert.Nil(t, err)
+				assert.Equal(t, f.Violation.Property, v.Property)
+				assert.Equal(t, f.Violation.Reasons, v.Reasons)
+			}
+
+			again, err := Walk(sys, test.opts)
+			assert.Nil(t, err)
+			assert.Equal(t, walks.Steps, again.Steps)
+		})
+	}
+}
+
+func TestParseBias(t *testing.T) {
+	var tests = []struct {
+		str     string
+		expBias Bias
+		expErr  string
+	}{
+		{str: "", expBias: Bias{}},
+		{str: "Server=2,environment=0.5", expBias: Bias{"Server": 2, BiasEnvironment: 0.5}},
+		{str: "(Worker 1)=0", expBias: Bias{"(Worker 1)": 0}},
+		{str: "Server", expErr: "expected name=weight, got Server"},
+		{str: "Server=-1", expErr: "expected a weight of at least 0 for Server, got -1"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("ParseBias(%s)", test.str), func(t *testing.T) {
+			b, err := ParseBias(test.str)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, test.expBias, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jaegerTraces is the JSON format of the Jaeger query API, which the Jaeger UI also exports.
+type jaegerTraces struct {
+	Data []struct {
+		TraceID string `json:"traceID"`
+		Spans   []struct {
+			TraceID       string      `json:"traceID"`
+			SpanID        string      `json:"spanID"`
+			OperationName string      `json:"operationName"`
+			StartTime     int64       `json:"startTime"`
+			Duration      int64       `json:"duration"`
+			Tags          []jaegerTag `json:"tags"`
+			Logs          []struct {
+				Timestamp int64       `json:"timestamp"`
+				Fields    []jaegerTag `json:"fields"`
+			} `json:"logs"`
+			ProcessID string `json:"processID"`
+		} `json:"spans"`
+		Processes map[string]struct {
+			ServiceName string `json:"serviceName"`
+		} `json:"processes"`
+	} `json:"data"`
+}
+
+type jaegerTag struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+func jaegerAttributes(tags []jaegerTag) map[string]any {
+	attrs := map[string]any{}
+	for _, tag := range tags {
+		val := tag.Value
+		if n, ok := val.(json.Number); ok {
+			if i, err := n.Int64(); err == nil && tag.Type != "float64" {
+				val = i
+			} else {
+				val, _ = n.Float64()
+			}
+		}
+		attrs[tag.Key] = val
+	}
+	return attrs
+}
+
+// micros converts a timestamp in microseconds since the epoch, the resolution of Jaeger.
+func micros(us int64) time.Time {
+	return time.UnixMicro(us).UTC()
+}
+
+func readJaeger(data []byte) ([]Span, error) {
+	docs, err := decode[jaegerTraces](data)
+	if err != nil {
+		return nil, fmt.Errorf("jaeger: %w", err)
+	}
+
+	spans := []Span{}
+	for _, doc := range docs {
+		for _, trace := range doc.Data {
+			for _, s := range trace.Spans {
+				span := Span{
+					TraceID:    s.TraceID,
+					SpanID:     s.SpanID,
+					Service:    trace.Processes[s.ProcessID].ServiceName,
+					Name:       s.OperationName,
+					Start:      micros(s.StartTime),
+					End:        micros(s.StartTime + s.Duration),
+					Attributes: jaegerAttributes(s.Tags),
+					Events:     []SpanEvent{},
+				}
+
+				// The event field names a log, like the name of an OpenTelemetry span event.
+				for _, log := range s.Logs {
+					attrs := jaegerAttributes(log.Fields)
+					name, _ := attrs["event"].(string)
+					delete(attrs, "event")
+					span.Events = append(span.Events, SpanEvent{Name: name, Time: micros(log.Timestamp), Attributes: attrs})
+				}
+				spans = append(spans, span)
+			}
+		}
+	}
+	return spans, nil
+}
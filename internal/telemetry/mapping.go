@@ -0,0 +1,174 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"dberk.nl/graphchecker/internal/ioco"
+)
+
+// Mapping maps spans and span events onto the messages of a specification. For example
+//
+//	{"instance": "DynamoDBProcess",
+//	 "rules": [
+//	   {"span": "GetTask", "type": "receive", "message": "getTaskForKey", "fields": {"key": "task.key"}},
+//	   {"event": "task.found", "type": "send", "message": "taskForKey"}]}
+//
+// maps every GetTask span onto the receipt of getTaskForKey, whose key field is the task.key attribute of the span.
+type Mapping struct {
+	// Instance is the instance that sent or received the messages, unless a rule says otherwise
+	Instance string `json:"instance"`
+	Rules    []Rule `json:"rules"`
+}
+
+// Rule maps the spans, or the span events, that match it onto an event.
+type Rule struct {
+	// Span or Event selects the spans, or the span events, by name
+	Span  string `json:"span"`
+	Event string `json:"event"`
+	// Service and Attributes restrict the rule to spans of a service, and with attributes of the given values
+	Service    string            `json:"service"`
+	Attributes map[string]string `json:"attributes"`
+	// At selects the time of a span that the event happens at, its start or its end. It defaults to the start.
+	At string `json:"at"`
+
+	// Type is the type of the event, send or receive
+	Type     string `json:"type"`
+	Message  string `json:"message"`
+	Instance string `json:"instance"`
+	// Fields maps the fields of the message onto the attributes that contain their values
+	Fields map[string]string `json:"fields"`
+}
+
+// LoadMapping reads a mapping from a JSON file.
+func LoadMapping(path string) (*Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := ReadMapping(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ReadMapping reads a mapping and validates its rules.
+func ReadMapping(r io.Reader) (*Mapping, error) {
+	m := &Mapping{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+
+	for idx, rule := range m.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", idx+1, err)
+		}
+	}
+	return m, nil
+}
+
+func (r *Rule) validate() error {
+	switch {
+	case (r.Span == "") == (r.Event == ""):
+		return fmt.Errorf("expected either a span or an event")
+	case r.Type != ioco.EventSend && r.Type != ioco.EventReceive && r.Type != ioco.EventQuiescent:
+		return fmt.Errorf("unknown type %q, expected %s, %s or %s", r.Type, ioco.EventSend, ioco.EventReceive, ioco.EventQuiescent)
+	case r.Message == "" && r.Type != ioco.EventQuiescent:
+		return fmt.Errorf("expected a message")
+	case r.At != "" && r.At != "start" && r.At != "end":
+		return fmt.Errorf("unknown time %q, expected start or end", r.At)
+	case r.At != "" && r.Event != "":
+		return fmt.Errorf("span events happen at a single time, at only applies to spans")
+	}
+	return nil
+}
+
+// matches returns whether the rule applies to a span or span event of the span.
+func (r *Rule) matches(span *Span, name string, attrs map[string]any) bool {
+	if r.Service != "" && r.Service != span.Service {
+		return false
+	}
+	if r.Span != "" && r.Span != name || r.Event != "" && r.Event != name {
+		return false
+	}
+	for key, val := range r.Attributes {
+		if v, ok := lookup(span, attrs, key); !ok || fmt.Sprint(v) != val {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the value of an attribute of a span event, or of the span that it belongs to.
+func lookup(span *Span, attrs map[string]any, key string) (any, bool) {
+	if v, ok := attrs[key]; ok {
+		return v, true
+	}
+	v, ok := span.Attributes[key]
+	return v, ok
+}
+
+func (r *Rule) event(span *Span, t time.Time, attrs map[string]any, source string) ioco.Event {
+	e := ioco.Event{
+		Time:     t,
+		Instance: r.Instance,
+		Type:     r.Type,
+		Message:  ioco.Message{Name: r.Message},
+		Source:   source,
+	}
+
+	for field, key := range r.Fields {
+		if v, ok := lookup(span, attrs, key); ok {
+			if e.Fields == nil {
+				e.Fields = map[string]any{}
+			}
+			e.Fields[field] = v
+		}
+	}
+	return e
+}
+
+// Events maps the spans onto events, in the order in which they happened. Spans and span events that no rule matches
+// are skipped.
+func (m *Mapping) Events(spans []Span) []ioco.Event {
+	events := []ioco.Event{}
+	for idx := range spans {
+		span := &spans[idx]
+		for ridx := range m.Rules {
+			r := &m.Rules[ridx]
+			if r.Span != "" && r.matches(span, span.Name, span.Attributes) {
+				t := span.Start
+				if r.At == "end" {
+					t = span.End
+				}
+				events = append(events, r.event(span, t, span.Attributes, fmt.Sprintf("span %s %s", span.Name, span.SpanID)))
+			}
+
+			for _, se := range span.Events {
+				if r.Event != "" && r.matches(span, se.Name, se.Attributes) {
+					events = append(events, r.event(span, se.Time, se.Attributes, fmt.Sprintf("event %s of span %s %s", se.Name, span.Name, span.SpanID)))
+				}
+			}
+		}
+	}
+
+	for idx := range events {
+		if events[idx].Instance == "" {
+			events[idx].Instance = m.Instance
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
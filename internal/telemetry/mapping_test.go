@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/ioco"
+	"github.com/stretchr/testify/assert"
+)
+
+const otlp = `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"dynamo"}}]},
+"scopeSpans":[{"spans":[
+  {"traceId":"t1","spanId":"s1","name":"GetTask","startTimeUnixNano":"1700000000000000000","endTimeUnixNano":"1700000000500000000",
+   "attributes":[{"key":"task.key","value":{"intValue":"42"}},{"key":"cached","value":{"boolValue":false}}],
+   "events":[{"timeUnixNano":"1700000000400000000","name":"task.found","attributes":[{"key":"task.owner","value":{"stringValue":"w1"}}]}]},
+  {"traceId":"t1","spanId":"s2","name":"Healthcheck","startTimeUnixNano":"1700000001000000000","endTimeUnixNano":"1700000001000000000"}
+]}]}]}
+{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"dynamo"}}]},
+"scopeSpans":[{"spans":[
+  {"traceId":"t2","spanId":"s3","name":"GetTask","startTimeUnixNano":"1700000002000000000","endTimeUnixNano":"1700000002100000000",
+   "attributes":[{"key":"task.key","value":{"intValue":"7"}},{"key":"cached","value":{"boolValue":true}}]}
+]}]}]}
+`
+
+const jaeger = `{
+  "data": [{
+    "traceID": "t1",
+    "spans": [
+      {"traceID": "t1", "spanID": "s1", "operationName": "GetTask", "startTime": 1700000000000000, "duration": 500000,
+       "tags": [{"key": "task.key", "type": "int64", "value": 42}, {"key": "cached", "type": "bool", "value": false}],
+       "logs": [{"timestamp": 1700000000400000, "fields": [{"key": "event", "type": "string", "value": "task.found"},
+                                                          {"key": "task.owner", "type": "string", "value": "w1"}]}],
+       "processID": "p1"},
+      {"traceID": "t1", "spanID": "s3", "operationName": "GetTask", "startTime": 1700000002000000, "duration": 100000,
+       "tags": [{"key": "task.key", "type": "int64", "value": 7}, {"key": "cached", "type": "bool", "value": true}],
+       "processID": "p1"}
+    ],
+    "processes": {"p1": {"serviceName": "dynamo"}}
+  }]
+}`
+
+const mapping = `{
+  "instance": "DynamoDBProcess",
+  "rules": [
+    {"span": "GetTask", "service": "dynamo", "type": "receive", "message": "getTaskForKey", "fields": {"key": "task.key"}},
+    {"event": "task.found", "type": "send", "message": "taskForKey", "fields": {"key": "task.key", "owner": "task.owner"}},
+    {"span": "GetTask", "attributes": {"cached": "true"}, "at": "end", "type": "send", "message": "noTaskForKey"}
+  ]
+}`
+
+func TestEvents(t *testing.T) {
+	var tests = []struct {
+		format string
+		data   string
+	}{
+		{format: FormatOTLP, data: otlp},
+		{format: FormatJaeger, data: jaeger},
+		{format: "", data: otlp},
+		{format: "", data: jaeger},
+	}
+
+	m, err := ReadMapping(strings.NewReader(mapping))
+	assert.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Events(%q)", test.format), func(t *testing.T) {
+			spans, err := Read(strings.NewReader(test.data), test.format)
+			assert.Nil(t, err)
+
+			strs := []string{}
+			for _, e := range m.Events(spans) {
+				assert.Equal(t, "DynamoDBProcess", e.Instance)
+				strs = append(strs, e.String())
+			}
+			assert.Equal(t, []string{
+				`receive getTaskForKey {"key":42} at 2023-11-14T22:13:20Z (span GetTask s1)`,
+				`send taskForKey {"key":42,"owner":"w1"} at 2023-11-14T22:13:20.4Z (event task.found of span GetTask s1)`,
+				`receive getTaskForKey {"key":7} at 2023-11-14T22:13:22Z (span GetTask s3)`,
+				`send noTaskForKey at 2023-11-14T22:13:22.1Z (span GetTask s3)`,
+			}, strs)
+		})
+	}
+
+	_, err = Read(strings.NewReader(`{"spans": []}`), "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown format ""`)
+}
+
+func TestReadMapping(t *testing.T) {
+	var tests = []struct {
+		str    string
+		expErr string
+	}{
+		{str: mapping},
+		{str: `{"rules": [{"type": "send", "message": "m"}]}`, expErr: "rule 1: expected either a span or an event"},
+		{str: `{"rules": [{"span": "s", "type": "call", "message": "m"}]}`, expErr: `rule 1: unknown type "call"`},
+		{str: `{"rules": [{"span": "s", "type": "send"}]}`, expErr: "rule 1: expected a message"},
+		{str: `{"rules": [{"event": "e", "at": "end", "type": "send", "message": "m"}]}`, expErr: "at only applies to spans"},
+		{str: `{"rule": []}`, expErr: `unknown field "rule"`},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("ReadMapping(%s)", test.str), func(t *testing.T) {
+			_, err := ReadMapping(strings.NewReader(test.str))
+			if test.expErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.expErr)
+		})
+	}
+}
+
+func TestConform(t *testing.T) {
+	// Integer attributes are imported as int64, the specification compares them with its integers.
+	data := `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+"scopeSpans":[{"spans":[
+  {"traceId":"t1","spanId":"s1","name":"Handle","startTimeUnixNano":"1700000000000000000","endTimeUnixNano":"1700000000500000000",
+   "attributes":[{"key":"n","value":{"intValue":"%s"}}]}
+]}]}]}`
+	mapping := `{"instance": "Server", "rules": [
+  {"span": "Handle", "type": "receive", "message": "req"},
+  {"span": "Handle", "at": "end", "type": "send", "message": "resp", "fields": {"n": "n"}}]}`
+
+	m, err := lisp.LoadString(`(defprocess Server (loop (?receive :message req) (!send :message resp :n 1)))`)
+	assert.Nil(t, err)
+	spec, err := ioco.NewSpec(m, "Server", 100)
+	assert.Nil(t, err)
+
+	mp, err := ReadMapping(strings.NewReader(mapping))
+	assert.Nil(t, err)
+
+	for _, test := range []struct {
+		n      string
+		expErr bool
+	}{{n: "1"}, {n: "2", expErr: true}} {
+		t.Run(fmt.Sprintf("Conform(n=%s)", test.n), func(t *testing.T) {
+			spans, err := Read(strings.NewReader(fmt.Sprintf(data, test.n)), FormatOTLP)
+			assert.Nil(t, err)
+
+			_, d := ioco.Conform(spec, mp.Events(spans), 0)
+			if !test.expErr {
+				assert.Nil(t, d)
+				return
+			}
+
+			assert.NotNil(t, d)
+			assert.Contains(t, d.String(), "the fields don't match")
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// otlpTraces is the OTLP JSON encoding of ExportTraceServiceRequest, as written by the file exporter of the collector.
+type otlpTraces struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []struct {
+				TraceID           string         `json:"traceId"`
+				SpanID            string         `json:"spanId"`
+				Name              string         `json:"name"`
+				StartTimeUnixNano string         `json:"startTimeUnixNano"`
+				EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+				Attributes        []otlpKeyValue `json:"attributes"`
+				Events            []struct {
+					TimeUnixNano string         `json:"timeUnixNano"`
+					Name         string         `json:"name"`
+					Attributes   []otlpKeyValue `json:"attributes"`
+				} `json:"events"`
+			} `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string      `json:"stringValue"`
+	BoolValue   *bool        `json:"boolValue"`
+	IntValue    *json.Number `json:"intValue"`
+	DoubleValue *json.Number `json:"doubleValue"`
+	ArrayValue  *struct {
+		Values []otlpValue `json:"values"`
+	} `json:"arrayValue"`
+}
+
+func (v otlpValue) value() any {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		if i, err := v.IntValue.Int64(); err == nil {
+			return i
+		}
+		return v.IntValue.String()
+	case v.DoubleValue != nil:
+		f, _ := v.DoubleValue.Float64()
+		return f
+	case v.ArrayValue != nil:
+		vals := []any{}
+		for _, elem := range v.ArrayValue.Values {
+			vals = append(vals, elem.value())
+		}
+		return vals
+	default:
+		return nil
+	}
+}
+
+func otlpAttributes(kvs []otlpKeyValue) map[string]any {
+	attrs := map[string]any{}
+	for _, kv := range kvs {
+		attrs[kv.Key] = kv.Value.value()
+	}
+	return attrs
+}
+
+// unixNano parses a timestamp in nanoseconds since the epoch, which OTLP JSON encodes as a string.
+func unixNano(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	ns, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return time.Unix(0, ns).UTC(), nil
+}
+
+func readOTLP(data []byte) ([]Span, error) {
+	docs, err := decode[otlpTraces](data)
+	if err != nil {
+		return nil, fmt.Errorf("otlp: %w", err)
+	}
+
+	spans := []Span{}
+	for _, doc := range docs {
+		for _, rs := range doc.ResourceSpans {
+			service, _ := otlpAttributes(rs.Resource.Attributes)["service.name"].(string)
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					span := Span{
+						TraceID:    s.TraceID,
+						SpanID:     s.SpanID,
+						Service:    service,
+						Name:       s.Name,
+						Attributes: otlpAttributes(s.Attributes),
+						Events:     []SpanEvent{},
+					}
+					if span.Start, err = unixNano(s.StartTimeUnixNano); err != nil {
+						return nil, fmt.Errorf("otlp: span %s: %w", s.Name, err)
+					}
+					if span.End, err = unixNano(s.EndTimeUnixNano); err != nil {
+						return nil, fmt.Errorf("otlp: span %s: %w", s.Name, err)
+					}
+
+					for _, e := range s.Events {
+						t, err := unixNano(e.TimeUnixNano)
+						if err != nil {
+							return nil, fmt.Errorf("otlp: span %s: event %s: %w", s.Name, e.Name, err)
+						}
+						span.Events = append(span.Events, SpanEvent{Name: e.Name, Time: t, Attributes: otlpAttributes(e.Attributes)})
+					}
+					spans = append(spans, span)
+				}
+			}
+		}
+	}
+	return spans, nil
+}
@@ -0,0 +1,3 @@
+// package telemetry imports OpenTelemetry (OTLP JSON) and Jaeger JSON trace files, and maps their spans onto the
+// events of trace conformance checking
+package telemetry
@@ -0,0 +1,83 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Span is an operation of a trace, independent of the format that it was read from.
+type Span struct {
+	TraceID, SpanID string
+	Service         string
+	Name            string
+	Start, End      time.Time
+	Attributes      map[string]any
+	Events          []SpanEvent
+}
+
+// SpanEvent is an event that was recorded during a span, e.g. a log of a Jaeger span.
+type SpanEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]any
+}
+
+// Formats
+const (
+	FormatOTLP   = "otlp"
+	FormatJaeger = "jaeger"
+)
+
+// Read reads the spans of a trace file in the format, or detects the format if it is empty.
+func Read(r io.Reader, format string) ([]Span, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = detect(data)
+	}
+
+	switch format {
+	case FormatOTLP:
+		return readOTLP(data)
+	case FormatJaeger:
+		return readJaeger(data)
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected %s or %s", format, FormatOTLP, FormatJaeger)
+	}
+}
+
+// detect guesses the format from the first key of the file: OTLP files start with resourceSpans, Jaeger files with
+// data.
+func detect(data []byte) string {
+	first, _, _ := bytes.Cut(data, []byte(":"))
+	switch {
+	case bytes.Contains(first, []byte(`"resourceSpans"`)):
+		return FormatOTLP
+	case bytes.Contains(first, []byte(`"data"`)):
+		return FormatJaeger
+	default:
+		return ""
+	}
+}
+
+// decode decodes every JSON document of a file into a new value: collectors write one document per line, exports
+// from a UI a single indented document.
+func decode[T any](data []byte) ([]T, error) {
+	docs := []T{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	for dec.More() {
+		var doc T
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
@@ -0,0 +1,119 @@
+package iocotest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"dberk.nl/graphchecker/internal/ioco"
+)
+
+// Quiescence is what Observe returns when the implementation produces no output within the timeout.
+const Quiescence = "δ"
+
+// Handler gives an input to the implementation. Fields contains the fields of the message, if any.
+type Handler func(fields map[string]any) error
+
+// Adapter connects a test to an implementation: handlers map the inputs of the specification onto calls of the
+// implementation, which reports its outputs with Emit. Tests that graphchecker generates drive an adapter with Give
+// and Observe.
+type Adapter struct {
+	// Timeout is how long Observe waits for an output before it observes quiescence, 100ms by default
+	Timeout time.Duration
+
+	t        testing.TB
+	handlers map[string]Handler
+	outputs  chan ioco.Message
+}
+
+// NewAdapter returns an adapter without handlers that reports failures to the test.
+func NewAdapter(t testing.TB) *Adapter {
+	return &Adapter{Timeout: 100 * time.Millisecond, t: t, handlers: map[string]Handler{}, outputs: make(chan ioco.Message, 1024)}
+}
+
+// Handle registers the handler of an input of the specification, e.g. getTaskForKey.
+func (a *Adapter) Handle(message string, h Handler) {
+	a.handlers[message] = h
+}
+
+// Emit reports an output of the implementation. It may be called from a handler, or from any other goroutine.
+func (a *Adapter) Emit(message string, fields map[string]any) {
+	a.outputs <- ioco.Message{Name: message, Fields: fields}
+}
+
+// Give gives an input to the implementation and returns the empty string. If the implementation has already produced
+// an output, then it returns that output instead of giving the input, and fails the test if the output isn't one of
+// the allowed outputs.
+func (a *Adapter) Give(message string, allowed ...string) string {
+	a.t.Helper()
+
+	select {
+	case msg := <-a.outputs:
+		a.check(msg.Name, allowed)
+		return msg.Name
+	default:
+	}
+
+	if err := a.input(ioco.Message{Name: message}); err != nil {
+		a.t.Fatalf("%v", err)
+	}
+	return ""
+}
+
+// Observe waits for an output of the implementation and returns it, or Quiescence. It fails the test if the
+// observation isn't one of the allowed observations.
+func (a *Adapter) Observe(allowed ...string) string {
+	a.t.Helper()
+
+	msg, ok := a.output(a.Timeout)
+	name := Quiescence
+	if ok {
+		name = msg.Name
+	}
+	a.check(name, allowed)
+	return name
+}
+
+func (a *Adapter) check(name string, allowed []string) {
+	a.t.Helper()
+
+	for _, out := range allowed {
+		if out == name {
+			return
+		}
+	}
+
+	if len(allowed) == 0 {
+		allowed = []string{"nothing"}
+	}
+	a.t.Fatalf("the implementation produced %s, the specification only allows %s", name, strings.Join(allowed, ", "))
+}
+
+func (a *Adapter) input(msg ioco.Message) error {
+	h, ok := a.handlers[msg.Name]
+	if !ok {
+		return fmt.Errorf("no handler for the input %s", msg.Name)
+	}
+	if err := h(msg.Fields); err != nil {
+		return fmt.Errorf("%s: %w", msg.Name, err)
+	}
+	return nil
+}
+
+// output returns the next output of the implementation, or false if there is none within the timeout. An output that
+// has already arrived takes precedence over an expired timeout.
+func (a *Adapter) output(timeout time.Duration) (ioco.Message, bool) {
+	select {
+	case msg := <-a.outputs:
+		return msg, true
+	default:
+	}
+
+	select {
+	case msg := <-a.outputs:
+		return msg, true
+	case <-time.After(timeout):
+		return ioco.Message{}, false
+	}
+}
@@ -0,0 +1,80 @@
+package iocotest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdapter(t *testing.T) {
+	var tests = []struct {
+		name       string
+		emit       []string
+		test       func(a *Adapter) []string
+		expResults []string
+		expFailure string
+	}{
+		{
+			name: "conforming",
+			emit: []string{"ok"},
+			test: func(a *Adapter) []string {
+				return []string{a.Give("put", "full"), a.Observe("full", "ok"), a.Observe(Quiescence)}
+			},
+			expResults: []string{"", "ok", Quiescence},
+		},
+		{
+			name: "output before the input",
+			test: func(a *Adapter) []string {
+				a.Emit("full", nil)
+				return []string{a.Give("put", "full")}
+			},
+			expResults: []string{"full"},
+		},
+		{
+			name: "unexpected output",
+			emit: []string{"full"},
+			test: func(a *Adapter) []string {
+				return []string{a.Give("put"), a.Observe("ok")}
+			},
+			expFailure: "the implementation produced full, the specification only allows ok",
+		},
+		{
+			name: "unexpected quiescence",
+			test: func(a *Adapter) []string {
+				return []string{a.Give("put"), a.Observe("ok")}
+			},
+			expFailure: "the implementation produced δ, the specification only allows ok",
+		},
+		{
+			name: "missing handler",
+			test: func(a *Adapter) []string {
+				return []string{a.Give("get")}
+			},
+			expFailure: "no handler for the input get",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Adapter - %s", test.name), func(t *testing.T) {
+			var results []string
+			failure := run(t, func(r *recorder) {
+				a := NewAdapter(r)
+				a.Timeout = 10 * time.Millisecond
+				a.Handle("put", func(fields map[string]any) error {
+					for _, out := range test.emit {
+						a.Emit(out, nil)
+					}
+					return nil
+				})
+				results = test.test(a)
+			})
+
+			assert.Equal(t, test.expFailure, failure)
+			if test.expFailure == "" {
+				assert.Equal(t, test.expResults, results)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+// package iocotest tests Go implementations against a graphchecker specification from go test. Handlers map the
+// inputs of the specification onto calls of the implementation, which reports its outputs with Emit. Run then tests
+// the implementation online, and reports a failing trace through t.Fatalf with the seed that replays it. If
+// GRAPHCHECKER_COVERAGE names a file, then every run adds its coverage of the specification to it.
+//
+// The tests that graphchecker testgen --go writes drive an Adapter directly, they don't need graphchecker or the
+// specification at test time.
+package iocotest
@@ -0,0 +1,148 @@
+package iocotest
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"dberk.nl/graphchecker/internal/dsl/lisp"
+	"dberk.nl/graphchecker/internal/ioco"
+)
+
+// SeedEnv is the environment variable that overrides the seed of every run, to replay a failed test.
+const SeedEnv = "GRAPHCHECKER_SEED"
+
+// CoverageEnv is the environment variable that names a file to which every run adds its coverage of the specification,
+// see graphchecker coverage.
+const CoverageEnv = "GRAPHCHECKER_COVERAGE"
+
+// coverage serializes the updates of the coverage file by parallel tests.
+var coverage sync.Mutex
+
+// Options configure a run. Zero values select the defaults.
+type Options struct {
+	// Steps is the number of inputs and observations, 100 by default
+	Steps int
+	// Seed determines the choices of the tester, it is picked at random by default
+	Seed int64
+	// Timeout is how long the tester waits for an output before it observes quiescence, by default the timeout of the
+	// adapter
+	Timeout time.Duration
+}
+
+// Tester tests an implementation against an instance of a specification.
+type Tester struct {
+	*Adapter
+	spec *ioco.Spec
+}
+
+// New loads the specification at path and selects the instance that the implementation implements. It fails the test
+// if the specification can't be loaded.
+func New(t testing.TB, path, instance string) *Tester {
+	t.Helper()
+
+	m, err := lisp.LoadFile(path)
+	if err != nil {
+		t.Fatalf("could not load %s: %v", path, err)
+	}
+
+	spec, err := ioco.NewSpec(m, instance, 100000)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+
+	return &Tester{Adapter: NewAdapter(t), spec: spec}
+}
+
+// Run tests the implementation. It fails the test with the trace and the seed of the run if the implementation does
+// something that the specification doesn't allow.
+func (tt *Tester) Run(opts Options) {
+	tt.t.Helper()
+
+	for _, in := range tt.inputs() {
+		if _, ok := tt.handlers[in]; !ok {
+			tt.t.Fatalf("no handler for the input %s", in)
+		}
+	}
+
+	if opts.Steps == 0 {
+		opts.Steps = 100
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = tt.Timeout
+	}
+	if env := os.Getenv(SeedEnv); env != "" {
+		seed, err := strconv.ParseInt(env, 10, 64)
+		if err != nil {
+			tt.t.Fatalf("%s: %v", SeedEnv, err)
+		}
+		opts.Seed = seed
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+
+	run, err := ioco.Online(tt.spec, online{tt.Adapter}, ioco.OnlineOptions{Steps: opts.Steps, Seed: opts.Seed, Timeout: opts.Timeout})
+	if err != nil {
+		tt.t.Fatalf("%v (replay with %s=%d)", err, SeedEnv, opts.Seed)
+	}
+	if path := os.Getenv(CoverageEnv); path != "" {
+		if err := tt.record(path, run); err != nil {
+			tt.t.Fatalf("%s: %v", CoverageEnv, err)
+		}
+	}
+
+	if run.Failure != nil {
+		tt.t.Fatalf("%s does not conform to the specification, %s(replay with %s=%d)", tt.spec.Name, run.Failure, SeedEnv, opts.Seed)
+	}
+}
+
+// record adds the coverage of the run to the file.
+func (tt *Tester) record(path string, run *ioco.Run) error {
+	coverage.Lock()
+	defer coverage.Unlock()
+
+	c, err := ioco.LoadCoverage(path, tt.spec.Process)
+	if err != nil {
+		return err
+	}
+	if err := c.Add(tt.spec, run.Trace); err != nil {
+		return err
+	}
+	return c.Save(path)
+}
+
+// inputs returns the names of the inputs of the specification.
+func (tt *Tester) inputs() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for s := range tt.spec.States {
+		for _, in := range tt.spec.In(s) {
+			if !seen[in.Name] {
+				seen[in.Name] = true
+				names = append(names, in.Name)
+			}
+		}
+	}
+	return names
+}
+
+// online connects the online tester to the adapter.
+type online struct {
+	*Adapter
+}
+
+func (o online) Input(msg ioco.Message) error {
+	return o.input(msg)
+}
+
+func (o online) Output(timeout time.Duration) (ioco.Message, bool, error) {
+	msg, ok := o.output(timeout)
+	return msg, ok, nil
+}
+
+func (o online) Close() error {
+	return nil
+}
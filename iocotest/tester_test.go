@@ -0,0 +1,165 @@
+package iocotest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const spec = `
+	(defprocess Store
+	  (loop
+	    (?receive :message getTaskForKey :from client)
+	    (select
+	      (!send :message taskForKey :to client)
+	      (!send :message noTaskForKey :to client))))
+`
+
+// recorder is a test that records why it failed, instead of failing.
+type recorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// run runs the test function as a test, and returns its failure.
+func run(t *testing.T, f func(r *recorder)) string {
+	r := &recorder{TB: t}
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		f(r)
+	}()
+	<-done
+	return r.failure
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.lisp")
+	assert.Nil(t, os.WriteFile(path, []byte(spec), 0o644))
+
+	var tests = []struct {
+		name       string
+		handle     func(tt *Tester) Handler
+		expFailure string
+	}{
+		{
+			name: "conforming",
+			handle: func(tt *Tester) Handler {
+				tasks := 0
+				return func(fields map[string]any) error {
+					tasks++
+					if tasks%2 == 0 {
+						tt.Emit("taskForKey", nil)
+					} else {
+						tt.Emit("noTaskForKey", nil)
+					}
+					return nil
+				}
+			},
+		},
+		{
+			name: "unexpected output",
+			handle: func(tt *Tester) Handler {
+				return func(fields map[string]any) error {
+					tt.Emit("taskForKey", nil)
+					tt.Emit("taskForKey", nil)
+					return nil
+				}
+			},
+			expFailure: "!taskForKey\nthe implementation may produce !taskForKey, the specification only allows δ\n(replay with GRAPHCHECKER_SEED=1)",
+		},
+		{
+			name: "error",
+			handle: func(tt *Tester) Handler {
+				return func(fields map[string]any) error {
+					return errors.New("connection refused")
+				}
+			},
+			expFailure: "getTaskForKey: connection refused (replay with GRAPHCHECKER_SEED=1)",
+		},
+		{
+			name:       "missing handler",
+			handle:     func(tt *Tester) Handler { return nil },
+			expFailure: "no handler for the input getTaskForKey",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Run - %s", test.name), func(t *testing.T) {
+			failure := run(t, func(r *recorder) {
+				tt := New(r, path, "Store")
+				if h := test.handle(tt); h != nil {
+					tt.Handle("getTaskForKey", h)
+				}
+				tt.Run(Options{Steps: 30, Seed: 1, Timeout: 10 * time.Millisecond})
+			})
+
+			if test.expFailure == "" {
+				assert.Equal(t, "", failure)
+			} else {
+				assert.Contains(t, failure, test.expFailure)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.lisp")
+	assert.Nil(t, os.WriteFile(path, []byte(spec), 0o644))
+	t.Setenv(SeedEnv, "42")
+
+	failures := []string{}
+	for i := 0; i < 2; i++ {
+		failures = append(failures, run(t, func(r *recorder) {
+			tt := New(r, path, "Store")
+			tt.Handle("getTaskForKey", func(fields map[string]any) error { return nil })
+			tt.Run(Options{Timeout: time.Millisecond})
+		}))
+	}
+
+	assert.Contains(t, failures[0], "replay with GRAPHCHECKER_SEED=42")
+	assert.Equal(t, failures[0], failures[1])
+}
+
+func TestNew(t *testing.T) {
+	failure := run(t, func(r *recorder) {
+		New(r, filepath.Join(t.TempDir(), "missing.lisp"), "Store")
+	})
+	assert.Contains(t, failure, "could not load")
+}
+
+func TestCoverage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.lisp")
+	assert.Nil(t, os.WriteFile(path, []byte(spec), 0o644))
+	t.Setenv(CoverageEnv, filepath.Join(dir, "coverage.json"))
+
+	for i := 0; i < 2; i++ {
+		failure := run(t, func(r *recorder) {
+			tt := New(r, path, "Store")
+			tt.Handle("getTaskForKey", func(fields map[string]any) error {
+				tt.Emit("taskForKey", nil)
+				return nil
+			})
+			tt.Run(Options{Steps: 10, Seed: 1, Timeout: time.Millisecond})
+		})
+		assert.Equal(t, "", failure)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "coverage.json"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"runs": 2`)
+}